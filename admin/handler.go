@@ -0,0 +1,258 @@
+// Package admin provides an http.Handler to inspect and change integration levels at runtime.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ensarkovankaya/go-logging/core"
+	"github.com/ensarkovankaya/go-logging/integrations/batch"
+)
+
+type Option func(h *Handler)
+
+// Handler lists and changes the levels of the integrations registered on a batch.Logger.
+//
+// Mount it with http.StripPrefix so the remaining path is the integration type:
+//
+//	GET /          lists every integration with its levels
+//	GET /{type}    lists the integrations of the given type
+//	PUT /          changes the levels of every integration
+//	PUT /{type}    changes the levels of the integrations of the given type
+//
+// A PUT body sets every level of the targeted integrations with "level", single levels by name with
+// "levels", and reverts the change after "ttl" when given:
+//
+//	{"level": "debug", "ttl": "10m"}
+//	{"levels": {"event": "warning"}}
+type Handler struct {
+	Logger  func() *batch.Logger
+	NowFunc func() time.Time
+
+	mu      sync.Mutex
+	reverts map[core.LevelController]*revert
+}
+
+type revert struct {
+	previous map[string]core.Level
+	timer    *time.Timer
+	at       time.Time
+}
+
+type levelsRequest struct {
+	Level  *core.Level           `json:"level,omitempty"`
+	Levels map[string]core.Level `json:"levels,omitempty"`
+	TTL    string                `json:"ttl,omitempty"`
+}
+
+type integrationLevels struct {
+	Type     string                `json:"type"`
+	Levels   map[string]core.Level `json:"levels"`
+	RevertAt *time.Time            `json:"revertAt,omitempty"`
+}
+
+type levelsResponse struct {
+	Integrations []integrationLevels `json:"integrations"`
+}
+
+func NewHandler(logger func() *batch.Logger, opts ...Option) *Handler {
+	handler := &Handler{
+		Logger:  logger,
+		NowFunc: time.Now,
+		reverts: make(map[core.LevelController]*revert),
+	}
+	for _, opt := range opts {
+		opt(handler)
+	}
+	return handler
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := strings.Trim(r.URL.Path, "/")
+	controllers := h.controllers(target)
+	if target != "" && len(controllers) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no integration with adjustable levels: %s", target))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		h.writeLevels(w, controllers)
+	case http.MethodPut:
+		h.update(w, r, target, controllers)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed: %s", r.Method))
+	}
+}
+
+func (h *Handler) update(w http.ResponseWriter, r *http.Request, target string, controllers []controller) {
+	var request levelsRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if request.Level == nil && len(request.Levels) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("either level or levels must be set"))
+		return
+	}
+	var ttl time.Duration
+	if request.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(request.TTL); err != nil || ttl <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid ttl: %s", request.TTL))
+			return
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	changes, err := levelChanges(target, controllers, request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// Every change is validated above, a failing controller still restores the ones changed before
+	// it so the update applies entirely or not at all.
+	for i, change := range changes {
+		if err = change.controller.SetLevels(change.levels); err != nil {
+			for _, applied := range changes[:i] {
+				_ = applied.controller.SetLevels(applied.previous)
+			}
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	for _, change := range changes {
+		h.schedule(change.controller, change.previous, ttl)
+	}
+	h.writeLevelsLocked(w, controllers)
+}
+
+type levelChange struct {
+	controller core.LevelController
+	levels     map[string]core.Level
+	previous   map[string]core.Level
+}
+
+// levelChanges returns the levels the request sets on each controller. A level name unknown to a
+// targeted integration is an error, so typos are not silently ignored, and so is a name no
+// integration knows when every integration is targeted.
+func levelChanges(target string, controllers []controller, request levelsRequest) ([]levelChange, error) {
+	changes := make([]levelChange, 0, len(controllers))
+	known := make(map[string]bool)
+	errs := make([]error, 0)
+	for _, c := range controllers {
+		previous := c.LevelController.Levels()
+		levels := make(map[string]core.Level)
+		for name := range previous {
+			if request.Level != nil {
+				levels[name] = *request.Level
+			}
+		}
+		for name, level := range request.Levels {
+			if _, ok := previous[name]; ok {
+				levels[name] = level
+				known[name] = true
+			} else if target != "" {
+				errs = append(errs, fmt.Errorf("unknown %s level: %s", c.Type, name))
+			}
+		}
+		if len(levels) > 0 {
+			changes = append(changes, levelChange{controller: c.LevelController, levels: levels, previous: previous})
+		}
+	}
+	if target == "" {
+		for name := range request.Levels {
+			if !known[name] {
+				errs = append(errs, fmt.Errorf("unknown level: %s", name))
+			}
+		}
+	}
+	return changes, errors.Join(errs...)
+}
+
+// schedule reverts the levels of the controller to previous after ttl. A pending revert is replaced,
+// keeping the levels from before the first change so the original state is restored.
+// It must be called with h.mu held.
+func (h *Handler) schedule(controller core.LevelController, previous map[string]core.Level, ttl time.Duration) {
+	if pending, ok := h.reverts[controller]; ok {
+		pending.timer.Stop()
+		delete(h.reverts, controller)
+		previous = pending.previous
+	}
+	if ttl > 0 {
+		pending := &revert{previous: previous, at: h.NowFunc().Add(ttl)}
+		pending.timer = time.AfterFunc(ttl, func() {
+			h.revert(controller, pending)
+		})
+		h.reverts[controller] = pending
+	}
+}
+
+func (h *Handler) revert(controller core.LevelController, pending *revert) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.reverts[controller] != pending {
+		return
+	}
+	delete(h.reverts, controller)
+	_ = controller.SetLevels(pending.previous)
+}
+
+func (h *Handler) writeLevels(w http.ResponseWriter, controllers []controller) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeLevelsLocked(w, controllers)
+}
+
+func (h *Handler) writeLevelsLocked(w http.ResponseWriter, controllers []controller) {
+	response := levelsResponse{Integrations: make([]integrationLevels, 0, len(controllers))}
+	for _, c := range controllers {
+		levels := integrationLevels{Type: c.Type, Levels: c.LevelController.Levels()}
+		if pending, ok := h.reverts[c.LevelController]; ok {
+			at := pending.at
+			levels.RevertAt = &at
+		}
+		response.Integrations = append(response.Integrations, levels)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+type controller struct {
+	core.LevelController
+	Type string
+}
+
+func (h *Handler) controllers(target string) []controller {
+	logger := h.Logger()
+	if logger == nil {
+		return nil
+	}
+	controllers := make([]controller, 0)
+	for _, integration := range logger.Integrations() {
+		if target != "" && integration.Type() != target {
+			continue
+		}
+		if levelController, ok := integration.(core.LevelController); ok {
+			controllers = append(controllers, controller{LevelController: levelController, Type: integration.Type()})
+		}
+	}
+	return controllers
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,180 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.uber.org/zap"
+
+	"github.com/ensarkovankaya/go-logging/core"
+	"github.com/ensarkovankaya/go-logging/integrations/batch"
+	"github.com/ensarkovankaya/go-logging/integrations/console"
+	"github.com/ensarkovankaya/go-logging/integrations/otel"
+)
+
+func TestHandler_List(t *testing.T) {
+	handler, _ := getTestHandler(t)
+	response := serve(t, handler, http.MethodGet, "/", "")
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+	integrations := decodeLevels(t, response)
+	if len(integrations) != 2 {
+		t.Fatalf("Expected 2 integrations, got %d", len(integrations))
+	}
+	if integrations[0].Type != console.Type || integrations[1].Type != otel.Type {
+		t.Errorf("Unexpected integration types: %s, %s", integrations[0].Type, integrations[1].Type)
+	}
+	if level := integrations[0].Levels[core.LogLevelName]; level != core.LevelInfo {
+		t.Errorf("Expected console level info, got %s", level)
+	}
+
+	response = serve(t, handler, http.MethodGet, "/unknown", "")
+	if response.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", response.Code)
+	}
+}
+
+func TestHandler_Update(t *testing.T) {
+	handler, logger := getTestHandler(t)
+	response := serve(t, handler, http.MethodPut, "/", `{"level":"error"}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+	for _, integration := range logger.Integrations() {
+		if level := integration.(core.LevelController).Levels()[core.LogLevelName]; level != core.LevelError {
+			t.Errorf("Expected %s level error, got %s", integration.Type(), level)
+		}
+	}
+
+	response = serve(t, handler, http.MethodPut, "/otel", `{"level":"trace"}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+	if level := logger.GetIntegration(otel.Type).(*otel.Logger).Levels()[core.LogLevelName]; level != core.LevelTrace {
+		t.Errorf("Expected otel level trace, got %s", level)
+	}
+	if level := logger.GetIntegration(console.Type).(*console.Logger).Levels()[core.LogLevelName]; level != core.LevelError {
+		t.Errorf("Expected console level to stay error, got %s", level)
+	}
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/", `{}`},
+		{"/", `{"level":"verbose"}`},
+		{"/", `{"level":"debug","ttl":"-1m"}`},
+		{"/otel", `{"levels":{"event":"debug"}}`},
+		{"/", `{"levels":{"event":"debug"}}`},
+		{"/", `{"level":"debug","levels":{"log":"info","evnt":"debug"}}`},
+	}
+	for _, tt := range tests {
+		response = serve(t, handler, http.MethodPut, tt.path, tt.body)
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s %s, got %d", tt.path, tt.body, response.Code)
+		}
+	}
+	for _, integration := range logger.Integrations() {
+		if level := integration.(core.LevelController).Levels()[core.LogLevelName]; level == core.LevelDebug {
+			t.Errorf("Expected the rejected requests to leave the %s level unchanged", integration.Type())
+		}
+	}
+
+	response = serve(t, handler, http.MethodDelete, "/", "")
+	if response.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", response.Code)
+	}
+}
+
+func TestHandler_TTL(t *testing.T) {
+	handler, logger := getTestHandler(t)
+	otelLogger := logger.GetIntegration(otel.Type).(*otel.Logger)
+
+	response := serve(t, handler, http.MethodPut, "/otel", `{"level":"trace","ttl":"50ms"}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+	if integrations := decodeLevels(t, response); integrations[0].RevertAt == nil {
+		t.Error("Expected revertAt to be set")
+	}
+	// A second change before the revert keeps the original level to revert to.
+	serve(t, handler, http.MethodPut, "/otel", `{"level":"debug","ttl":"50ms"}`)
+	if level := otelLogger.Levels()[core.LogLevelName]; level != core.LevelDebug {
+		t.Fatalf("Expected otel level debug, got %s", level)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for otelLogger.Levels()[core.LogLevelName] != core.LevelWarning {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected otel level to revert to warning, got %s", otelLogger.Levels()[core.LogLevelName])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if integrations := decodeLevels(t, serve(t, handler, http.MethodGet, "/otel", "")); integrations[0].RevertAt != nil {
+		t.Error("Expected revertAt to be cleared after the revert")
+	}
+}
+
+func TestHandler_ConcurrentLogging(t *testing.T) {
+	handler, logger := getTestHandler(t)
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					logger.Debug(ctx, "message")
+				}
+			}
+		}()
+	}
+	for _, level := range []string{"trace", "error", "info"} {
+		serve(t, handler, http.MethodPut, "/otel", `{"level":"`+level+`"}`)
+	}
+	close(done)
+	wg.Wait()
+}
+
+func getTestHandler(t *testing.T) (*Handler, *batch.Logger) {
+	t.Helper()
+	logger := batch.New()
+	logger.AddIntegration(console.New(func(l *console.Logger) {
+		l.Level = core.LevelInfo
+		l.Transport = zap.NewNop()
+	}))
+	logger.AddIntegration(otel.NewWithProvider(sdklog.NewLoggerProvider(), func(l *otel.Logger) {
+		l.Level = core.LevelWarning
+	}))
+	return NewHandler(func() *batch.Logger { return logger }), logger
+}
+
+func serve(t *testing.T, handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	return response
+}
+
+func decodeLevels(t *testing.T, response *httptest.ResponseRecorder) []integrationLevels {
+	t.Helper()
+	var decoded levelsResponse
+	if err := json.NewDecoder(response.Body).Decode(&decoded); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return decoded.Integrations
+}
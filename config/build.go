@@ -0,0 +1,424 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"go.uber.org/zap"
+
+	"github.com/ensarkovankaya/go-logging/core"
+	"github.com/ensarkovankaya/go-logging/integrations/batch"
+	"github.com/ensarkovankaya/go-logging/integrations/console"
+	elastic "github.com/ensarkovankaya/go-logging/integrations/elasticsearch/v8"
+	"github.com/ensarkovankaya/go-logging/integrations/file"
+	"github.com/ensarkovankaya/go-logging/integrations/otel"
+	"github.com/ensarkovankaya/go-logging/integrations/sentry"
+)
+
+// Build returns a batch.Logger with the configured level rules and integrations. The static fields
+// are added to every integration. The configuration is expected to be valid, see Validate.
+func (c *Config) Build() (*batch.Logger, error) {
+	logger := batch.New()
+	rules, err := c.levelRules()
+	if err != nil {
+		return nil, err
+	}
+	logger.SetLevelRules(rules)
+	for _, s := range sections {
+		if !s.present(&c.Integrations) {
+			continue
+		}
+		integration, err := c.build(s)
+		if err != nil {
+			_ = logger.Close(context.Background())
+			return nil, err
+		}
+		logger.AddIntegration(integration)
+	}
+	return logger, nil
+}
+
+// section describes how one integration of the document is detected, compared and built.
+type section struct {
+	Type string
+	// config returns the section of the document, nil when it is missing.
+	config func(i *Integrations) any
+	build  func(i *Integrations) (core.Interface, error)
+	// exclusive reports whether the integrations built from two versions of the section need a
+	// resource only one of them can hold at a time, nil when they never do.
+	exclusive func(previous, next *Integrations) bool
+}
+
+// sections are built in order. Elasticsearch is built last since it is the most expensive to build
+// and close when a later integration fails.
+var sections = []section{
+	{
+		Type:   console.Type,
+		config: func(i *Integrations) any { return i.Console },
+		build:  func(i *Integrations) (core.Interface, error) { return i.Console.build() },
+	},
+	{
+		Type:   file.Type,
+		config: func(i *Integrations) any { return i.File },
+		build:  func(i *Integrations) (core.Interface, error) { return i.File.build() },
+	},
+	{
+		Type:   sentry.Type,
+		config: func(i *Integrations) any { return i.Sentry },
+		build:  func(i *Integrations) (core.Interface, error) { return i.Sentry.build() },
+	},
+	{
+		Type:   otel.Type,
+		config: func(i *Integrations) any { return i.Otel },
+		build:  func(i *Integrations) (core.Interface, error) { return i.Otel.build() },
+	},
+	{
+		Type:   elastic.Type,
+		config: func(i *Integrations) any { return i.Elasticsearch },
+		build:  func(i *Integrations) (core.Interface, error) { return i.Elasticsearch.build() },
+		// A spool directory is owned by a single elastic.Spool, which recovers it when opened.
+		exclusive: func(previous, next *Integrations) bool {
+			return previous.Elasticsearch.Spool != nil && next.Elasticsearch.Spool != nil &&
+				filepath.Clean(previous.Elasticsearch.Spool.Dir) == filepath.Clean(next.Elasticsearch.Spool.Dir)
+		},
+	},
+}
+
+func (s section) present(i *Integrations) bool {
+	return !reflect.ValueOf(s.config(i)).IsNil()
+}
+
+// build builds the integration of the section with the static fields.
+func (c *Config) build(s section) (core.Interface, error) {
+	integration, err := s.build(&c.Integrations)
+	if err != nil {
+		return nil, fmt.Errorf("integrations.%s: %w", s.Type, err)
+	}
+	if fields := c.fields(); len(fields) > 0 {
+		integration = integration.With(fields...)
+	}
+	return integration, nil
+}
+
+func (c *Config) levelRules() (core.LevelRules, error) {
+	if c.Levels == "" {
+		return core.LevelRules{}, nil
+	}
+	rules, err := core.ParseLevelRules(c.Levels)
+	if err != nil {
+		return nil, &FieldError{Path: "levels", Env: c.sources["levels"], Err: err}
+	}
+	return rules, nil
+}
+
+// fields returns the static fields sorted by key, so entries are built the same way on every run.
+func (c *Config) fields() []core.Field {
+	fields := make([]core.Field, 0, len(c.Fields))
+	for key, value := range c.Fields {
+		fields = append(fields, core.F(key, value))
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Key < fields[j].Key
+	})
+	return fields
+}
+
+func (c *Console) build() (*console.Logger, error) {
+	zapLogger, err := console.Initialize(func(cfg *zap.Config) {
+		cfg.Level = zap.NewAtomicLevelAt(console.TraceLevel)
+		if c.Development != nil {
+			cfg.Development = *c.Development
+		}
+	}, console.ZapEncoding(c.Encoding.build(console.DefaultEncoding())))
+	if err != nil {
+		return nil, err
+	}
+	return console.New(func(l *console.Logger) {
+		l.Transport = zapLogger
+		setLevel(&l.Level, c.Level)
+	}), nil
+}
+
+func (f *File) build() (*file.Logger, error) {
+	options := []file.Option{func(l *file.Logger) {
+		setLevel(&l.Level, f.Level)
+	}}
+	if f.Encoding.Format != "" || f.Encoding.TimeFormat != "" || len(f.Encoding.Keys) > 0 {
+		options = append(options, file.WithEncoding(f.Encoding.build(file.DefaultEncoding)))
+	}
+	if f.Rotation != nil {
+		options = append(options, file.WithRotation(f.Rotation.build()))
+	}
+	if f.ReopenOnSignal {
+		options = append(options, file.WithReopenSignals())
+	}
+	if f.CheckInterval != "" {
+		interval, _ := time.ParseDuration(f.CheckInterval)
+		options = append(options, file.WithCheckInterval(interval))
+	}
+	if f.CreateDirs {
+		options = append(options, file.WithCreateDirs(0755))
+	}
+	if f.Fallback {
+		options = append(options, file.WithFallback(os.Stderr))
+	}
+	return file.Open(f.Path, options...)
+}
+
+// build returns encoding with the configured values replacing its own.
+func (e *Encoding) build(encoding console.Encoding) console.Encoding {
+	if e.Format != "" {
+		encoding.Format = console.Format(e.Format)
+	}
+	if e.TimeFormat != "" {
+		encoding.TimeFormat = e.TimeFormat
+	}
+	if len(e.Keys) > 0 {
+		encoding.Keys = e.Keys
+	}
+	return encoding
+}
+
+func (r *Rotation) build() file.Rotation {
+	rotation := file.Rotation{
+		Interval: file.Interval(r.Interval),
+		Compress: r.Compress,
+	}
+	if r.MaxSizeMB != nil {
+		rotation.MaxSize = int64(*r.MaxSizeMB) * 1024 * 1024
+	}
+	if r.MaxBackups != nil {
+		rotation.MaxBackups = *r.MaxBackups
+	}
+	if r.MaxAge != "" {
+		rotation.MaxAge, _ = time.ParseDuration(r.MaxAge)
+	}
+	return rotation
+}
+
+func (s *Sentry) build() (*sentry.Logger, error) {
+	hub, err := sentry.Initialize(func(cfg *sentry.Config) {
+		cfg.DSN = s.DSN
+		if s.Environment != "" {
+			cfg.Environment = s.Environment
+		}
+		if s.ServerName != "" {
+			cfg.ServerName = s.ServerName
+		}
+		if s.SampleRate != nil {
+			cfg.SampleRate = *s.SampleRate
+		}
+		if s.TracesSampleRate != nil {
+			cfg.TracesSampleRate = *s.TracesSampleRate
+		}
+		if s.MaxBreadcrumbs != nil {
+			cfg.MaxBreadcrumbs = *s.MaxBreadcrumbs
+		}
+		if s.EnableLogs != nil {
+			cfg.EnableLogs = *s.EnableLogs
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger := sentry.New(func(l *sentry.Logger) {
+		l.Hub = hub
+		setLevel(&l.LogLevel, s.LogLevel)
+		setLevel(&l.EventLevel, s.EventLevel)
+		setLevel(&l.BreadcrumbLevel, s.BreadcrumbLevel)
+		if s.FlushTimeout != "" {
+			l.FlushTimeout, _ = time.ParseDuration(s.FlushTimeout)
+		}
+		for _, fingerprint := range s.Fingerprints {
+			l.Fingerprints = append(l.Fingerprints, sentry.FingerprintRule{
+				Pattern:     fingerprint.Pattern,
+				Fingerprint: fingerprint.Fingerprint,
+			})
+		}
+		if s.RateLimit != nil {
+			window, _ := time.ParseDuration(s.RateLimitWindow)
+			sentry.WithRateLimit(*s.RateLimit, window)(l)
+		}
+		if l.RateLimiter != nil && s.RateLimitBypassLevel != "" {
+			l.RateLimiter.BypassLevel, _ = core.ParseLevel(s.RateLimitBypassLevel)
+		}
+	})
+	return logger, nil
+}
+
+func (e *Elasticsearch) build() (*elastic.Logger, error) {
+	client, err := elastic.NewClient(func(cfg *elasticsearch.Config) {
+		cfg.Addresses = e.Addresses
+		cfg.Username = e.Username
+		cfg.Password = e.Password
+		cfg.APIKey = e.APIKey
+	})
+	if err != nil {
+		return nil, err
+	}
+	if e.Bootstrap != nil {
+		if err = e.bootstrap(client); err != nil {
+			return nil, err
+		}
+	}
+	sink, err := elastic.NewSink(func(cfg *esutil.BulkIndexerConfig) {
+		cfg.Client = client
+		if e.Sink.FlushBytes != nil {
+			cfg.FlushBytes = *e.Sink.FlushBytes
+		}
+		if e.Sink.FlushInterval != "" {
+			cfg.FlushInterval, _ = time.ParseDuration(e.Sink.FlushInterval)
+		}
+		if e.Sink.NumWorkers != nil {
+			cfg.NumWorkers = *e.Sink.NumWorkers
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	var deadLetter *elastic.DeadLetter
+	if e.DeadLetter != nil {
+		if deadLetter, err = e.DeadLetter.build(); err != nil {
+			_ = sink.Close(context.Background())
+			return nil, err
+		}
+	}
+	if e.Spool != nil {
+		// The documents rejected on replay lost their callbacks, they are dead-lettered when a dead
+		// letter is configured and reported to stderr otherwise.
+		onFailure := reportReplayFailure
+		if deadLetter != nil {
+			onFailure = deadLetter.Reject
+		}
+		spooled, err := e.Spool.build(client, sink, onFailure)
+		if err != nil {
+			if deadLetter != nil {
+				// Closing the dead letter closes sink and the dead letter file.
+				deadLetter.Sink = sink
+				_ = deadLetter.Close(context.Background())
+			} else {
+				_ = sink.Close(context.Background())
+			}
+			return nil, err
+		}
+		sink = spooled
+	}
+	if deadLetter != nil {
+		deadLetter.Sink = sink
+		sink = deadLetter
+	}
+	return elastic.New(func(l *elastic.Logger) {
+		l.Sink = sink
+		setLevel(&l.Level, e.Level)
+		if e.IndexName != "" {
+			l.IndexBuilder = elastic.NewIndexBuilder(e.IndexName)
+		}
+		// Validate rejects fields combined with ecs or data_stream, the ECS documents write the fields
+		// as labels which the guard does not apply to.
+		switch {
+		case e.DataStream != "":
+			elastic.WithDataStream(e.DataStream)(l)
+		case e.ECS:
+			l.DocumentBuilder = elastic.ECSDocumentBuilder
+		case e.Fields != nil:
+			guard := elastic.FieldGuard{Mode: e.Fields.Mode}
+			if e.Fields.MaxDepth != nil {
+				guard.MaxDepth = *e.Fields.MaxDepth
+			}
+			if e.Fields.MaxFields != nil {
+				guard.MaxFields = *e.Fields.MaxFields
+			}
+			elastic.WithFieldGuard(guard)(l)
+		}
+	}), nil
+}
+
+// build returns the DeadLetter without its Sink, which the caller sets. The DeadLetter owns the file
+// at Path and closes it with its Sink.
+func (d *DeadLetter) build() (*elastic.DeadLetter, error) {
+	var options []elastic.DeadLetterOption
+	if d.MaxRetries != nil {
+		options = append(options, elastic.WithMaxRetries(*d.MaxRetries))
+	}
+	if d.Path != "" {
+		writer, err := file.NewWriter(d.Path, file.Rotation{})
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, elastic.WithDeadLetterWriteCloser(writer))
+	}
+	return elastic.NewDeadLetter(nil, options...), nil
+}
+
+func (s *Spool) build(client *elasticsearch.Client, sink esutil.BulkIndexer, onFailure func(context.Context, esutil.BulkIndexerItem, esutil.BulkIndexerResponseItem, error)) (esutil.BulkIndexer, error) {
+	options := []elastic.SpoolOption{elastic.WithSpoolClient(client), elastic.WithSpoolOnFailure(onFailure)}
+	if s.MaxSizeMB != nil {
+		options = append(options, elastic.WithSpoolMaxBytes(int64(*s.MaxSizeMB)<<20))
+	}
+	if s.ReplayInterval != "" {
+		interval, _ := time.ParseDuration(s.ReplayInterval)
+		options = append(options, elastic.WithReplayInterval(interval))
+	}
+	return elastic.NewSpool(s.Dir, sink, options...)
+}
+
+func reportReplayFailure(_ context.Context, item esutil.BulkIndexerItem, resp esutil.BulkIndexerResponseItem, err error) {
+	reason := fmt.Sprintf("status %d", resp.Status)
+	if err != nil {
+		reason = err.Error()
+	} else if resp.Error.Type != "" {
+		reason = fmt.Sprintf("%s: %s", resp.Error.Type, resp.Error.Reason)
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Elasticsearch rejected a spooled document of index %s: %s\n", item.Index, reason)
+}
+
+// bootstrapTimeout bounds the template and policy installation, which blocks Build.
+const bootstrapTimeout = 30 * time.Second
+
+func (e *Elasticsearch) bootstrap(client *elasticsearch.Client) error {
+	options := []elastic.BootstrapOption{
+		elastic.WithBootstrapClient(client),
+		elastic.WithPolicy(elastic.Policy{
+			RolloverMaxSize: e.Bootstrap.RolloverMaxSize,
+			RolloverMaxAge:  e.Bootstrap.RolloverMaxAge,
+			DeleteAfter:     e.Bootstrap.DeleteAfter,
+		}),
+	}
+	name := e.IndexName
+	if e.DataStream != "" {
+		name = e.DataStream
+		options = append(options, elastic.WithBootstrapDataStream())
+	} else if e.ECS {
+		options = append(options, elastic.WithECSMappings())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), bootstrapTimeout)
+	defer cancel()
+	return elastic.NewBootstrap(name, options...).Run(ctx)
+}
+
+func (o *Otel) build() (*otel.Logger, error) {
+	provider, err := otel.NewProvider(o.Exporter)
+	if err != nil {
+		return nil, err
+	}
+	return otel.NewWithProvider(provider, func(l *otel.Logger) {
+		setLevel(&l.Level, o.Level)
+	}), nil
+}
+
+// setLevel sets target to the level when value is set, keeping the integration default otherwise.
+func setLevel(target *core.Level, value string) {
+	if value == "" {
+		return
+	}
+	if level, err := core.ParseLevel(value); err == nil {
+		*target = level
+	}
+}
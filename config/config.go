@@ -0,0 +1,527 @@
+// Package config builds a batch.Logger from a declarative YAML or JSON document.
+//
+// A document describes the level rules, the static fields added to every entry and the integrations
+// to build:
+//
+//	levels: "db.*=warning,http=info"
+//	fields:
+//	  service: billing
+//	integrations:
+//	  console:
+//	    level: info
+//	    format: pretty
+//	  file:
+//	    path: /var/log/billing.log
+//	    rotation:
+//	      max_size_mb: 100
+//	      interval: daily
+//	      max_backups: 7
+//	      compress: true
+//	  sentry:
+//	    dsn: https://public@sentry.example.com/1
+//	    event_level: error
+//	    fingerprints:
+//	      - pattern: db.*
+//	        fingerprint: ["{{ logger }}", "{{ error.type }}"]
+//	    rate_limit: 10
+//	    rate_limit_window: 1m
+//	  elasticsearch:
+//	    addresses: ["https://elastic.example.com:9200"]
+//	    index_name: billing
+//	    bootstrap:
+//	      delete_after: 30d
+//	    sink:
+//	      flush_interval: 10s
+//	  otel:
+//	    exporter: otlp
+//
+// Only the integrations present in the document are built. The environment variables read by the
+// integrations (CONSOLE_LOG_LEVEL, SENTRY_DSN, ELASTICSEARCH_URL, ...) override the values of the
+// document, so one document can be shared across environments.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ensarkovankaya/go-logging/core"
+	consolelog "github.com/ensarkovankaya/go-logging/integrations/console"
+	elastic "github.com/ensarkovankaya/go-logging/integrations/elasticsearch/v8"
+	"github.com/ensarkovankaya/go-logging/integrations/otel"
+)
+
+type Config struct {
+	// Levels are level rules in the LOG_LEVELS format, see core.ParseLevelRules.
+	Levels       string         `yaml:"levels"`
+	Fields       map[string]any `yaml:"fields"`
+	Integrations Integrations   `yaml:"integrations"`
+
+	// sources maps the key paths overridden by environment variables to the variable name.
+	sources map[string]string
+}
+
+type Integrations struct {
+	Console       *Console       `yaml:"console"`
+	File          *File          `yaml:"file"`
+	Sentry        *Sentry        `yaml:"sentry"`
+	Elasticsearch *Elasticsearch `yaml:"elasticsearch"`
+	Otel          *Otel          `yaml:"otel"`
+}
+
+type Console struct {
+	Level       string `yaml:"level"`
+	Development *bool  `yaml:"development"`
+	Encoding    `yaml:",inline"`
+}
+
+type File struct {
+	Path     string `yaml:"path"`
+	Level    string `yaml:"level"`
+	Encoding `yaml:",inline"`
+	Rotation *Rotation `yaml:"rotation"`
+	// ReopenOnSignal reopens the file on SIGHUP and SIGUSR1, for external rotation tools.
+	ReopenOnSignal bool `yaml:"reopen_on_signal"`
+	// CheckInterval is the interval of the checks reopening the file when it was moved or removed.
+	CheckInterval string `yaml:"check_interval"`
+	// CreateDirs creates the missing parent directories of Path.
+	CreateDirs bool `yaml:"create_dirs"`
+	// Fallback writes the entries to stderr while the file is unwritable.
+	Fallback bool `yaml:"fallback"`
+}
+
+// Encoding holds the entry format of the console and file integrations, see console.Encoding.
+type Encoding struct {
+	Format     string            `yaml:"format"`
+	TimeFormat string            `yaml:"time_format"`
+	Keys       map[string]string `yaml:"keys"`
+}
+
+// Rotation holds the rotation parameters of the file integration, see file.Rotation.
+type Rotation struct {
+	MaxSizeMB  *int   `yaml:"max_size_mb"`
+	Interval   string `yaml:"interval"`
+	MaxBackups *int   `yaml:"max_backups"`
+	MaxAge     string `yaml:"max_age"`
+	Compress   bool   `yaml:"compress"`
+}
+
+type Sentry struct {
+	DSN              string   `yaml:"dsn"`
+	Environment      string   `yaml:"environment"`
+	ServerName       string   `yaml:"server_name"`
+	LogLevel         string   `yaml:"log_level"`
+	EventLevel       string   `yaml:"event_level"`
+	BreadcrumbLevel  string   `yaml:"breadcrumb_level"`
+	SampleRate       *float64 `yaml:"sample_rate"`
+	TracesSampleRate *float64 `yaml:"traces_sample_rate"`
+	MaxBreadcrumbs   *int     `yaml:"max_breadcrumbs"`
+	EnableLogs       *bool    `yaml:"enable_logs"`
+	FlushTimeout     string   `yaml:"flush_timeout"`
+	// Fingerprints group the events by logger name, the first matching pattern applies.
+	Fingerprints []Fingerprint `yaml:"fingerprints"`
+	// RateLimit is the number of events sent per RateLimitWindow for the same level, message,
+	// logger name and error type, zero sends every event. Events at RateLimitBypassLevel and above
+	// are never suppressed.
+	RateLimit            *int   `yaml:"rate_limit"`
+	RateLimitWindow      string `yaml:"rate_limit_window"`
+	RateLimitBypassLevel string `yaml:"rate_limit_bypass_level"`
+}
+
+type Fingerprint struct {
+	Pattern     string   `yaml:"pattern"`
+	Fingerprint []string `yaml:"fingerprint"`
+}
+
+type Elasticsearch struct {
+	Addresses []string `yaml:"addresses"`
+	Username  string   `yaml:"username"`
+	Password  string   `yaml:"password"`
+	APIKey    string   `yaml:"api_key"`
+	IndexName string   `yaml:"index_name"`
+	// DataStream writes ECS documents to the data stream instead of time-suffixed indices.
+	DataStream string `yaml:"data_stream"`
+	// ECS writes Elastic Common Schema documents to the indices.
+	ECS   bool   `yaml:"ecs"`
+	Level string `yaml:"level"`
+	Sink  Sink   `yaml:"sink"`
+	// Bootstrap installs the index template and the ILM policy of the indices when building.
+	Bootstrap *Bootstrap `yaml:"bootstrap"`
+	// DeadLetter retries failed documents and writes the documents given up on to a file.
+	DeadLetter *DeadLetter `yaml:"dead_letter"`
+	// Fields rewrites the entry fields so values of different kinds do not conflict in the index
+	// mapping, see elastic.FieldGuard for the index template it needs.
+	Fields *Fields `yaml:"fields"`
+	// Spool spills the documents to disk while the cluster is unreachable.
+	Spool *Spool `yaml:"spool"`
+}
+
+// Fields holds the elastic.FieldGuard of the entry fields.
+type Fields struct {
+	Mode      string `yaml:"mode"`
+	MaxDepth  *int   `yaml:"max_depth"`
+	MaxFields *int   `yaml:"max_fields"`
+}
+
+type Spool struct {
+	Dir            string `yaml:"dir"`
+	MaxSizeMB      *int   `yaml:"max_size_mb"`
+	ReplayInterval string `yaml:"replay_interval"`
+}
+
+type DeadLetter struct {
+	MaxRetries *int `yaml:"max_retries"`
+	// Path is the file dead-lettered documents are written to as JSON lines.
+	Path string `yaml:"path"`
+}
+
+// Bootstrap holds the ILM policy phases installed by elastic.Bootstrap, in Elasticsearch units
+// such as 50gb and 30d.
+type Bootstrap struct {
+	RolloverMaxSize string `yaml:"rollover_max_size"`
+	RolloverMaxAge  string `yaml:"rollover_max_age"`
+	DeleteAfter     string `yaml:"delete_after"`
+}
+
+// Sink holds the batching parameters of the Elasticsearch bulk indexer.
+type Sink struct {
+	FlushBytes    *int   `yaml:"flush_bytes"`
+	FlushInterval string `yaml:"flush_interval"`
+	NumWorkers    *int   `yaml:"num_workers"`
+}
+
+type Otel struct {
+	Exporter string `yaml:"exporter"`
+	Level    string `yaml:"level"`
+}
+
+// FieldError reports an invalid value with the key path it was read from, and the environment
+// variable when the value was overridden by one.
+type FieldError struct {
+	Path string
+	Env  string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	if e.Env != "" {
+		return fmt.Sprintf("%s (from %s): %v", e.Path, e.Env, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// LoadFile reads the document at path, see Load.
+func LoadFile(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open logging config: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return Load(file)
+}
+
+// Load decodes a YAML or JSON document, JSON being a subset of YAML, applies the environment
+// overrides and validates the result. Unknown keys are rejected.
+func Load(r io.Reader) (*Config, error) {
+	cfg := &Config{}
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode logging config: %w", err)
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid value of the configuration.
+//
+//nolint:gocyclo
+func (c *Config) Validate() error {
+	errs := make([]error, 0)
+	check := func(path string, err error) {
+		if err != nil {
+			errs = append(errs, &FieldError{Path: path, Env: c.sources[path], Err: err})
+		}
+	}
+	if c.Levels != "" {
+		_, err := core.ParseLevelRules(c.Levels)
+		check("levels", err)
+	}
+	if console := c.Integrations.Console; console != nil {
+		check("integrations.console.level", validateLevel(console.Level))
+		console.Encoding.validate("integrations.console", check)
+	}
+	if file := c.Integrations.File; file != nil {
+		check("integrations.file.path", validateRequired(file.Path))
+		check("integrations.file.level", validateLevel(file.Level))
+		file.Encoding.validate("integrations.file", check)
+		check("integrations.file.check_interval", validateDuration(file.CheckInterval))
+		if r := file.Rotation; r != nil {
+			check("integrations.file.rotation.max_size_mb", validateNonNegative(r.MaxSizeMB))
+			check("integrations.file.rotation.max_backups", validateNonNegative(r.MaxBackups))
+			check("integrations.file.rotation.max_age", validateDuration(r.MaxAge))
+			switch r.Interval {
+			case "", "hourly", "daily":
+			default:
+				check("integrations.file.rotation.interval", fmt.Errorf("unsupported interval: %s", r.Interval))
+			}
+		}
+	}
+	if s := c.Integrations.Sentry; s != nil {
+		if err := validateRequired(s.DSN); err != nil {
+			check("integrations.sentry.dsn", err)
+		} else {
+			_, err = sentry.NewDsn(s.DSN)
+			check("integrations.sentry.dsn", err)
+		}
+		check("integrations.sentry.log_level", validateLevel(s.LogLevel))
+		check("integrations.sentry.event_level", validateLevel(s.EventLevel))
+		check("integrations.sentry.breadcrumb_level", validateLevel(s.BreadcrumbLevel))
+		check("integrations.sentry.sample_rate", validateRate(s.SampleRate))
+		check("integrations.sentry.traces_sample_rate", validateRate(s.TracesSampleRate))
+		check("integrations.sentry.max_breadcrumbs", validateNonNegative(s.MaxBreadcrumbs))
+		check("integrations.sentry.flush_timeout", validateDuration(s.FlushTimeout))
+		check("integrations.sentry.rate_limit", validateNonNegative(s.RateLimit))
+		check("integrations.sentry.rate_limit_window", validateDuration(s.RateLimitWindow))
+		check("integrations.sentry.rate_limit_bypass_level", validateLevel(s.RateLimitBypassLevel))
+		for i, fingerprint := range s.Fingerprints {
+			path := fmt.Sprintf("integrations.sentry.fingerprints[%d]", i)
+			check(path+".pattern", validateRequired(fingerprint.Pattern))
+			if len(fingerprint.Fingerprint) == 0 {
+				check(path+".fingerprint", errors.New("at least one value is required"))
+			}
+		}
+	}
+	if e := c.Integrations.Elasticsearch; e != nil {
+		if len(e.Addresses) == 0 {
+			check("integrations.elasticsearch.addresses", errors.New("at least one address is required"))
+		}
+		for i, address := range e.Addresses {
+			check(fmt.Sprintf("integrations.elasticsearch.addresses[%d]", i), validateURL(address))
+		}
+		if e.DataStream != "" && e.IndexName != "" {
+			check("integrations.elasticsearch.data_stream", errors.New("cannot be combined with index_name"))
+		}
+		check("integrations.elasticsearch.level", validateLevel(e.Level))
+		if e.Bootstrap != nil && e.DataStream == "" {
+			check("integrations.elasticsearch.bootstrap", elastic.NewBootstrap(e.IndexName).Validate())
+		}
+		if e.DeadLetter != nil {
+			check("integrations.elasticsearch.dead_letter.max_retries", validateNonNegative(e.DeadLetter.MaxRetries))
+		}
+		if f := e.Fields; f != nil {
+			if e.ECS || e.DataStream != "" {
+				check("integrations.elasticsearch.fields", errors.New("cannot be combined with ecs or data_stream"))
+			}
+			switch f.Mode {
+			case "", elastic.FieldModeTyped, elastic.FieldModeFlattened:
+			default:
+				check("integrations.elasticsearch.fields.mode", fmt.Errorf("unsupported mode: %s", f.Mode))
+			}
+			check("integrations.elasticsearch.fields.max_depth", validateNonNegative(f.MaxDepth))
+			check("integrations.elasticsearch.fields.max_fields", validateNonNegative(f.MaxFields))
+		}
+		if sp := e.Spool; sp != nil {
+			check("integrations.elasticsearch.spool.dir", validateRequired(sp.Dir))
+			check("integrations.elasticsearch.spool.max_size_mb", validateNonNegative(sp.MaxSizeMB))
+			check("integrations.elasticsearch.spool.replay_interval", validateDuration(sp.ReplayInterval))
+		}
+		check("integrations.elasticsearch.sink.flush_bytes", validateNonNegative(e.Sink.FlushBytes))
+		check("integrations.elasticsearch.sink.flush_interval", validateDuration(e.Sink.FlushInterval))
+		if e.Sink.NumWorkers != nil && *e.Sink.NumWorkers <= 0 {
+			check("integrations.elasticsearch.sink.num_workers", fmt.Errorf("must be positive, got %d", *e.Sink.NumWorkers))
+		}
+	}
+	if o := c.Integrations.Otel; o != nil {
+		switch strings.ToLower(strings.TrimSpace(o.Exporter)) {
+		case "", otel.ExporterConsole, "stdout", otel.ExporterOTLP, otel.ExporterNone:
+		default:
+			check("integrations.otel.exporter", fmt.Errorf("unsupported exporter: %s", o.Exporter))
+		}
+		check("integrations.otel.level", validateLevel(o.Level))
+	}
+	return errors.Join(errs...)
+}
+
+// applyEnv overrides the values of the configured integrations with the environment variables
+// the integrations read on their own.
+func (c *Config) applyEnv() error {
+	o := &overrides{sources: make(map[string]string)}
+	override(o, &c.Levels, "levels", "LOG_LEVELS", parseString)
+	if console := c.Integrations.Console; console != nil {
+		override(o, &console.Level, "integrations.console.level", "CONSOLE_LOG_LEVEL", parseString)
+		override(o, &console.Development, "integrations.console.development", "CONSOLE_DEBUG", parseBool)
+		override(o, &console.Format, "integrations.console.format", "CONSOLE_LOG_FORMAT", parseString)
+		override(o, &console.TimeFormat, "integrations.console.time_format", "CONSOLE_LOG_TIME_FORMAT", parseString)
+		override(o, &console.Keys, "integrations.console.keys", "CONSOLE_LOG_KEYS", consolelog.ParseKeys)
+	}
+	if file := c.Integrations.File; file != nil {
+		override(o, &file.Level, "integrations.file.level", "FILE_LOG_LEVEL", parseString)
+	}
+	if s := c.Integrations.Sentry; s != nil {
+		override(o, &s.DSN, "integrations.sentry.dsn", "SENTRY_DSN", parseString)
+		override(o, &s.Environment, "integrations.sentry.environment", "ENVIRONMENT", parseString)
+		override(o, &s.Environment, "integrations.sentry.environment", "ENV", parseString)
+		override(o, &s.ServerName, "integrations.sentry.server_name", "APP_NAME", parseString)
+		override(o, &s.LogLevel, "integrations.sentry.log_level", "SENTRY_LOG_LEVEL", parseString)
+		override(o, &s.EventLevel, "integrations.sentry.event_level", "SENTRY_EVENT_LEVEL", parseString)
+		override(o, &s.BreadcrumbLevel, "integrations.sentry.breadcrumb_level", "SENTRY_BREADCRUMB_LEVEL", parseString)
+		override(o, &s.SampleRate, "integrations.sentry.sample_rate", "SENTRY_SAMPLE_RATE", parseFloat)
+		override(o, &s.TracesSampleRate, "integrations.sentry.traces_sample_rate", "SENTRY_TRACE_SAMPLE_RATE", parseFloat)
+		override(o, &s.MaxBreadcrumbs, "integrations.sentry.max_breadcrumbs", "SENTRY_MAX_BREADCRUMBS", parseInt)
+		override(o, &s.EnableLogs, "integrations.sentry.enable_logs", "SENTRY_ENABLE_LOGS", parseBool)
+		override(o, &s.FlushTimeout, "integrations.sentry.flush_timeout", "SENTRY_FLUSH_TIMEOUT", parseString)
+		override(o, &s.RateLimit, "integrations.sentry.rate_limit", "SENTRY_RATE_LIMIT", parseInt)
+		override(o, &s.RateLimitWindow, "integrations.sentry.rate_limit_window", "SENTRY_RATE_LIMIT_WINDOW", parseString)
+		override(o, &s.RateLimitBypassLevel, "integrations.sentry.rate_limit_bypass_level", "SENTRY_RATE_LIMIT_BYPASS_LEVEL", parseString)
+	}
+	if e := c.Integrations.Elasticsearch; e != nil {
+		override(o, &e.Addresses, "integrations.elasticsearch.addresses", "ELASTICSEARCH_URL", parseList)
+		override(o, &e.IndexName, "integrations.elasticsearch.index_name", "ELASTICSEARCH_INDEX_NAME", parseString)
+		override(o, &e.Level, "integrations.elasticsearch.level", "ELASTICSEARCH_LOG_LEVEL", parseString)
+		override(o, &e.Sink.FlushBytes, "integrations.elasticsearch.sink.flush_bytes", "ELASTICSEARCH_SINK_FLUSH_BYTES", parseInt)
+		override(o, &e.Sink.FlushInterval, "integrations.elasticsearch.sink.flush_interval", "ELASTICSEARCH_SINK_FLUSH_INTERVAL", parseString)
+		override(o, &e.Sink.NumWorkers, "integrations.elasticsearch.sink.num_workers", "ELASTICSEARCH_SINK_NUM_WORKERS", parseInt)
+	}
+	if otelConfig := c.Integrations.Otel; otelConfig != nil {
+		override(o, &otelConfig.Exporter, "integrations.otel.exporter", "OTEL_LOGS_EXPORTER", parseString)
+		override(o, &otelConfig.Level, "integrations.otel.level", "OTEL_LOGS_LEVEL", parseString)
+	}
+	c.sources = o.sources
+	return errors.Join(o.errs...)
+}
+
+type overrides struct {
+	sources map[string]string
+	errs    []error
+}
+
+func override[T any](o *overrides, target *T, path, env string, parse func(string) (T, error)) {
+	value := os.Getenv(env)
+	if value == "" {
+		return
+	}
+	parsed, err := parse(value)
+	if err != nil {
+		o.errs = append(o.errs, &FieldError{Path: path, Env: env, Err: err})
+		return
+	}
+	*target = parsed
+	o.sources[path] = env
+}
+
+func parseString(value string) (string, error) {
+	return value, nil
+}
+
+func parseBool(value string) (*bool, error) {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func parseInt(value string) (*int, error) {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func parseFloat(value string) (*float64, error) {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func parseList(value string) ([]string, error) {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (e *Encoding) validate(path string, check func(path string, err error)) {
+	check(path+".format", consolelog.Encoding{Format: consolelog.Format(e.Format)}.Validate())
+	check(path+".keys", consolelog.Encoding{Keys: e.Keys}.Validate())
+}
+
+func validateRequired(value string) error {
+	if value == "" {
+		return errors.New("required")
+	}
+	return nil
+}
+
+func validateLevel(value string) error {
+	if value == "" {
+		return nil
+	}
+	_, err := core.ParseLevel(value)
+	return err
+}
+
+func validateDuration(value string) error {
+	if value == "" {
+		return nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if duration < 0 {
+		return fmt.Errorf("must not be negative, got %s", value)
+	}
+	return nil
+}
+
+func validateRate(value *float64) error {
+	if value != nil && (*value < 0 || *value > 1) {
+		return fmt.Errorf("must be between 0 and 1, got %v", *value)
+	}
+	return nil
+}
+
+func validateNonNegative(value *int) error {
+	if value != nil && *value < 0 {
+		return fmt.Errorf("must not be negative, got %d", *value)
+	}
+	return nil
+}
+
+func validateURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("invalid url: %s", value)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ensarkovankaya/go-logging/core"
+	"github.com/ensarkovankaya/go-logging/integrations/console"
+	"github.com/ensarkovankaya/go-logging/integrations/otel"
+	"github.com/ensarkovankaya/go-logging/integrations/sentry"
+)
+
+const testYAML = `
+levels: "db.*=warning"
+fields:
+  service: billing
+integrations:
+  console:
+    level: info
+  sentry:
+    dsn: https://public@sentry.example.com/1
+    event_level: warning
+    sample_rate: 0.5
+    flush_timeout: 2s
+  otel:
+    exporter: none
+    level: error
+`
+
+func TestLoad_YAML(t *testing.T) {
+	cfg, err := Load(strings.NewReader(testYAML))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Levels != "db.*=warning" {
+		t.Errorf("Expected levels 'db.*=warning', got '%s'", cfg.Levels)
+	}
+	if cfg.Integrations.Console == nil || cfg.Integrations.Console.Level != "info" {
+		t.Errorf("Expected console level info, got %+v", cfg.Integrations.Console)
+	}
+	if cfg.Integrations.Sentry == nil || *cfg.Integrations.Sentry.SampleRate != 0.5 {
+		t.Errorf("Expected sentry sample rate 0.5, got %+v", cfg.Integrations.Sentry)
+	}
+	if cfg.Integrations.File != nil || cfg.Integrations.Elasticsearch != nil {
+		t.Error("Expected integrations missing from the document to stay nil")
+	}
+}
+
+func TestLoad_JSON(t *testing.T) {
+	cfg, err := Load(strings.NewReader(`{"integrations": {"otel": {"exporter": "none", "level": "debug"}}}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Integrations.Otel == nil || cfg.Integrations.Otel.Level != "debug" {
+		t.Errorf("Expected otel level debug, got %+v", cfg.Integrations.Otel)
+	}
+}
+
+func TestLoad_Validation(t *testing.T) {
+	document := `
+levels: "db.*"
+integrations:
+  console:
+    level: verbose
+    format: xml
+  file:
+    rotation:
+      interval: weekly
+      max_backups: -1
+  sentry:
+    dsn: not-a-dsn
+    sample_rate: 2
+    fingerprints:
+      - pattern: db.*
+    rate_limit_window: often
+  elasticsearch:
+    addresses: ["localhost:9200"]
+    ecs: true
+    bootstrap:
+      delete_after: 30d
+    dead_letter:
+      max_retries: -1
+    fields:
+      mode: nested
+    spool:
+      replay_interval: often
+    sink:
+      flush_interval: soon
+  otel:
+    exporter: zipkin
+`
+	_, err := Load(strings.NewReader(document))
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	paths := []string{
+		"levels",
+		"integrations.console.level",
+		"integrations.console.format",
+		"integrations.file.path",
+		"integrations.file.rotation.interval",
+		"integrations.file.rotation.max_backups",
+		"integrations.sentry.dsn",
+		"integrations.sentry.sample_rate",
+		"integrations.sentry.fingerprints[0].fingerprint",
+		"integrations.sentry.rate_limit_window",
+		"integrations.elasticsearch.addresses[0]",
+		"integrations.elasticsearch.bootstrap",
+		"integrations.elasticsearch.dead_letter.max_retries",
+		"integrations.elasticsearch.fields",
+		"integrations.elasticsearch.fields.mode",
+		"integrations.elasticsearch.spool.dir",
+		"integrations.elasticsearch.spool.replay_interval",
+		"integrations.elasticsearch.sink.flush_interval",
+		"integrations.otel.exporter",
+	}
+	for _, path := range paths {
+		if !strings.Contains(err.Error(), path+":") {
+			t.Errorf("Expected error for '%s', got: %v", path, err)
+		}
+	}
+	var fieldError *FieldError
+	if !errors.As(err, &fieldError) {
+		t.Errorf("Expected a FieldError, got %T", err)
+	}
+
+	if _, err = Load(strings.NewReader("integrations:\n  consol: {}\n")); err == nil {
+		t.Error("Expected error for unknown key")
+	}
+}
+
+func TestLoad_EnvOverride(t *testing.T) {
+	t.Setenv("CONSOLE_LOG_LEVEL", "error")
+	t.Setenv("SENTRY_EVENT_LEVEL", "fatal")
+	t.Setenv("SENTRY_SAMPLE_RATE", "0.1")
+	t.Setenv("OTEL_LOGS_LEVEL", "verbose")
+	_, err := Load(strings.NewReader(testYAML))
+	if err == nil || !strings.Contains(err.Error(), "integrations.otel.level (from OTEL_LOGS_LEVEL):") {
+		t.Fatalf("Expected otel level error naming the environment variable, got: %v", err)
+	}
+
+	t.Setenv("OTEL_LOGS_LEVEL", "")
+	cfg, err := Load(strings.NewReader(testYAML))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Integrations.Console.Level != "error" {
+		t.Errorf("Expected console level error, got '%s'", cfg.Integrations.Console.Level)
+	}
+	if cfg.Integrations.Sentry.EventLevel != "fatal" {
+		t.Errorf("Expected sentry event level fatal, got '%s'", cfg.Integrations.Sentry.EventLevel)
+	}
+	if *cfg.Integrations.Sentry.SampleRate != 0.1 {
+		t.Errorf("Expected sentry sample rate 0.1, got %v", *cfg.Integrations.Sentry.SampleRate)
+	}
+}
+
+func TestConfig_Build(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	cfg, err := Load(strings.NewReader(testYAML + "  file:\n    path: " + path + "\n    level: debug\n"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	logger, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	integrations := logger.Integrations()
+	if len(integrations) != 4 {
+		t.Fatalf("Expected 4 integrations, got %d", len(integrations))
+	}
+	if level := integrations[0].(*console.Logger).Level; level != core.LevelInfo {
+		t.Errorf("Expected console level info, got %s", level)
+	}
+	sentryLogger := logger.GetIntegration(sentry.Type).(*sentry.Logger)
+	if level := sentryLogger.EventLevel; level != core.LevelWarning {
+		t.Errorf("Expected sentry event level warning, got %s", level)
+	}
+	if sentryLogger.FlushTimeout.String() != "2s" {
+		t.Errorf("Expected sentry flush timeout 2s, got %s", sentryLogger.FlushTimeout)
+	}
+	if level := logger.GetIntegration(otel.Type).(*otel.Logger).Level; level != core.LevelError {
+		t.Errorf("Expected otel level error, got %s", level)
+	}
+	if level, ok := logger.LevelRules().Level("db.query"); !ok || level != core.LevelWarning {
+		t.Errorf("Expected level rule warning for db.query, got %s", level)
+	}
+
+	logger.Named("db").Named("query").Info(context.Background(), "dropped")
+	logger.Info(context.Background(), "written")
+	if err = integrations[1].Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if strings.Contains(string(content), "dropped") || !strings.Contains(string(content), "written") {
+		t.Errorf("Unexpected log file content: %s", content)
+	}
+	if !strings.Contains(string(content), `"service":"billing"`) {
+		t.Errorf("Expected static field in log file, got: %s", content)
+	}
+}
+
+func TestConfig_BuildFileError(t *testing.T) {
+	cfg, err := Load(strings.NewReader("integrations:\n  file:\n    path: " + filepath.Join(t.TempDir(), "missing", "app.log") + "\n"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if _, err = cfg.Build(); err == nil || !strings.Contains(err.Error(), "integrations.file") {
+		t.Errorf("Expected build error for the file integration, got: %v", err)
+	}
+}
+
+func TestConfig_BuildFileCreateDirs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "app.log")
+	cfg, err := Load(strings.NewReader("integrations:\n  file:\n    path: " + path + "\n    create_dirs: true\n    fallback: true\n"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	logger, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if err = logger.Health(); err != nil {
+		t.Errorf("Expected a healthy logger, got %v", err)
+	}
+	if _, err = os.Stat(path); err != nil {
+		t.Errorf("Expected the log file to be created: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ensarkovankaya/go-logging/core"
+	"github.com/ensarkovankaya/go-logging/integrations/batch"
+)
+
+var DefaultWatchInterval = 5 * time.Second
+
+type WatcherOption func(w *Watcher)
+
+// Watcher reloads a configuration file into a running batch.Logger when the file changes or the
+// process receives SIGHUP.
+//
+// A reload only rebuilds the integrations whose section changed and removes the integrations whose
+// section was removed from the document. When Current is nil the integrations of types the document
+// does not describe are left untouched, since they may not come from the configuration. The swap is
+// a single batch.Logger.UpdateIntegrations call, so every log call reaches either the previous or
+// the new integrations, and the swapped out integrations are closed once no log call uses them, see
+// core.Close. An elasticsearch integration keeping its spool directory is closed before its
+// replacement opens the directory, so the entries logged while it is built are not indexed.
+//
+// Loggers derived from the batch.Logger before a reload keep the integrations they were created
+// with, which the reload closes. Derive them again after a reload.
+type Watcher struct {
+	Path   string
+	Logger *batch.Logger
+	// Interval is the delay between two checks of the file, DefaultWatchInterval when not positive.
+	Interval time.Duration
+	// Current is the configuration the logger was built from, nil when unknown, in which case the
+	// first reload rebuilds every configured integration.
+	Current *Config
+	// OnError is called with reload failures, the running configuration is kept when a reload fails.
+	OnError func(err error)
+
+	mu       sync.Mutex
+	modified time.Time
+	size     int64
+}
+
+func NewWatcher(path string, logger *batch.Logger, opts ...WatcherOption) *Watcher {
+	watcher := &Watcher{
+		Path:     path,
+		Logger:   logger,
+		Interval: DefaultWatchInterval,
+		OnError: func(err error) {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to reload logging config %s: %v\n", path, err)
+		},
+	}
+	for _, opt := range opts {
+		opt(watcher)
+	}
+	return watcher
+}
+
+// WithCurrent sets the configuration the logger was built from.
+func WithCurrent(cfg *Config) WatcherOption {
+	return func(w *Watcher) {
+		w.Current = cfg
+	}
+}
+
+// Run polls the file for changes and listens for SIGHUP until ctx is done.
+func (w *Watcher) Run(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	w.changed()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			w.changed()
+			w.reload(ctx)
+		case <-ticker.C:
+			if w.changed() {
+				w.reload(ctx)
+			}
+		}
+	}
+}
+
+// Reload loads the file and applies it to the logger.
+func (w *Watcher) Reload(ctx context.Context) error {
+	next, err := LoadFile(w.Path)
+	if err != nil {
+		return err
+	}
+	return w.Apply(ctx, next)
+}
+
+// Apply rebuilds the integrations whose section differs from the current configuration, swaps them
+// into the logger and closes the swapped out ones.
+func (w *Watcher) Apply(ctx context.Context, next *Config) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	previous := w.Current
+	rules, err := next.levelRules()
+	if err != nil {
+		return err
+	}
+
+	fieldsChanged := previous == nil || !reflect.DeepEqual(previous.Fields, next.Fields)
+	built := make(map[string]core.Interface)
+	order := make([]string, 0, len(sections))
+	removed := make(map[string]bool)
+	reopened := make([]section, 0)
+	for _, s := range sections {
+		if !s.present(&next.Integrations) {
+			if previous != nil && s.present(&previous.Integrations) {
+				removed[s.Type] = true
+			}
+			continue
+		}
+		if !fieldsChanged && s.present(&previous.Integrations) &&
+			reflect.DeepEqual(s.config(&previous.Integrations), s.config(&next.Integrations)) {
+			continue
+		}
+		if previous != nil && s.present(&previous.Integrations) && s.exclusive != nil &&
+			s.exclusive(&previous.Integrations, &next.Integrations) {
+			reopened = append(reopened, s)
+			continue
+		}
+		integration, err := next.build(s)
+		if err != nil {
+			closeAll(ctx, built)
+			return err
+		}
+		built[s.Type] = integration
+		order = append(order, s.Type)
+	}
+
+	// The integrations sharing a resource with their replacement are swapped out and closed before
+	// the replacement is built, the entries logged meanwhile do not reach them.
+	errs := make([]error, 0)
+	for i, s := range reopened {
+		errs = append(errs, w.swap(ctx, nil, nil, map[string]bool{s.Type: true})...)
+		integration, err := next.build(s)
+		if err != nil {
+			// The running configuration is kept, the closed integrations are built again.
+			closeAll(ctx, built)
+			errs = append(errs, err)
+			for _, s := range reopened[:i+1] {
+				restored, err := previous.build(s)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to restore the %s integration: %w", s.Type, err))
+					continue
+				}
+				w.Logger.AddIntegration(restored)
+			}
+			return errors.Join(errs...)
+		}
+		built[s.Type] = integration
+		order = append(order, s.Type)
+	}
+
+	errs = append(errs, w.swap(ctx, built, order, removed)...)
+	if previous == nil || previous.Levels != next.Levels {
+		w.Logger.SetLevelRules(rules)
+	}
+	w.Current = next
+	return errors.Join(errs...)
+}
+
+// swap replaces the integrations of the types of built, appending the ones of types the logger does
+// not have in order, removes the integrations of the removed types and closes the swapped out ones,
+// see core.Close. UpdateIntegrations returns once no log call uses them.
+func (w *Watcher) swap(ctx context.Context, built map[string]core.Interface, order []string, removed map[string]bool) []error {
+	swapped := make([]core.Interface, 0)
+	w.Logger.UpdateIntegrations(func(integrations []core.Interface) []core.Interface {
+		updated := make([]core.Interface, 0, len(integrations)+len(built))
+		replaced := make(map[string]bool)
+		for _, integration := range integrations {
+			_type := integration.Type()
+			if replacement, ok := built[_type]; ok && !replaced[_type] {
+				updated = append(updated, replacement)
+				replaced[_type] = true
+				swapped = append(swapped, integration)
+				continue
+			}
+			if _, ok := built[_type]; ok || removed[_type] {
+				swapped = append(swapped, integration)
+				continue
+			}
+			updated = append(updated, integration)
+		}
+		for _, _type := range order {
+			if !replaced[_type] {
+				updated = append(updated, built[_type])
+			}
+		}
+		return updated
+	})
+
+	errs := make([]error, 0)
+	for _, integration := range swapped {
+		if err := core.Close(ctx, integration); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %s integration: %w", integration.Type(), err))
+		}
+	}
+	return errs
+}
+
+func closeAll(ctx context.Context, integrations map[string]core.Interface) {
+	for _, integration := range integrations {
+		_ = core.Close(ctx, integration)
+	}
+}
+
+// changed reports whether the modification time or the size of the file changed since the last call.
+func (w *Watcher) changed() bool {
+	info, err := os.Stat(w.Path)
+	if err != nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if info.ModTime().Equal(w.modified) && info.Size() == w.size {
+		return false
+	}
+	w.modified, w.size = info.ModTime(), info.Size()
+	return true
+}
+
+func (w *Watcher) reload(ctx context.Context) {
+	if err := w.Reload(ctx); err != nil {
+		w.OnError(err)
+	}
+}
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ensarkovankaya/go-logging/core"
+	"github.com/ensarkovankaya/go-logging/integrations/batch"
+	"github.com/ensarkovankaya/go-logging/integrations/console"
+	"github.com/ensarkovankaya/go-logging/integrations/file"
+	"github.com/ensarkovankaya/go-logging/integrations/otel"
+)
+
+func TestWatcher_Reload(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	configPath := writeConfig(t, dir, `
+levels: "db=warning"
+integrations:
+  file:
+    path: `+logPath+`
+    level: info
+  otel:
+    exporter: none
+`)
+	cfg, err := LoadFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	logger, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	watcher := NewWatcher(configPath, logger, WithCurrent(cfg))
+	previousFile := logger.GetIntegration(file.Type)
+	previousOtel := logger.GetIntegration(otel.Type)
+
+	writeConfig(t, dir, `
+levels: "db=error"
+integrations:
+  file:
+    path: `+logPath+`
+    level: debug
+  otel:
+    exporter: none
+`)
+	if err = watcher.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if logger.GetIntegration(file.Type) == previousFile {
+		t.Error("Expected the file integration to be replaced")
+	}
+	if level := logger.GetIntegration(file.Type).(*file.Logger).Level; level != core.LevelDebug {
+		t.Errorf("Expected file level debug, got %s", level)
+	}
+	if logger.GetIntegration(otel.Type) != previousOtel {
+		t.Error("Expected the unchanged otel integration to be kept")
+	}
+	if level, _ := logger.LevelRules().Level("db"); level != core.LevelError {
+		t.Errorf("Expected level rule error for db, got %s", level)
+	}
+
+	writeConfig(t, dir, "integrations:\n  otel:\n    exporter: none\n")
+	if err = watcher.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if integrations := logger.Integrations(); len(integrations) != 1 || integrations[0] != previousOtel {
+		t.Errorf("Expected only the otel integration to remain, got %v", integrations)
+	}
+
+	writeConfig(t, dir, "integrations:\n  otel:\n    exporter: zipkin\n")
+	if err = watcher.Reload(context.Background()); err == nil || !strings.Contains(err.Error(), "integrations.otel.exporter") {
+		t.Errorf("Expected validation error, got: %v", err)
+	}
+	if logger.GetIntegration(otel.Type) != previousOtel {
+		t.Error("Expected the running configuration to be kept after an invalid reload")
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeConfig(t, dir, "integrations:\n  otel:\n    exporter: none\n    level: info\n")
+	cfg, err := LoadFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	logger, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	watcher := NewWatcher(configPath, logger, WithCurrent(cfg), func(w *Watcher) {
+		w.Interval = 10 * time.Millisecond
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+
+	time.Sleep(50 * time.Millisecond)
+	writeConfig(t, dir, "integrations:\n  otel:\n    exporter: none\n    level: error\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if level := logger.GetIntegration(otel.Type).(*otel.Logger).Level; level == core.LevelError {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the watcher to reload the changed file")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcher_CloseSwapped(t *testing.T) {
+	dir := t.TempDir()
+	previousPath, nextPath := filepath.Join(dir, "previous.log"), filepath.Join(dir, "next.log")
+	cfg := &Config{Integrations: Integrations{File: &File{Path: previousPath, Level: "info"}}}
+	logger, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	watcher := NewWatcher("", logger, WithCurrent(cfg))
+	previous := logger.GetIntegration(file.Type).(*file.Logger)
+	logger.Info(context.Background(), "previous message")
+
+	next := &Config{Integrations: Integrations{File: &File{Path: nextPath, Level: "info"}}}
+	if err = watcher.Apply(context.Background(), next); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if _, err = previous.Writer.Write([]byte("message\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected the swapped out file to be closed, got: %v", err)
+	}
+	logger.Info(context.Background(), "next message")
+	if err = logger.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if content, _ := os.ReadFile(previousPath); !strings.Contains(string(content), "previous message") {
+		t.Errorf("Expected the previous file to be flushed, got %q", content)
+	}
+	if content, _ := os.ReadFile(nextPath); !strings.Contains(string(content), "next message") {
+		t.Errorf("Expected the logger to write to the next file, got %q", content)
+	}
+}
+
+func TestWatcher_ReopenSpool(t *testing.T) {
+	var mu sync.Mutex
+	up := false
+	indexed := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		mu.Lock()
+		defer mu.Unlock()
+		items := make([]string, 0)
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+		for line := 0; scanner.Scan(); line++ {
+			if !up {
+				// The shards are unavailable, the documents are spooled and replayed.
+				if line%2 == 0 {
+					items = append(items, `{"index":{"status":503,"error":{"type":"unavailable_shards_exception"}}}`)
+				}
+				continue
+			}
+			if line%2 == 0 {
+				items = append(items, `{"index":{"status":201}}`)
+				continue
+			}
+			var document struct {
+				Message string `json:"message"`
+			}
+			_ = json.Unmarshal(scanner.Bytes(), &document)
+			indexed[document.Message]++
+		}
+		_, _ = fmt.Fprintf(w, `{"errors":%t,"items":[%s]}`, !up, strings.Join(items, ","))
+	}))
+	defer server.Close()
+
+	spoolDir := t.TempDir()
+	config := func(flushBytes int) *Config {
+		return &Config{Integrations: Integrations{Elasticsearch: &Elasticsearch{
+			Addresses: []string{server.URL},
+			IndexName: "logs",
+			Level:     "info",
+			Sink:      Sink{FlushBytes: &flushBytes, FlushInterval: "10ms"},
+			Spool:     &Spool{Dir: spoolDir, ReplayInterval: "10ms"},
+		}}}
+	}
+	cfg := config(1 << 10)
+	logger, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	watcher := NewWatcher("", logger, WithCurrent(cfg))
+
+	messages := make([]string, 0)
+	for reload := 0; reload < 3; reload++ {
+		for i := 0; i < 5; i++ {
+			message := fmt.Sprintf("message %d-%d", reload, i)
+			messages = append(messages, message)
+			logger.Info(context.Background(), message)
+		}
+		if err = logger.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+		if reload < 2 {
+			if err = watcher.Apply(context.Background(), config(2<<10+reload)); err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+		}
+	}
+
+	mu.Lock()
+	up = true
+	mu.Unlock()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		delivered := len(indexed)
+		mu.Unlock()
+		if delivered == len(messages) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err = logger.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, message := range messages {
+		if count := indexed[message]; count != 1 {
+			t.Errorf("Expected %q to be indexed once, got %d", message, count)
+		}
+	}
+}
+
+func TestWatcher_ApplyWithoutCurrent(t *testing.T) {
+	logger := batch.New()
+	logger.AddIntegration(console.New())
+	watcher := NewWatcher("", logger)
+	cfg := &Config{Integrations: Integrations{Otel: &Otel{Exporter: "none"}}}
+	if err := watcher.Apply(context.Background(), cfg); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if logger.GetIntegration(console.Type) == nil {
+		t.Error("Expected the integration the document does not describe to be kept")
+	}
+	if logger.GetIntegration(otel.Type) == nil {
+		t.Error("Expected the otel integration to be added")
+	}
+}
+
+func TestWatcher_RunWithoutInterval(t *testing.T) {
+	watcher := NewWatcher(filepath.Join(t.TempDir(), "logging.yaml"), batch.New(), func(w *Watcher) {
+		w.Interval = 0
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	watcher.Run(ctx)
+}
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "logging.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
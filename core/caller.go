@@ -0,0 +1,29 @@
+package core
+
+import (
+	"context"
+)
+
+type callersKey struct{}
+
+// WithCallers returns a copy of ctx carrying the program counters of the call site of a log call,
+// outermost frame last as returned by runtime.Callers. Integrations writing an entry away from its
+// call site, such as the async integration, set them so the caller and stack trace reported by the
+// wrapped integration are the ones of the call site. ctx is returned as is when it already carries
+// program counters.
+func WithCallers(ctx context.Context, pcs []uintptr) context.Context {
+	if len(pcs) == 0 || Callers(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, callersKey{}, pcs)
+}
+
+// Callers returns the program counters set by WithCallers, nil when ctx carries none. The first
+// one is the frame calling the logger of the integration setting them.
+func Callers(ctx context.Context) []uintptr {
+	if ctx == nil {
+		return nil
+	}
+	pcs, _ := ctx.Value(callersKey{}).([]uintptr)
+	return pcs
+}
@@ -1,5 +1,20 @@
 package core
 
+import "net/http"
+
+// Keys of the fields that integrations may map to their own concepts, Sentry for example sends them
+// as tags, user, request and contexts instead of extras. Other integrations write them as any field.
+const (
+	// TagPrefix prefixes the key of a searchable tag, tag.region.
+	TagPrefix = "tag."
+	// ContextPrefix prefixes the key of a group of values, context.order.
+	ContextPrefix = "context."
+	UserIDKey     = "user.id"
+	UserEmailKey  = "user.email"
+	UserNameKey   = "user.name"
+	RequestKey    = "http.request"
+)
+
 type Field struct {
 	Key   string
 	Value any
@@ -20,3 +35,33 @@ func E(err error) Field {
 		Value: err,
 	}
 }
+
+// Tag is a helper function to create a Field object for a searchable tag.
+func Tag(name, value string) Field {
+	return F(TagPrefix+name, value)
+}
+
+// UserID is a helper function to create a Field object for the id of the user.
+func UserID(id string) Field {
+	return F(UserIDKey, id)
+}
+
+// UserEmail is a helper function to create a Field object for the email of the user.
+func UserEmail(email string) Field {
+	return F(UserEmailKey, email)
+}
+
+// UserName is a helper function to create a Field object for the name of the user.
+func UserName(name string) Field {
+	return F(UserNameKey, name)
+}
+
+// Request is a helper function to create a Field object for the HTTP request being handled.
+func Request(req *http.Request) Field {
+	return F(RequestKey, req)
+}
+
+// Context is a helper function to create a Field object for a named group of values.
+func Context(name string, values map[string]any) Field {
+	return F(ContextPrefix+name, values)
+}
@@ -4,15 +4,57 @@ import (
 	"context"
 )
 
+// Interface is implemented by every integration.
+//
+// Fatal and Panic only record the entry at LevelFatal and LevelPanic. Terminating the process is left
+// to batch.Logger, which flushes every integration first so the last entry is not lost: calling
+// Fatal or Panic directly on an integration neither exits nor panics.
 type Interface interface {
 	Type() string
 	Named(string) Interface
 	Clone() Interface
 	WithContext(context.Context) context.Context
 	With(...Field) Interface
+	Trace(ctx context.Context, msg string, fields ...Field)
 	Debug(ctx context.Context, msg string, fields ...Field)
 	Info(ctx context.Context, msg string, fields ...Field)
 	Warning(ctx context.Context, msg string, fields ...Field)
 	Error(ctx context.Context, msg string, fields ...Field)
+	Fatal(ctx context.Context, msg string, fields ...Field)
+	Panic(ctx context.Context, msg string, fields ...Field)
 	Flush(ctx context.Context) error
 }
+
+// LogLevelName is the LevelController name of the level deciding which entries are logged.
+const LogLevelName = "log"
+
+// LevelController is implemented by integrations whose levels can be read and changed at runtime.
+// Level names are integration specific, "log" for integrations with a single level and
+// "log", "event" and "breadcrumb" for sentry. Changes are safe while other goroutines are logging
+// and apply to every logger derived from the same integration.
+type LevelController interface {
+	Levels() map[string]Level
+	SetLevels(levels map[string]Level) error
+}
+
+// HealthChecker is implemented by integrations that can report whether they deliver entries to
+// their destination, for health checks. Health returns nil when they do, or the error that keeps
+// them from it, such as a file integration writing to its stderr fallback.
+type HealthChecker interface {
+	Health() error
+}
+
+// Closer is implemented by integrations holding resources, such as files, goroutines or bulk
+// indexers, to release once they are no longer used. Close flushes the integration first. Loggers
+// derived through Named, With or Clone share these resources, so only one of them is closed.
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// Close closes the integration when it implements Closer and flushes it otherwise.
+func Close(ctx context.Context, integration Interface) error {
+	if closer, ok := integration.(Closer); ok {
+		return closer.Close(ctx)
+	}
+	return integration.Flush(ctx)
+}
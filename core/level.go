@@ -3,8 +3,12 @@ package core
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 )
 
+// Level is the severity of an entry. The numeric values are stable, the levels added after
+// LevelDisabled are appended instead of inserted, so levels are ordered with Enabled rather than by
+// comparing their values.
 type Level int
 
 const (
@@ -13,10 +17,15 @@ const (
 	LevelWarning
 	LevelError
 	LevelDisabled
+	LevelTrace
+	LevelPanic
+	LevelFatal
 )
 
 func (l Level) String() string {
 	switch l {
+	case LevelTrace:
+		return "TRACE"
 	case LevelDebug:
 		return "DEBUG"
 	case LevelInfo:
@@ -25,6 +34,10 @@ func (l Level) String() string {
 		return "WARNING"
 	case LevelError:
 		return "ERROR"
+	case LevelPanic:
+		return "PANIC"
+	case LevelFatal:
+		return "FATAL"
 	case LevelDisabled:
 		return "DISABLED"
 	default:
@@ -35,6 +48,8 @@ func (l Level) String() string {
 func ParseLevel(levelStr string) (Level, error) {
 	serialized := strings.ToUpper(strings.ReplaceAll(levelStr, " ", ""))
 	switch serialized {
+	case "TRACE":
+		return LevelTrace, nil
 	case "DEBUG":
 		return LevelDebug, nil
 	case "INFO":
@@ -43,9 +58,89 @@ func ParseLevel(levelStr string) (Level, error) {
 		return LevelWarning, nil
 	case "ERROR":
 		return LevelError, nil
+	case "PANIC":
+		return LevelPanic, nil
+	case "FATAL":
+		return LevelFatal, nil
 	case "DISABLED", "DISABLE", "OFF":
 		return LevelDisabled, nil
 	default:
 		return -1, fmt.Errorf("unknown log level: %s", levelStr)
 	}
 }
+
+// Enabled reports whether entries at level pass l used as a threshold, that is whether level is at
+// least as severe as l.
+func (l Level) Enabled(level Level) bool {
+	return l.severity() <= level.severity()
+}
+
+// severity orders the levels from LevelTrace to LevelDisabled, unknown levels come first.
+func (l Level) severity() int {
+	switch l {
+	case LevelTrace:
+		return 1
+	case LevelDebug:
+		return 2
+	case LevelInfo:
+		return 3
+	case LevelWarning:
+		return 4
+	case LevelError:
+		return 5
+	case LevelPanic:
+		return 6
+	case LevelFatal:
+		return 7
+	case LevelDisabled:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// MarshalText encodes the level as its lower-case name.
+func (l Level) MarshalText() ([]byte, error) {
+	if l.String() == "" {
+		return nil, fmt.Errorf("unknown log level: %d", l)
+	}
+	return []byte(strings.ToLower(l.String())), nil
+}
+
+// UnmarshalText decodes a level name accepted by ParseLevel.
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// AtomicLevel is a Level that can be read and changed while other goroutines are logging.
+type AtomicLevel struct {
+	level atomic.Int32
+}
+
+func NewAtomicLevel(level Level) *AtomicLevel {
+	atomicLevel := &AtomicLevel{}
+	atomicLevel.Store(level)
+	return atomicLevel
+}
+
+func (a *AtomicLevel) Load() Level {
+	return Level(a.level.Load())
+}
+
+func (a *AtomicLevel) Store(level Level) {
+	a.level.Store(int32(level))
+}
+
+// Enabled reports whether entries at level pass the stored level.
+func (a *AtomicLevel) Enabled(level Level) bool {
+	return a.Load().Enabled(level)
+}
+
+func (a *AtomicLevel) String() string {
+	return a.Load().String()
+}
@@ -0,0 +1,17 @@
+package core
+
+import "testing"
+
+func TestLevel_Enabled(t *testing.T) {
+	if LevelDebug != 1 || LevelInfo != 2 || LevelWarning != 3 || LevelError != 4 || LevelDisabled != 5 {
+		t.Error("Expected the numeric values of the existing levels to be stable")
+	}
+	ordered := []Level{LevelTrace, LevelDebug, LevelInfo, LevelWarning, LevelError, LevelPanic, LevelFatal, LevelDisabled}
+	for i, threshold := range ordered {
+		for j, level := range ordered {
+			if enabled := threshold.Enabled(level); enabled != (j >= i) {
+				t.Errorf("Expected %s enabled by %s to be %t", level, threshold, j >= i)
+			}
+		}
+	}
+}
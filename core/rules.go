@@ -0,0 +1,108 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LevelRule assigns Level to the logger names matching Pattern.
+//
+// Patterns are dotted names like the ones built by Named. A "*" segment matches exactly one name segment,
+// a trailing "*" segment matches one or more segments and the pattern "*" matches every name, including
+// the empty name of the root logger.
+type LevelRule struct {
+	Pattern string
+	Level   Level
+}
+
+// LevelRules is an ordered list of LevelRule.
+//
+// The rules filter the entries before they reach the integrations, so they can only keep entries
+// away from an integration. An integration still applies its own levels to the entries that pass:
+// a "db=debug" rule does not make an integration whose level is info write debug entries.
+type LevelRules []LevelRule
+
+// ParseLevelRules parses rules in the form "db.*=warning,http.client=debug,*=info".
+func ParseLevelRules(rules string) (LevelRules, error) {
+	parsed := make(LevelRules, 0)
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		pattern, levelStr, ok := strings.Cut(rule, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid level rule %q, expected pattern=level", rule)
+		}
+		level, err := ParseLevel(levelStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level rule %q: %w", rule, err)
+		}
+		parsed = append(parsed, LevelRule{Pattern: pattern, Level: level})
+	}
+	return parsed, nil
+}
+
+// Level returns the level of the most specific rule matching name. Rules with more literal segments
+// are more specific, then rules with more segments; remaining ties are won by the earlier rule.
+func (r LevelRules) Level(name string) (Level, bool) {
+	var (
+		matched  bool
+		level    Level
+		literals = -1
+		segments = -1
+	)
+	for _, rule := range r {
+		if !MatchName(rule.Pattern, name) {
+			continue
+		}
+		ruleLiterals, ruleSegments := specificity(rule.Pattern)
+		if ruleLiterals > literals || (ruleLiterals == literals && ruleSegments > segments) {
+			matched, level, literals, segments = true, rule.Level, ruleLiterals, ruleSegments
+		}
+	}
+	return level, matched
+}
+
+func (r LevelRules) String() string {
+	rules := make([]string, 0, len(r))
+	for _, rule := range r {
+		rules = append(rules, fmt.Sprintf("%s=%s", rule.Pattern, strings.ToLower(rule.Level.String())))
+	}
+	return strings.Join(rules, ",")
+}
+
+// MatchName reports whether the dotted logger name matches pattern, see LevelRule for the syntax.
+func MatchName(pattern, name string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if name == "" {
+		return pattern == ""
+	}
+	patternSegments := strings.Split(pattern, ".")
+	nameSegments := strings.Split(name, ".")
+	for i, segment := range patternSegments {
+		if i >= len(nameSegments) {
+			return false
+		}
+		if segment == "*" && i == len(patternSegments)-1 {
+			return true
+		}
+		if segment != "*" && segment != nameSegments[i] {
+			return false
+		}
+	}
+	return len(patternSegments) == len(nameSegments)
+}
+
+func specificity(pattern string) (literals int, segments int) {
+	for _, segment := range strings.Split(pattern, ".") {
+		segments++
+		if segment != "*" {
+			literals++
+		}
+	}
+	return literals, segments
+}
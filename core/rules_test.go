@@ -0,0 +1,75 @@
+package core
+
+import "testing"
+
+func TestMatchName(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*", "", true},
+		{"*", "db", true},
+		{"*", "db.query.slow", true},
+		{"db", "db", true},
+		{"db", "db.query", false},
+		{"db.*", "db", false},
+		{"db.*", "db.query", true},
+		{"db.*", "db.query.slow", true},
+		{"db.*.slow", "db.query.slow", true},
+		{"db.*.slow", "db.query.fast", false},
+		{"db.*.slow", "db.query.slow.sub", false},
+		{"http.client", "http.client", true},
+		{"http.client", "http.server", false},
+		{"http.client", "", false},
+	}
+	for _, c := range cases {
+		if got := MatchName(c.pattern, c.name); got != c.match {
+			t.Errorf("MatchName(%q, %q) = %v, expected %v", c.pattern, c.name, got, c.match)
+		}
+	}
+}
+
+func TestParseLevelRules(t *testing.T) {
+	rules, err := ParseLevelRules(" db.*=warning, http.client = debug ,*=info,")
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+	if rules.String() != "db.*=warning,http.client=debug,*=info" {
+		t.Errorf("unexpected rules: %s", rules.String())
+	}
+	for _, invalid := range []string{"db", "=debug", "db=verbose"} {
+		if _, err = ParseLevelRules(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestLevelRules_Level(t *testing.T) {
+	rules, err := ParseLevelRules("*=info,db.*=warning,db.*.slow=debug,http.client=debug,http.*=error")
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+	cases := map[string]Level{
+		"":                LevelInfo,
+		"api":             LevelInfo,
+		"db.query":        LevelWarning,
+		"db.query.slow":   LevelDebug,
+		"http.client":     LevelDebug,
+		"http.server":     LevelError,
+		"http.client.sub": LevelError,
+	}
+	for name, expected := range cases {
+		level, ok := rules.Level(name)
+		if !ok {
+			t.Errorf("expected a rule to match %q", name)
+			continue
+		}
+		if level != expected {
+			t.Errorf("expected level %s for %q, got %s", expected, name, level)
+		}
+	}
+	if _, ok := (LevelRules{{Pattern: "db", Level: LevelError}}).Level("api"); ok {
+		t.Error("expected no rule to match 'api'")
+	}
+}
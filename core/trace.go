@@ -0,0 +1,30 @@
+package core
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TraceIDKey    = "trace_id"
+	SpanIDKey     = "span_id"
+	TraceFlagsKey = "trace_flags"
+)
+
+// TraceFields returns the trace_id, span_id and trace_flags fields of the OpenTelemetry span active in ctx.
+// It returns nil when ctx carries no valid span context.
+func TraceFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+	return []Field{
+		F(TraceIDKey, spanContext.TraceID().String()),
+		F(SpanIDKey, spanContext.SpanID().String()),
+		F(TraceFlagsKey, spanContext.TraceFlags().String()),
+	}
+}
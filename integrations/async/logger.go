@@ -0,0 +1,400 @@
+// Package async provides a core.Interface wrapper that hands entries to the wrapped integration on
+// a dedicated worker goroutine through a bounded queue, so a slow integration does not block callers.
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ensarkovankaya/go-logging/core"
+	"github.com/ensarkovankaya/go-logging/integrations/batch"
+)
+
+var DefaultQueueSize = 1024
+
+// ErrClosed is returned by Flush and Close once the worker is stopped.
+var ErrClosed = errors.New("async logger is closed")
+
+type Option func(l *Logger)
+
+// Logger queues entries for the wrapped integration and writes them in order on a single worker.
+// Loggers derived through Named, With and Clone share the queue and worker of the logger they were
+// derived from. The context of an entry is detached from its cancellation, its values such as the
+// active span are kept. The call site is captured when an entry is queued and passed to the wrapped
+// integration with core.WithCallers, so the console caller and the Sentry stack trace point at it
+// rather than at the worker.
+//
+// When the queue is full the Policy decides whether the caller waits or an entry is dropped. Fatal
+// and Panic entries are never dropped.
+type Logger struct {
+	Integration core.Interface
+	QueueSize   int
+	Policy      Policy
+
+	worker *worker
+}
+
+// Policy decides what happens to an entry logged while the queue is full.
+type Policy struct {
+	mode  policyMode
+	level core.Level
+}
+
+type policyMode int
+
+const (
+	modeBlock policyMode = iota
+	modeDropNewest
+	modeDropOldest
+	modeDropBelow
+)
+
+var (
+	// Block waits for room in the queue.
+	Block = Policy{mode: modeBlock}
+	// DropNewest drops the entry being logged.
+	DropNewest = Policy{mode: modeDropNewest}
+	// DropOldest drops the oldest queued entry to make room for the entry being logged.
+	DropOldest = Policy{mode: modeDropOldest}
+)
+
+// DropBelow drops entries below level and waits for room for the others.
+func DropBelow(level core.Level) Policy {
+	return Policy{mode: modeDropBelow, level: level}
+}
+
+func New(integration core.Interface, opts ...Option) *Logger {
+	logger := &Logger{
+		Integration: integration,
+		QueueSize:   DefaultQueueSize,
+		Policy:      Block,
+	}
+	for _, opt := range opts {
+		opt(logger)
+	}
+	logger.worker = newWorker(logger.QueueSize, logger.Policy)
+	go logger.worker.run()
+	return logger
+}
+
+// Type returns the type of the wrapped integration, so the logger can be replaced by type in a batch.Logger.
+func (l *Logger) Type() string {
+	return l.Integration.Type()
+}
+
+func (l *Logger) Named(name string) core.Interface {
+	return l.derive(l.Integration.Named(name))
+}
+
+func (l *Logger) Clone() core.Interface {
+	return l.derive(l.Integration.Clone())
+}
+
+func (l *Logger) WithContext(ctx context.Context) context.Context {
+	return l.Integration.WithContext(ctx)
+}
+
+func (l *Logger) With(fields ...core.Field) core.Interface {
+	return l.derive(l.Integration.With(fields...))
+}
+
+func (l *Logger) Trace(ctx context.Context, msg string, fields ...core.Field) {
+	l.enqueue(ctx, core.LevelTrace, msg, fields)
+}
+
+func (l *Logger) Debug(ctx context.Context, msg string, fields ...core.Field) {
+	l.enqueue(ctx, core.LevelDebug, msg, fields)
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, fields ...core.Field) {
+	l.enqueue(ctx, core.LevelInfo, msg, fields)
+}
+
+func (l *Logger) Warning(ctx context.Context, msg string, fields ...core.Field) {
+	l.enqueue(ctx, core.LevelWarning, msg, fields)
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, fields ...core.Field) {
+	l.enqueue(ctx, core.LevelError, msg, fields)
+}
+
+func (l *Logger) Fatal(ctx context.Context, msg string, fields ...core.Field) {
+	l.enqueue(ctx, core.LevelFatal, msg, fields)
+}
+
+func (l *Logger) Panic(ctx context.Context, msg string, fields ...core.Field) {
+	l.enqueue(ctx, core.LevelPanic, msg, fields)
+}
+
+// Flush waits for the entries queued before the call to be written, then flushes the wrapped
+// integration. It returns ctx.Err() when ctx is done first, the queued entries are still written.
+func (l *Logger) Flush(ctx context.Context) error {
+	flushed := make(chan error, 1)
+	marker := entry{ctx: ctx, integration: l.Integration, flushed: flushed}
+	select {
+	case <-l.worker.closed:
+		return ErrClosed
+	default:
+	}
+	select {
+	case l.worker.queue <- marker:
+	case <-l.worker.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-flushed:
+		return err
+	case <-l.worker.stopped:
+		// The worker may have stopped without reaching the marker when Close raced with this call.
+		select {
+		case err := <-flushed:
+			return err
+		default:
+			return ErrClosed
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes the queue, stops the worker and closes the wrapped integration, see core.Close.
+// Entries logged afterwards are dropped.
+func (l *Logger) Close(ctx context.Context) error {
+	err := l.Flush(ctx)
+	closing := false
+	l.worker.closeOnce.Do(func() {
+		closing = true
+		close(l.worker.closed)
+	})
+	select {
+	case <-l.worker.stopped:
+	case <-ctx.Done():
+		return errors.Join(err, ctx.Err())
+	}
+	if errors.Is(err, ErrClosed) {
+		return nil
+	}
+	if closing {
+		err = errors.Join(err, core.Close(ctx, l.Integration))
+	}
+	return err
+}
+
+// Dropped returns the number of entries dropped by the overflow policy or after Close.
+func (l *Logger) Dropped() uint64 {
+	return l.worker.dropped.Load()
+}
+
+// Levels returns the levels of the wrapped integration when it implements core.LevelController.
+func (l *Logger) Levels() map[string]core.Level {
+	if controller, ok := l.Integration.(core.LevelController); ok {
+		return controller.Levels()
+	}
+	return map[string]core.Level{}
+}
+
+// SetLevels changes the levels of the wrapped integration when it implements core.LevelController.
+func (l *Logger) SetLevels(levels map[string]core.Level) error {
+	if controller, ok := l.Integration.(core.LevelController); ok {
+		return controller.SetLevels(levels)
+	}
+	if len(levels) > 0 {
+		return fmt.Errorf("%s integration has no adjustable levels", l.Integration.Type())
+	}
+	return nil
+}
+
+// Health returns the health of the wrapped integration when it implements core.HealthChecker.
+func (l *Logger) Health() error {
+	if checker, ok := l.Integration.(core.HealthChecker); ok {
+		return checker.Health()
+	}
+	return nil
+}
+
+func (l *Logger) derive(integration core.Interface) *Logger {
+	_l := *l
+	_l.Integration = integration
+	return &_l
+}
+
+// maxCallers is the number of frames of the call site captured for an entry.
+const maxCallers = 32
+
+func (l *Logger) enqueue(ctx context.Context, level core.Level, msg string, fields []core.Field) {
+	if core.Callers(ctx) == nil {
+		pcs := make([]uintptr, maxCallers)
+		// Skip runtime.Callers, enqueue and the logging method, the first frame is the caller of the logger.
+		ctx = core.WithCallers(ctx, pcs[:runtime.Callers(3, pcs)])
+	}
+	l.worker.enqueue(entry{
+		ctx:         context.WithoutCancel(ctx),
+		integration: l.Integration,
+		level:       level,
+		msg:         msg,
+		fields:      append([]core.Field(nil), fields...),
+	})
+}
+
+// entry is a queued log call, or a flush marker when flushed is set.
+type entry struct {
+	ctx         context.Context
+	integration core.Interface
+	level       core.Level
+	msg         string
+	fields      []core.Field
+	flushed     chan<- error
+}
+
+type worker struct {
+	queue     chan entry
+	policy    Policy
+	dropped   atomic.Uint64
+	closed    chan struct{}
+	closeOnce sync.Once
+	stopped   chan struct{}
+}
+
+func newWorker(size int, policy Policy) *worker {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	return &worker{
+		queue:   make(chan entry, size),
+		policy:  policy,
+		closed:  make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+func (w *worker) run() {
+	defer close(w.stopped)
+	for {
+		select {
+		case e := <-w.queue:
+			w.process(e)
+		case <-w.closed:
+			for {
+				select {
+				case e := <-w.queue:
+					w.process(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (w *worker) enqueue(e entry) {
+	select {
+	case <-w.closed:
+		w.dropped.Add(1)
+		return
+	default:
+	}
+	select {
+	case w.queue <- e:
+		return
+	default:
+	}
+	switch {
+	case core.LevelPanic.Enabled(e.level), w.policy.mode == modeBlock,
+		w.policy.mode == modeDropBelow && w.policy.level.Enabled(e.level):
+		select {
+		case w.queue <- e:
+		case <-w.closed:
+			w.dropped.Add(1)
+		}
+	case w.policy.mode == modeDropOldest:
+		w.replaceOldest(e)
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// replaceOldest drops queued entries until e fits. Flush markers are queued again instead of dropped,
+// once only markers remain it waits for the worker to make room.
+func (w *worker) replaceOldest(e entry) {
+	for markers := 0; ; {
+		select {
+		case w.queue <- e:
+			return
+		default:
+		}
+		if markers >= cap(w.queue) {
+			select {
+			case w.queue <- e:
+			case <-w.closed:
+				w.dropped.Add(1)
+			}
+			return
+		}
+		select {
+		case oldest := <-w.queue:
+			if oldest.flushed != nil {
+				markers++
+				select {
+				case w.queue <- oldest:
+				case <-w.closed:
+					oldest.flushed <- ErrClosed
+				}
+			} else {
+				w.dropped.Add(1)
+			}
+		default:
+		}
+	}
+}
+
+func (w *worker) process(e entry) {
+	defer func() {
+		// A panicking integration must not stop the worker, the remaining entries are still written.
+		if r := recover(); r != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Async %s integration panicked: %v\n", e.integration.Type(), r)
+			if e.flushed != nil {
+				e.flushed <- fmt.Errorf("async %s integration panicked: %v", e.integration.Type(), r)
+			}
+		}
+	}()
+	if e.flushed != nil {
+		e.flushed <- e.integration.Flush(e.ctx)
+		return
+	}
+	switch e.level {
+	case core.LevelTrace:
+		e.integration.Trace(e.ctx, e.msg, e.fields...)
+	case core.LevelDebug:
+		e.integration.Debug(e.ctx, e.msg, e.fields...)
+	case core.LevelInfo:
+		e.integration.Info(e.ctx, e.msg, e.fields...)
+	case core.LevelWarning:
+		e.integration.Warning(e.ctx, e.msg, e.fields...)
+	case core.LevelError:
+		e.integration.Error(e.ctx, e.msg, e.fields...)
+	case core.LevelFatal:
+		e.integration.Fatal(e.ctx, e.msg, e.fields...)
+	case core.LevelPanic:
+		e.integration.Panic(e.ctx, e.msg, e.fields...)
+	}
+}
+
+// WrapAll wraps every integration of logger that is not asynchronous yet, so each one gets its own
+// queue and worker.
+func WrapAll(logger *batch.Logger, opts ...Option) {
+	logger.UpdateIntegrations(func(integrations []core.Interface) []core.Interface {
+		for i, integration := range integrations {
+			if _, ok := integration.(*Logger); !ok {
+				integrations[i] = New(integration, opts...)
+			}
+		}
+		return integrations
+	})
+}
@@ -0,0 +1,347 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/ensarkovankaya/go-logging/core"
+	"github.com/ensarkovankaya/go-logging/integrations/batch"
+	"github.com/ensarkovankaya/go-logging/integrations/console"
+)
+
+// mockIntegration records messages into a store shared with the integrations derived from it.
+type mockIntegration struct {
+	*store
+	name string
+}
+
+type store struct {
+	mu       sync.Mutex
+	messages []string
+	flushed  int
+	// gate blocks every write until it is closed.
+	gate chan struct{}
+}
+
+func newMockIntegration() *mockIntegration {
+	gate := make(chan struct{})
+	close(gate)
+	return &mockIntegration{store: &store{gate: gate}}
+}
+
+func (m *mockIntegration) Type() string {
+	return "mock"
+}
+
+func (m *mockIntegration) Named(name string) core.Interface {
+	_m := *m
+	_m.name = name
+	return &_m
+}
+
+func (m *mockIntegration) Clone() core.Interface {
+	return m
+}
+
+func (m *mockIntegration) WithContext(ctx context.Context) context.Context {
+	return ctx
+}
+
+func (m *mockIntegration) With(_ ...core.Field) core.Interface {
+	return m
+}
+
+func (m *mockIntegration) Trace(_ context.Context, msg string, _ ...core.Field) {
+	m.record(msg)
+}
+
+func (m *mockIntegration) Debug(_ context.Context, msg string, _ ...core.Field) {
+	m.record(msg)
+}
+
+func (m *mockIntegration) Info(_ context.Context, msg string, _ ...core.Field) {
+	m.record(msg)
+}
+
+func (m *mockIntegration) Warning(_ context.Context, msg string, _ ...core.Field) {
+	m.record(msg)
+}
+
+func (m *mockIntegration) Error(_ context.Context, msg string, _ ...core.Field) {
+	m.record(msg)
+}
+
+func (m *mockIntegration) Fatal(_ context.Context, msg string, _ ...core.Field) {
+	m.record(msg)
+}
+
+func (m *mockIntegration) Panic(_ context.Context, msg string, _ ...core.Field) {
+	m.record(msg)
+}
+
+func (m *mockIntegration) Flush(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flushed++
+	return nil
+}
+
+func (m *mockIntegration) Flushed() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.flushed
+}
+
+func (m *mockIntegration) record(msg string) {
+	<-m.gate
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.name != "" {
+		msg = m.name + ": " + msg
+	}
+	m.messages = append(m.messages, msg)
+}
+
+func (m *mockIntegration) Messages() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.messages...)
+}
+
+func TestLogger_FlushInOrder(t *testing.T) {
+	integration := newMockIntegration()
+	logger := New(integration)
+	ctx := context.Background()
+	logger.Info(ctx, "first")
+	logger.Named("sub").Warning(ctx, "second")
+	logger.Error(ctx, "third")
+	if err := logger.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	assertMessages(t, integration.Messages(), "first", "sub: second", "third")
+	if integration.Flushed() != 1 {
+		t.Errorf("Expected the integration to be flushed once, got %d", integration.Flushed())
+	}
+	if logger.Type() != "mock" {
+		t.Errorf("Expected the wrapped integration type, got %s", logger.Type())
+	}
+}
+
+func TestLogger_Policies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		expected []string
+		dropped  uint64
+	}{
+		{"DropNewest", DropNewest, []string{"blocked", "1", "2"}, 2},
+		{"DropOldest", DropOldest, []string{"blocked", "3", "4"}, 2},
+		{"DropBelow", DropBelow(core.LevelWarning), []string{"blocked", "1", "2", "4"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			integration, release := newBlockedIntegration()
+			logger := New(integration, func(l *Logger) {
+				l.QueueSize = 2
+				l.Policy = tt.policy
+			})
+			ctx := context.Background()
+			logger.Info(ctx, "blocked")
+			waitForQueue(t, logger, 0)
+			logger.Info(ctx, "1")
+			logger.Info(ctx, "2")
+			logger.Info(ctx, "3")
+			// With DropBelow the warning waits for room since it is at or above the policy level.
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				logger.Warning(ctx, "4")
+			}()
+			if tt.policy.mode != modeDropBelow {
+				<-done
+			}
+			release()
+			<-done
+			if err := logger.Flush(ctx); err != nil {
+				t.Fatalf("Flush failed: %v", err)
+			}
+			assertMessages(t, integration.Messages(), tt.expected...)
+			if logger.Dropped() != tt.dropped {
+				t.Errorf("Expected %d dropped entries, got %d", tt.dropped, logger.Dropped())
+			}
+		})
+	}
+}
+
+func TestLogger_FatalIsNeverDropped(t *testing.T) {
+	integration, release := newBlockedIntegration()
+	logger := New(integration, func(l *Logger) {
+		l.QueueSize = 1
+		l.Policy = DropNewest
+	})
+	ctx := context.Background()
+	logger.Info(ctx, "blocked")
+	waitForQueue(t, logger, 0)
+	logger.Info(ctx, "1")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Fatal(ctx, "fatal")
+	}()
+	release()
+	<-done
+	if err := logger.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	assertMessages(t, integration.Messages(), "blocked", "1", "fatal")
+}
+
+func TestLogger_FlushDeadline(t *testing.T) {
+	integration, release := newBlockedIntegration()
+	defer release()
+	logger := New(integration)
+	logger.Info(context.Background(), "blocked")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := logger.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestLogger_Close(t *testing.T) {
+	integration := newMockIntegration()
+	logger := New(integration)
+	ctx := context.Background()
+	logger.Info(ctx, "before")
+	if err := logger.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	logger.Info(ctx, "after")
+	assertMessages(t, integration.Messages(), "before")
+	if logger.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped entry, got %d", logger.Dropped())
+	}
+	if err := logger.Flush(ctx); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	if err := logger.Close(ctx); err != nil {
+		t.Errorf("Expected a second Close to succeed, got %v", err)
+	}
+}
+
+func TestLogger_Concurrent(t *testing.T) {
+	integration := newMockIntegration()
+	logger := New(integration, func(l *Logger) {
+		l.QueueSize = 8
+	})
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info(ctx, "message")
+			}
+		}()
+	}
+	wg.Wait()
+	if err := logger.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if len(integration.Messages()) != 400 {
+		t.Errorf("Expected 400 messages, got %d", len(integration.Messages()))
+	}
+}
+
+func TestWrapAll(t *testing.T) {
+	integration := newMockIntegration()
+	logger := batch.New()
+	logger.AddIntegration(integration)
+	WrapAll(logger)
+	WrapAll(logger)
+
+	wrapped, ok := logger.GetIntegration("mock").(*Logger)
+	if !ok {
+		t.Fatalf("Expected the integration to be wrapped, got %T", logger.GetIntegration("mock"))
+	}
+	if wrapped.Integration != integration {
+		t.Error("Expected the integration to be wrapped once")
+	}
+	ctx := context.Background()
+	logger.Info(ctx, "message")
+	if err := logger.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	assertMessages(t, integration.Messages(), "message")
+}
+
+// newBlockedIntegration returns an integration whose writes wait until release is called.
+func newBlockedIntegration() (*mockIntegration, func()) {
+	integration := newMockIntegration()
+	integration.gate = make(chan struct{})
+	var once sync.Once
+	return integration, func() {
+		once.Do(func() {
+			close(integration.gate)
+		})
+	}
+}
+
+// waitForQueue waits until the worker took entries off the queue so it holds length entries.
+func waitForQueue(t *testing.T, logger *Logger, length int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(logger.worker.queue) != length {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d queued entries, got %d", length, len(logger.worker.queue))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func assertMessages(t *testing.T, messages []string, expected ...string) {
+	t.Helper()
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected messages %v, got %v", expected, messages)
+	}
+	for i := range expected {
+		if messages[i] != expected[i] {
+			t.Fatalf("Expected messages %v, got %v", expected, messages)
+		}
+	}
+}
+
+func TestLogger_CallSite(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	logger := batch.New()
+	logger.AddIntegration(New(console.New(func(l *console.Logger) {
+		l.Transport = zap.New(observed, zap.AddCaller(), zap.AddCallerSkip(2), zap.AddStacktrace(zapcore.ErrorLevel))
+	})))
+	_, _, line, _ := runtime.Caller(0)
+	logger.Error(context.Background(), "error message")
+	if err := logger.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	caller := entries[0].Caller
+	if !strings.HasSuffix(caller.File, "async/logger_test.go") || caller.Line != line+1 {
+		t.Errorf("Expected the caller to be the call site, got %s", caller.String())
+	}
+	if !strings.HasPrefix(entries[0].Stack, caller.Function+"\n") {
+		t.Errorf("Expected the stack trace to start at the call site, got %s", entries[0].Stack)
+	}
+}
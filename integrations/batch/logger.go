@@ -3,21 +3,70 @@ package batch
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
 
 	"github.com/ensarkovankaya/go-logging/core"
 )
 
 const Type = "batch"
 
+var (
+	defaultLevelRules = core.LevelRules{}
+)
+
+var (
+	envLevelRules = "LOG_LEVELS"
+)
+
+// exit terminates the process after Fatal, it is replaced in tests.
+var exit = os.Exit
+
 // Logger aggregates multiple core.Interface instances.
+//
+// Level rules are matched against the dotted name built by Named, entries below the level of the
+// most specific matching rule are dropped before they reach any integration.
+//
+// Integrations and level rules can be changed while other goroutines are logging. Log calls read an
+// immutable snapshot without locking, changes are serialized and publish a new snapshot, then wait
+// for the calls still using the previous one to return. Every call therefore reaches either the
+// previous or the new integrations, and a removed integration receives no call once the change
+// returns. Loggers already derived through Named, With or Clone keep the integrations they were
+// created with.
 type Logger struct {
+	// mu serializes changes, log calls never take it.
+	mu    sync.Mutex
+	state atomic.Pointer[state]
+	name  string
+}
+
+// retiredBias is added to the call count of a retired snapshot, it is larger than any count of calls.
+const retiredBias = 1 << 40
+
+// state is an immutable snapshot of the integrations and level rules of a Logger.
+type state struct {
 	integrations []core.Interface
+	rules        core.LevelRules
+	// level is the level resolved from rules for the logger name, zero when no rule matches.
+	level core.Level
+	// inflight counts the calls using this snapshot, offset by retiredBias once it is retired.
+	// drained is closed once it is retired and unused.
+	inflight  atomic.Int64
+	drained   chan struct{}
+	drainOnce sync.Once
+}
+
+func newState(integrations []core.Interface, rules core.LevelRules, level core.Level) *state {
+	return &state{integrations: integrations, rules: rules, level: level, drained: make(chan struct{})}
 }
 
 func New() *Logger {
-	return &Logger{
-		integrations: make([]core.Interface, 0),
-	}
+	logger := &Logger{}
+	logger.state.Store(newState(make([]core.Interface, 0), nil, 0))
+	logger.SetLevelRules(defaultLevelRules)
+	return logger
 }
 
 func (l *Logger) Type() string {
@@ -25,84 +74,186 @@ func (l *Logger) Type() string {
 }
 
 func (l *Logger) Named(name string) core.Interface {
-	_l := &Logger{
-		integrations: make([]core.Interface, 0, len(l.integrations)),
+	s := l.state.Load()
+	_l := &Logger{name: l.name}
+	switch {
+	case name == "":
+	case l.name != "":
+		_l.name = fmt.Sprintf("%s.%s", l.name, name)
+	default:
+		_l.name = name
 	}
-	for _, integration := range l.integrations {
-		_l.integrations = append(_l.integrations, integration.Named(name))
+	integrations := make([]core.Interface, 0, len(s.integrations))
+	for _, integration := range s.integrations {
+		integrations = append(integrations, integration.Named(name))
 	}
+	level, _ := s.rules.Level(_l.name)
+	_l.state.Store(newState(integrations, s.rules, level))
 	return _l
 }
 
 func (l *Logger) WithContext(ctx context.Context) context.Context {
-	for _, integration := range l.integrations {
+	for _, integration := range l.state.Load().integrations {
 		ctx = integration.WithContext(ctx)
 	}
 	return ctx
 }
 
 func (l *Logger) With(fields ...core.Field) core.Interface {
-	_t := &Logger{
-		integrations: make([]core.Interface, 0, len(l.integrations)),
-	}
-	for _, integration := range l.integrations {
-		_t.integrations = append(_t.integrations, integration.With(fields...))
-	}
-	return _t
+	return l.derive(func(integration core.Interface) core.Interface {
+		return integration.With(fields...)
+	})
 }
 
 func (l *Logger) Clone() core.Interface {
-	_t := &Logger{
-		integrations: make([]core.Interface, 0, len(l.integrations)),
+	return l.derive(core.Interface.Clone)
+}
+
+func (l *Logger) Trace(ctx context.Context, msg string, fields ...core.Field) {
+	s := l.acquire()
+	defer s.release()
+	if !s.enabled(core.LevelTrace) {
+		return
 	}
-	for _, integration := range l.integrations {
-		_t.integrations = append(_t.integrations, integration.Clone())
+	for _, integration := range s.integrations {
+		integration.Trace(ctx, msg, fields...)
 	}
-	return _t
 }
 
 func (l *Logger) Debug(ctx context.Context, msg string, fields ...core.Field) {
-	for _, integration := range l.integrations {
+	s := l.acquire()
+	defer s.release()
+	if !s.enabled(core.LevelDebug) {
+		return
+	}
+	for _, integration := range s.integrations {
 		integration.Debug(ctx, msg, fields...)
 	}
 }
 
 func (l *Logger) Info(ctx context.Context, msg string, fields ...core.Field) {
-	for _, integration := range l.integrations {
+	s := l.acquire()
+	defer s.release()
+	if !s.enabled(core.LevelInfo) {
+		return
+	}
+	for _, integration := range s.integrations {
 		integration.Info(ctx, msg, fields...)
 	}
 }
 
 func (l *Logger) Warning(ctx context.Context, msg string, fields ...core.Field) {
-	for _, integration := range l.integrations {
+	s := l.acquire()
+	defer s.release()
+	if !s.enabled(core.LevelWarning) {
+		return
+	}
+	for _, integration := range s.integrations {
 		integration.Warning(ctx, msg, fields...)
 	}
 }
 
 func (l *Logger) Error(ctx context.Context, msg string, fields ...core.Field) {
-	for _, integration := range l.integrations {
+	s := l.acquire()
+	defer s.release()
+	if !s.enabled(core.LevelError) {
+		return
+	}
+	for _, integration := range s.integrations {
 		integration.Error(ctx, msg, fields...)
 	}
 }
 
+// Fatal logs the message on every integration, flushes them and exits the process with status 1.
+// The process exits even when a level rule drops the entry.
+func (l *Logger) Fatal(ctx context.Context, msg string, fields ...core.Field) {
+	s := l.acquire()
+	if s.enabled(core.LevelFatal) {
+		for _, integration := range s.integrations {
+			integration.Fatal(ctx, msg, fields...)
+		}
+	}
+	s.release()
+	l.flushBeforeExit(ctx)
+	exit(1)
+}
+
+// Panic logs the message on every integration, flushes them and panics with the message.
+// It panics even when a level rule drops the entry.
+func (l *Logger) Panic(ctx context.Context, msg string, fields ...core.Field) {
+	s := l.acquire()
+	if s.enabled(core.LevelPanic) {
+		for _, integration := range s.integrations {
+			integration.Panic(ctx, msg, fields...)
+		}
+	}
+	s.release()
+	l.flushBeforeExit(ctx)
+	panic(msg)
+}
+
 func (l *Logger) AddIntegration(integration core.Interface) {
-	if integration != nil {
-		l.integrations = append(l.integrations, integration)
+	if integration == nil {
+		return
 	}
+	l.UpdateIntegrations(func(integrations []core.Interface) []core.Interface {
+		return append(integrations, integration)
+	})
 }
 
+// ReplaceIntegration replaces the first integration of the given type, or adds it when there is none.
+// The replaced integration is not closed since loggers derived before the call may still use it,
+// use UpdateIntegrations to release it.
 func (l *Logger) ReplaceIntegration(_type string, integration core.Interface) {
-	for i, existing := range l.integrations {
-		if existing.Type() == _type {
-			l.integrations[i] = integration
-			return
+	l.UpdateIntegrations(func(integrations []core.Interface) []core.Interface {
+		for i, existing := range integrations {
+			if existing.Type() == _type {
+				integrations[i] = integration
+				return integrations
+			}
 		}
+		return append(integrations, integration)
+	})
+}
+
+// RemoveIntegration removes the first integration of the given type and flushes it. It does nothing
+// when there is no integration of the type. The removed integration is not closed since loggers
+// derived before the call may still use it, close it with core.Close once they are no longer used.
+func (l *Logger) RemoveIntegration(ctx context.Context, _type string) error {
+	var removed core.Interface
+	l.UpdateIntegrations(func(integrations []core.Interface) []core.Interface {
+		for i, existing := range integrations {
+			if existing.Type() == _type {
+				removed = existing
+				return append(integrations[:i], integrations[i+1:]...)
+			}
+		}
+		return integrations
+	})
+	if removed == nil {
+		return nil
 	}
-	l.AddIntegration(integration)
+	return removed.Flush(ctx)
+}
+
+// UpdateIntegrations replaces the integration list with the result of update in a single step, nil
+// integrations are dropped. update is called once with a copy of the current list it may modify.
+// It returns once no log call uses the previous list anymore, so it must not be called from an
+// integration while it is logging.
+func (l *Logger) UpdateIntegrations(update func(integrations []core.Interface) []core.Interface) {
+	l.update(func(s *state) *state {
+		integrations := make([]core.Interface, 0, len(s.integrations))
+		for _, integration := range update(append([]core.Interface{}, s.integrations...)) {
+			if integration != nil {
+				integrations = append(integrations, integration)
+			}
+		}
+		return newState(integrations, s.rules, s.level)
+	})
 }
 
 func (l *Logger) GetIntegration(_type string) core.Interface {
-	for _, integration := range l.integrations {
+	for _, integration := range l.state.Load().integrations {
 		if integration.Type() == _type {
 			return integration
 		}
@@ -110,12 +261,131 @@ func (l *Logger) GetIntegration(_type string) core.Interface {
 	return nil
 }
 
+// Integrations returns a copy of the integration list.
+func (l *Logger) Integrations() []core.Interface {
+	return append([]core.Interface{}, l.state.Load().integrations...)
+}
+
+// SetLevelRules replaces the level rules of the logger. Loggers already derived through Named, With
+// or Clone keep the rules they were created with.
+func (l *Logger) SetLevelRules(rules core.LevelRules) {
+	l.update(func(s *state) *state {
+		level, _ := rules.Level(l.name)
+		return newState(s.integrations, rules, level)
+	})
+}
+
+func (l *Logger) LevelRules() core.LevelRules {
+	return l.state.Load().rules
+}
+
 func (l *Logger) Flush(ctx context.Context) error {
 	errs := make([]error, 0)
-	for _, integration := range l.integrations {
+	for _, integration := range l.state.Load().integrations {
 		if err := integration.Flush(ctx); err != nil {
 			errs = append(errs, err)
 		}
 	}
 	return errors.Join(errs...)
 }
+
+// Close closes every integration, see core.Close. The integrations are shared with the loggers
+// derived from this one, which must not be used afterwards.
+func (l *Logger) Close(ctx context.Context) error {
+	errs := make([]error, 0)
+	for _, integration := range l.state.Load().integrations {
+		if err := core.Close(ctx, integration); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", integration.Type(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Health returns the health errors of the integrations implementing core.HealthChecker, nil when
+// all of them are healthy.
+func (l *Logger) Health() error {
+	errs := make([]error, 0)
+	for _, integration := range l.state.Load().integrations {
+		if checker, ok := integration.(core.HealthChecker); ok {
+			if err := checker.Health(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", integration.Type(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// acquire returns the current snapshot registered as in use, it must be released once the call returns.
+func (l *Logger) acquire() *state {
+	for {
+		s := l.state.Load()
+		s.inflight.Add(1)
+		// A change may have published a new snapshot between the load and the registration, in which
+		// case it may already have stopped waiting for this one.
+		if l.state.Load() == s {
+			return s
+		}
+		s.release()
+	}
+}
+
+// update publishes the snapshot returned by next and waits for the calls using the previous one.
+func (l *Logger) update(next func(s *state) *state) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	previous := l.state.Load()
+	l.state.Store(next(previous))
+	previous.retire()
+}
+
+// derive returns a Logger sharing the name and level rules of l, with every integration passed through fn.
+func (l *Logger) derive(fn func(integration core.Interface) core.Interface) *Logger {
+	s := l.state.Load()
+	integrations := make([]core.Interface, 0, len(s.integrations))
+	for _, integration := range s.integrations {
+		integrations = append(integrations, fn(integration))
+	}
+	_l := &Logger{name: l.name}
+	_l.state.Store(newState(integrations, s.rules, s.level))
+	return _l
+}
+
+// flushBeforeExit flushes every integration, reporting failures to stderr since there is no caller to return them to.
+func (l *Logger) flushBeforeExit(ctx context.Context) {
+	if err := l.Flush(context.WithoutCancel(ctx)); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to flush logger integrations: %v\n", err)
+	}
+}
+
+func (s *state) release() {
+	if s.inflight.Add(-1) == retiredBias {
+		s.drainOnce.Do(func() {
+			close(s.drained)
+		})
+	}
+}
+
+// retire waits for the calls using s to return. The bias and the count share one word, so a call
+// releasing s observes both in the same step: checking a separate retired flag after the decrement
+// would let a release that reached zero before the retirement close drained while a call acquired
+// since then is still running.
+func (s *state) retire() {
+	if s.inflight.Add(retiredBias) == retiredBias {
+		return
+	}
+	<-s.drained
+}
+
+func (s *state) enabled(level core.Level) bool {
+	return s.level == 0 || s.level.Enabled(level)
+}
+
+func init() {
+	if os.Getenv(envLevelRules) != "" {
+		if rules, err := core.ParseLevelRules(os.Getenv(envLevelRules)); err == nil {
+			defaultLevelRules = rules
+		} else {
+			_, _ = fmt.Fprintf(os.Stderr, "Invalid %s environment value, ignoring level rules: %v\n", envLevelRules, err)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+type mockIntegration struct {
+	_type   string
+	mu      *sync.Mutex
+	levels  []core.Level
+	flushed int
+}
+
+func newMockIntegration(_type string) *mockIntegration {
+	return &mockIntegration{_type: _type, mu: &sync.Mutex{}}
+}
+
+func (m *mockIntegration) Type() string {
+	return m._type
+}
+
+func (m *mockIntegration) Named(_ string) core.Interface {
+	return m
+}
+
+func (m *mockIntegration) Clone() core.Interface {
+	return m
+}
+
+func (m *mockIntegration) WithContext(ctx context.Context) context.Context {
+	return ctx
+}
+
+func (m *mockIntegration) With(_ ...core.Field) core.Interface {
+	return m
+}
+
+func (m *mockIntegration) Trace(_ context.Context, _ string, _ ...core.Field) {
+	m.record(core.LevelTrace)
+}
+
+func (m *mockIntegration) Debug(_ context.Context, _ string, _ ...core.Field) {
+	m.record(core.LevelDebug)
+}
+
+func (m *mockIntegration) Info(_ context.Context, _ string, _ ...core.Field) {
+	m.record(core.LevelInfo)
+}
+
+func (m *mockIntegration) Warning(_ context.Context, _ string, _ ...core.Field) {
+	m.record(core.LevelWarning)
+}
+
+func (m *mockIntegration) Error(_ context.Context, _ string, _ ...core.Field) {
+	m.record(core.LevelError)
+}
+
+func (m *mockIntegration) Fatal(_ context.Context, _ string, _ ...core.Field) {
+	m.record(core.LevelFatal)
+}
+
+func (m *mockIntegration) Panic(_ context.Context, _ string, _ ...core.Field) {
+	m.record(core.LevelPanic)
+}
+
+func (m *mockIntegration) Flush(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flushed++
+	return nil
+}
+
+func (m *mockIntegration) record(level core.Level) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.levels = append(m.levels, level)
+}
+
+func (m *mockIntegration) Levels() []core.Level {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]core.Level{}, m.levels...)
+}
+
+func (m *mockIntegration) Flushed() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.flushed
+}
+
+func TestLogger_Fatal(t *testing.T) {
+	var exitCode int
+	defer func(original func(int)) {
+		exit = original
+	}(exit)
+	exit = func(code int) {
+		exitCode = code
+	}
+
+	first, second := newMockIntegration("first"), newMockIntegration("second")
+	logger := New()
+	logger.AddIntegration(first)
+	logger.AddIntegration(second)
+	logger.Fatal(context.Background(), "fatal message")
+
+	if exitCode != 1 {
+		t.Errorf("expected exit code 1, got %d", exitCode)
+	}
+	for _, integration := range []*mockIntegration{first, second} {
+		if levels := integration.Levels(); len(levels) != 1 || levels[0] != core.LevelFatal {
+			t.Errorf("expected %s to receive one fatal entry, got %v", integration.Type(), levels)
+		}
+		if integration.Flushed() != 1 {
+			t.Errorf("expected %s to be flushed once before exit, got %d", integration.Type(), integration.Flushed())
+		}
+	}
+}
+
+func TestLogger_Panic(t *testing.T) {
+	integration := newMockIntegration("mock")
+	logger := New()
+	logger.AddIntegration(integration)
+
+	defer func() {
+		if recovered := recover(); recovered != "panic message" {
+			t.Errorf("expected panic with 'panic message', got %v", recovered)
+		}
+		if levels := integration.Levels(); len(levels) != 1 || levels[0] != core.LevelPanic {
+			t.Errorf("expected one panic entry, got %v", levels)
+		}
+		if integration.Flushed() != 1 {
+			t.Errorf("expected integration to be flushed once before panic, got %d", integration.Flushed())
+		}
+	}()
+	logger.Panic(context.Background(), "panic message")
+}
+
+func TestLogger_LevelRules(t *testing.T) {
+	rules, err := core.ParseLevelRules("db.*=warning,http.client=debug,*=info")
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+	integration := newMockIntegration("mock")
+	logger := New()
+	logger.AddIntegration(integration)
+	logger.SetLevelRules(rules)
+
+	ctx := context.Background()
+	cases := []struct {
+		logger   core.Interface
+		expected []core.Level
+	}{
+		{logger, []core.Level{core.LevelInfo, core.LevelWarning}},
+		{logger.Named("db").Named("query"), []core.Level{core.LevelWarning}},
+		{logger.Named("http").Named("client"), []core.Level{core.LevelDebug, core.LevelInfo, core.LevelWarning}},
+		{logger.Named("http").With(core.F("key", "value")).Named("client"), []core.Level{core.LevelDebug, core.LevelInfo, core.LevelWarning}},
+	}
+	for _, c := range cases {
+		integration.levels = nil
+		c.logger.Trace(ctx, "trace message")
+		c.logger.Debug(ctx, "debug message")
+		c.logger.Info(ctx, "info message")
+		c.logger.Warning(ctx, "warning message")
+		levels := integration.Levels()
+		if len(levels) != len(c.expected) {
+			t.Errorf("expected levels %v for %q, got %v", c.expected, c.logger.(*Logger).name, levels)
+			continue
+		}
+		for i, level := range c.expected {
+			if levels[i] != level {
+				t.Errorf("expected levels %v for %q, got %v", c.expected, c.logger.(*Logger).name, levels)
+				break
+			}
+		}
+	}
+}
+
+func TestLogger_ReplaceRemoveIntegration(t *testing.T) {
+	first, replacement := newMockIntegration("mock"), newMockIntegration("mock")
+	logger := New()
+	logger.AddIntegration(first)
+
+	logger.ReplaceIntegration("mock", replacement)
+	if first.Flushed() != 0 {
+		t.Error("expected the replaced integration to be left open")
+	}
+	if logger.GetIntegration("mock") != replacement {
+		t.Error("expected the replacement to be registered")
+	}
+	if err := logger.RemoveIntegration(context.Background(), "mock"); err != nil {
+		t.Errorf("unexpected remove error: %v", err)
+	}
+	if replacement.Flushed() != 1 {
+		t.Errorf("expected the removed integration to be flushed once, got %d", replacement.Flushed())
+	}
+	if err := logger.RemoveIntegration(context.Background(), "mock"); err != nil {
+		t.Errorf("expected no error when no integration is registered, got %v", err)
+	}
+	if len(logger.Integrations()) != 0 {
+		t.Errorf("expected no integration, got %d", len(logger.Integrations()))
+	}
+}
+
+func TestLogger_UpdateIntegrationsConcurrently(t *testing.T) {
+	logger := New()
+	logger.AddIntegration(newMockIntegration("mock"))
+
+	const goroutines, calls = 4, 500
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < calls; j++ {
+				logger.Info(context.Background(), "message")
+			}
+		}()
+	}
+	swapped := make([]*mockIntegration, 0)
+	for i := 0; i < 50; i++ {
+		logger.UpdateIntegrations(func(integrations []core.Interface) []core.Interface {
+			swapped = append(swapped, integrations[0].(*mockIntegration))
+			return []core.Interface{newMockIntegration("mock")}
+		})
+	}
+	wg.Wait()
+
+	total := len(logger.GetIntegration("mock").(*mockIntegration).Levels())
+	for _, integration := range swapped {
+		total += len(integration.Levels())
+	}
+	if total != goroutines*calls {
+		t.Errorf("expected %d entries across all integrations, got %d", goroutines*calls, total)
+	}
+}
+
+func TestLogger_ConcurrentChanges(t *testing.T) {
+	logger := New()
+	ctx := context.Background()
+	rules, err := core.ParseLevelRules("db=warning")
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					logger.Info(ctx, "message")
+					logger.Named("db").With(core.F("key", "value")).Warning(ctx, "message")
+					_ = logger.Clone().Flush(ctx)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		logger.AddIntegration(newMockIntegration("first"))
+		logger.ReplaceIntegration("first", newMockIntegration("first"))
+		logger.AddIntegration(newMockIntegration("second"))
+		logger.SetLevelRules(rules)
+		if err := logger.RemoveIntegration(ctx, "first"); err != nil {
+			t.Errorf("unexpected remove error: %v", err)
+		}
+		if err := logger.RemoveIntegration(ctx, "second"); err != nil {
+			t.Errorf("unexpected remove error: %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if len(logger.Integrations()) != 0 {
+		t.Errorf("expected no integration, got %d", len(logger.Integrations()))
+	}
+}
+
+func TestLogger_RemovedIntegrationReceivesNoCall(t *testing.T) {
+	logger := New()
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					logger.Info(ctx, "message")
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		integration := newMockIntegration("mock")
+		logger.AddIntegration(integration)
+		if err := logger.RemoveIntegration(ctx, "mock"); err != nil {
+			t.Fatalf("unexpected remove error: %v", err)
+		}
+		count := len(integration.Levels())
+		time.Sleep(time.Millisecond)
+		if len(integration.Levels()) != count {
+			t.Fatalf("expected no entry after RemoveIntegration returned, got %d more", len(integration.Levels())-count)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+// unhealthyIntegration reports err as its health.
+type unhealthyIntegration struct {
+	*mockIntegration
+	err error
+}
+
+func (u *unhealthyIntegration) Health() error {
+	return u.err
+}
+
+func TestLogger_Health(t *testing.T) {
+	logger := New()
+	logger.AddIntegration(newMockIntegration("mock"))
+	logger.AddIntegration(&unhealthyIntegration{mockIntegration: newMockIntegration("healthy")})
+	if err := logger.Health(); err != nil {
+		t.Fatalf("Expected healthy integrations, got %v", err)
+	}
+	failure := errors.New("disk full")
+	logger.AddIntegration(&unhealthyIntegration{mockIntegration: newMockIntegration("file"), err: failure})
+	err := logger.Health()
+	if !errors.Is(err, failure) || !strings.Contains(err.Error(), "file: disk full") {
+		t.Errorf("Expected the file integration failure, got %v", err)
+	}
+}
+
+// closingIntegration records its closes instead of its flushes.
+type closingIntegration struct {
+	*mockIntegration
+	closed int
+	err    error
+}
+
+func (c *closingIntegration) Close(_ context.Context) error {
+	c.closed++
+	return c.err
+}
+
+func TestLogger_Close(t *testing.T) {
+	logger := New()
+	flushed := newMockIntegration("mock")
+	failure := errors.New("connection reset")
+	closing := &closingIntegration{mockIntegration: newMockIntegration("elasticsearch"), err: failure}
+	removed := &closingIntegration{mockIntegration: newMockIntegration("sentry")}
+	logger.AddIntegration(flushed)
+	logger.AddIntegration(closing)
+	logger.AddIntegration(removed)
+
+	if err := logger.RemoveIntegration(context.Background(), "sentry"); err != nil {
+		t.Fatalf("RemoveIntegration failed: %v", err)
+	}
+	if removed.closed != 0 || removed.Flushed() != 1 {
+		t.Errorf("Expected the removed integration to be flushed and left open, got %d closes and %d flushes", removed.closed, removed.Flushed())
+	}
+
+	err := logger.Close(context.Background())
+	if !errors.Is(err, failure) || !strings.Contains(err.Error(), "elasticsearch: connection reset") {
+		t.Errorf("Expected the elasticsearch close failure, got %v", err)
+	}
+	if closing.closed != 1 || closing.Flushed() != 0 {
+		t.Errorf("Expected the closer to be closed, got %d closes and %d flushes", closing.closed, closing.Flushed())
+	}
+	if flushed.Flushed() != 1 {
+		t.Errorf("Expected the integration without Close to be flushed, got %d flushes", flushed.Flushed())
+	}
+}
@@ -0,0 +1,513 @@
+package console
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+type Format string
+
+const (
+	FormatJSON   Format = "json"
+	FormatLogfmt Format = "logfmt"
+	// FormatPretty is an aligned, colored format for local development. Map, slice and struct
+	// field values are printed as indented JSON below the entry.
+	FormatPretty Format = "pretty"
+)
+
+// prettyNoColor is the zap encoding name of FormatPretty without colors.
+const prettyNoColor = "pretty-nocolor"
+
+// Key names accepted in Encoding.Keys.
+const (
+	KeyTime       = "time"
+	KeyLevel      = "level"
+	KeyName       = "name"
+	KeyCaller     = "caller"
+	KeyFunction   = "function"
+	KeyMessage    = "message"
+	KeyStacktrace = "stacktrace"
+)
+
+// OmitKey removes an element from the entries when used as a key in Encoding.Keys.
+const OmitKey = "-"
+
+var (
+	format     = FormatJSON
+	timeFormat = ""
+	keys       = map[string]string{}
+	noColor    = false
+)
+
+var (
+	envLogFormat     = "CONSOLE_LOG_FORMAT"
+	envLogTimeFormat = "CONSOLE_LOG_TIME_FORMAT"
+	envLogKeys       = "CONSOLE_LOG_KEYS"
+	envNoColor       = "NO_COLOR"
+)
+
+// Encoding selects the format of the entries, the format of their time and the keys of the entry
+// elements.
+type Encoding struct {
+	Format Format
+	// TimeFormat is one of iso8601, rfc3339, rfc3339nano, epoch, millis and nanos, or a time layout
+	// such as 15:04:05.000. Empty uses the default of the format.
+	TimeFormat string
+	// Keys maps the element names, KeyTime, KeyMessage, ..., to the key written in the entries.
+	Keys map[string]string
+	// NoColor disables the colors of FormatPretty.
+	NoColor bool
+}
+
+// DefaultEncoding returns the encoding configured by the CONSOLE_LOG_FORMAT, CONSOLE_LOG_TIME_FORMAT,
+// CONSOLE_LOG_KEYS and NO_COLOR environment variables.
+func DefaultEncoding() Encoding {
+	_keys := make(map[string]string, len(keys))
+	for name, key := range keys {
+		_keys[name] = key
+	}
+	return Encoding{Format: format, TimeFormat: timeFormat, Keys: _keys, NoColor: noColor}
+}
+
+func (e Encoding) Validate() error {
+	switch e.Format {
+	case "", FormatJSON, FormatLogfmt, FormatPretty:
+	default:
+		return fmt.Errorf("unsupported format: %s", e.Format)
+	}
+	for name := range e.Keys {
+		switch name {
+		case KeyTime, KeyLevel, KeyName, KeyCaller, KeyFunction, KeyMessage, KeyStacktrace:
+		default:
+			return fmt.Errorf("unknown key name: %s", name)
+		}
+	}
+	return nil
+}
+
+// EncoderConfig returns the zap encoder configuration of the encoding, based on the zap production
+// configuration.
+func (e Encoding) EncoderConfig() zapcore.EncoderConfig {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.EncodeLevel = LevelEncoder
+	_timeFormat := e.TimeFormat
+	if _timeFormat == "" {
+		switch e.Format {
+		case FormatLogfmt:
+			_timeFormat = "iso8601"
+		case FormatPretty:
+			_timeFormat = "2006-01-02 15:04:05.000"
+		}
+	}
+	switch _timeFormat {
+	case "":
+	case "iso8601", "rfc3339", "rfc3339nano", "epoch", "millis", "nanos":
+		_ = cfg.EncodeTime.UnmarshalText([]byte(_timeFormat))
+	default:
+		cfg.EncodeTime = zapcore.TimeEncoderOfLayout(_timeFormat)
+	}
+	for name, key := range e.Keys {
+		if key == OmitKey {
+			key = zapcore.OmitKey
+		}
+		switch name {
+		case KeyTime:
+			cfg.TimeKey = key
+		case KeyLevel:
+			cfg.LevelKey = key
+		case KeyName:
+			cfg.NameKey = key
+		case KeyCaller:
+			cfg.CallerKey = key
+		case KeyFunction:
+			cfg.FunctionKey = key
+		case KeyMessage:
+			cfg.MessageKey = key
+		case KeyStacktrace:
+			cfg.StacktraceKey = key
+		}
+	}
+	return cfg
+}
+
+// NewEncoder returns the zap encoder of the encoding.
+func NewEncoder(e Encoding) (zapcore.Encoder, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	cfg := e.EncoderConfig()
+	switch e.Format {
+	case FormatLogfmt:
+		return newTextEncoder(cfg, false, false), nil
+	case FormatPretty:
+		return newTextEncoder(cfg, true, !e.NoColor), nil
+	default:
+		return zapcore.NewJSONEncoder(cfg), nil
+	}
+}
+
+// ZapEncoding builds the zap logger with the encoding, see Initialize.
+func ZapEncoding(e Encoding) ZapConfigOption {
+	return func(cfg *zap.Config) {
+		cfg.EncoderConfig = e.EncoderConfig()
+		switch {
+		case e.Format == FormatPretty && e.NoColor:
+			cfg.Encoding = prettyNoColor
+		case e.Format != "":
+			cfg.Encoding = string(e.Format)
+		default:
+			cfg.Encoding = string(FormatJSON)
+		}
+	}
+}
+
+// ParseKeys parses key names in the CONSOLE_LOG_KEYS format, message=msg,time=@timestamp.
+func ParseKeys(value string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, key, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid key name pair: %s", pair)
+		}
+		parsed[strings.TrimSpace(name)] = strings.TrimSpace(key)
+	}
+	if err := (Encoding{Keys: parsed}).Validate(); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+var bufferPool = buffer.NewPool()
+
+const (
+	colorReset   = "\x1b[0m"
+	colorDim     = "\x1b[2m"
+	colorRed     = "\x1b[31m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+)
+
+// textEncoder encodes entries as logfmt, or in the pretty format. Fields are collected in a
+// zapcore.MapObjectEncoder and written sorted by key, the fields added through With first.
+type textEncoder struct {
+	*zapcore.MapObjectEncoder
+	cfg    zapcore.EncoderConfig
+	pretty bool
+	color  bool
+}
+
+func newTextEncoder(cfg zapcore.EncoderConfig, pretty, color bool) *textEncoder {
+	if cfg.LineEnding == "" {
+		cfg.LineEnding = zapcore.DefaultLineEnding
+	}
+	return &textEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), cfg: cfg, pretty: pretty, color: color}
+}
+
+func (e *textEncoder) Clone() zapcore.Encoder {
+	clone := newTextEncoder(e.cfg, e.pretty, e.color)
+	for key, value := range e.Fields {
+		clone.Fields[key] = value
+	}
+	return clone
+}
+
+func (e *textEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	fieldEncoder := zapcore.NewMapObjectEncoder()
+	for _, field := range fields {
+		field.AddTo(fieldEncoder)
+	}
+	buf := bufferPool.Get()
+	if e.pretty {
+		e.encodePretty(buf, entry, fieldEncoder.Fields)
+	} else {
+		e.encodeLogfmt(buf, entry, fieldEncoder.Fields)
+	}
+	buf.AppendString(e.cfg.LineEnding)
+	return buf, nil
+}
+
+func (e *textEncoder) encodeLogfmt(buf *buffer.Buffer, entry zapcore.Entry, fields map[string]any) {
+	pairs := make([][2]string, 0, len(e.Fields)+len(fields)+6)
+	if e.cfg.TimeKey != "" && e.cfg.EncodeTime != nil {
+		pairs = append(pairs, [2]string{e.cfg.TimeKey, encodePrimitive(func(enc zapcore.PrimitiveArrayEncoder) {
+			e.cfg.EncodeTime(entry.Time, enc)
+		})})
+	}
+	if e.cfg.LevelKey != "" {
+		pairs = append(pairs, [2]string{e.cfg.LevelKey, e.level(entry.Level)})
+	}
+	if e.cfg.NameKey != "" && entry.LoggerName != "" {
+		pairs = append(pairs, [2]string{e.cfg.NameKey, entry.LoggerName})
+	}
+	if e.cfg.CallerKey != "" && entry.Caller.Defined {
+		pairs = append(pairs, [2]string{e.cfg.CallerKey, e.caller(entry.Caller)})
+	}
+	if e.cfg.FunctionKey != "" && entry.Caller.Function != "" {
+		pairs = append(pairs, [2]string{e.cfg.FunctionKey, entry.Caller.Function})
+	}
+	if e.cfg.MessageKey != "" {
+		pairs = append(pairs, [2]string{e.cfg.MessageKey, entry.Message})
+	}
+	for _, m := range []map[string]any{e.Fields, fields} {
+		for _, key := range sortedKeys(m) {
+			pairs = append(pairs, [2]string{key, formatValue(m[key])})
+		}
+	}
+	if e.cfg.StacktraceKey != "" && entry.Stack != "" {
+		pairs = append(pairs, [2]string{e.cfg.StacktraceKey, entry.Stack})
+	}
+	for i, pair := range pairs {
+		if i > 0 {
+			buf.AppendByte(' ')
+		}
+		buf.AppendString(pair[0])
+		buf.AppendByte('=')
+		buf.AppendString(quote(pair[1]))
+	}
+}
+
+func (e *textEncoder) encodePretty(buf *buffer.Buffer, entry zapcore.Entry, fields map[string]any) {
+	if e.cfg.TimeKey != "" && e.cfg.EncodeTime != nil {
+		e.appendColored(buf, colorDim, encodePrimitive(func(enc zapcore.PrimitiveArrayEncoder) {
+			e.cfg.EncodeTime(entry.Time, enc)
+		}))
+		buf.AppendByte(' ')
+	}
+	if e.cfg.LevelKey != "" {
+		level := strings.ToUpper(e.level(entry.Level))
+		e.appendColored(buf, levelColor(entry.Level), fmt.Sprintf("%-5s", level))
+		buf.AppendByte(' ')
+	}
+	if e.cfg.NameKey != "" && entry.LoggerName != "" {
+		e.appendColored(buf, colorCyan, entry.LoggerName)
+		buf.AppendByte(' ')
+	}
+	if e.cfg.CallerKey != "" && entry.Caller.Defined {
+		e.appendColored(buf, colorDim, e.caller(entry.Caller))
+		buf.AppendByte(' ')
+	}
+	if e.cfg.MessageKey != "" {
+		buf.AppendString(entry.Message)
+	}
+	nested := make([][2]string, 0)
+	for _, m := range []map[string]any{e.Fields, fields} {
+		for _, key := range sortedKeys(m) {
+			value := m[key]
+			if isNested(value) {
+				indented, err := json.MarshalIndent(value, "    ", "  ")
+				if err != nil {
+					indented = []byte(fmt.Sprintf("%+v", value))
+				}
+				nested = append(nested, [2]string{key, string(indented)})
+				continue
+			}
+			buf.AppendByte(' ')
+			e.appendColored(buf, colorBlue, key+"=")
+			buf.AppendString(quote(formatValue(value)))
+		}
+	}
+	for _, pair := range nested {
+		buf.AppendString(e.cfg.LineEnding)
+		buf.AppendString("    ")
+		e.appendColored(buf, colorBlue, pair[0]+":")
+		buf.AppendByte(' ')
+		buf.AppendString(pair[1])
+	}
+	if e.cfg.StacktraceKey != "" && entry.Stack != "" {
+		buf.AppendString(e.cfg.LineEnding)
+		e.appendColored(buf, colorDim, entry.Stack)
+	}
+}
+
+func (e *textEncoder) level(level zapcore.Level) string {
+	encode := e.cfg.EncodeLevel
+	if encode == nil {
+		encode = LevelEncoder
+	}
+	return encodePrimitive(func(enc zapcore.PrimitiveArrayEncoder) {
+		encode(level, enc)
+	})
+}
+
+func (e *textEncoder) caller(caller zapcore.EntryCaller) string {
+	encode := e.cfg.EncodeCaller
+	if encode == nil {
+		encode = zapcore.ShortCallerEncoder
+	}
+	return encodePrimitive(func(enc zapcore.PrimitiveArrayEncoder) {
+		encode(caller, enc)
+	})
+}
+
+func (e *textEncoder) appendColored(buf *buffer.Buffer, color, value string) {
+	if !e.color {
+		buf.AppendString(value)
+		return
+	}
+	buf.AppendString(color)
+	buf.AppendString(value)
+	buf.AppendString(colorReset)
+}
+
+func levelColor(level zapcore.Level) string {
+	switch {
+	case level <= TraceLevel:
+		return colorDim
+	case level == zapcore.DebugLevel:
+		return colorMagenta
+	case level == zapcore.InfoLevel:
+		return colorBlue
+	case level == zapcore.WarnLevel:
+		return colorYellow
+	default:
+		return colorRed
+	}
+}
+
+// encodePrimitive returns the value appended by a zap time, level or caller encoder as a string.
+func encodePrimitive(encode func(enc zapcore.PrimitiveArrayEncoder)) string {
+	enc := zapcore.NewMapObjectEncoder()
+	_ = enc.AddArray("value", zapcore.ArrayMarshalerFunc(func(array zapcore.ArrayEncoder) error {
+		encode(array)
+		return nil
+	}))
+	values, _ := enc.Fields["value"].([]any)
+	if len(values) == 0 {
+		return ""
+	}
+	return formatValue(values[0])
+}
+
+// formatValue formats a value collected by zapcore.MapObjectEncoder, values without a plain text
+// form are formatted as JSON.
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		return fmt.Sprint(v)
+	case float64:
+		return formatFloat(v, 64)
+	case float32:
+		return formatFloat(float64(v), 32)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%+v", value)
+	}
+	return string(encoded)
+}
+
+func formatFloat(value float64, bits int) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'f', -1, bits)
+}
+
+// quote quotes values that would otherwise be ambiguous in logfmt.
+func quote(value string) string {
+	if value == "" || !utf8.ValidString(value) {
+		return strconv.Quote(value)
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f {
+			return strconv.Quote(value)
+		}
+	}
+	return value
+}
+
+// isNested reports whether a field value is printed as indented JSON in the pretty format.
+func isNested(value any) bool {
+	switch value.(type) {
+	case nil, []byte, time.Time, time.Duration, error, fmt.Stringer:
+		return false
+	}
+	kind := reflect.TypeOf(value).Kind()
+	if kind == reflect.Pointer {
+		kind = reflect.TypeOf(value).Elem().Kind()
+	}
+	switch kind {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return true
+	default:
+		return false
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	if value := os.Getenv(envLogFormat); value != "" {
+		if err := (Encoding{Format: Format(value)}).Validate(); err == nil {
+			format = Format(value)
+		} else {
+			_, _ = fmt.Fprintf(os.Stderr, "Invalid CONSOLE_LOG_FORMAT value: %s, using default %s\n", value, format)
+		}
+	}
+	timeFormat = os.Getenv(envLogTimeFormat)
+	if value := os.Getenv(envLogKeys); value != "" {
+		if parsed, err := ParseKeys(value); err == nil {
+			keys = parsed
+		} else {
+			_, _ = fmt.Fprintf(os.Stderr, "Invalid CONSOLE_LOG_KEYS value, using default keys: %v\n", err)
+		}
+	}
+	noColor = os.Getenv(envNoColor) != ""
+
+	for name, color := range map[string]bool{string(FormatLogfmt): false, string(FormatPretty): true, prettyNoColor: false} {
+		pretty, _color := name != string(FormatLogfmt), color
+		err := zap.RegisterEncoder(name, func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return newTextEncoder(cfg, pretty, _color), nil
+		})
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to register the %s encoder: %v\n", name, err)
+		}
+	}
+}
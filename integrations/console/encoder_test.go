@@ -0,0 +1,115 @@
+package console
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewEncoder_Logfmt(t *testing.T) {
+	encoder, err := NewEncoder(Encoding{Format: FormatLogfmt, TimeFormat: "15:04:05", Keys: map[string]string{KeyMessage: "msg"}})
+	if err != nil {
+		t.Fatalf("NewEncoder failed: %v", err)
+	}
+	encoder.AddString("service", "billing")
+	line := encode(t, encoder, zapcore.InfoLevel, "user logged in",
+		zap.String("user", "John Doe"), zap.Int("attempt", 2), zap.Error(errors.New("bad=value")))
+	expected := `ts=10:30:00 level=info logger=db msg="user logged in" service=billing attempt=2 error="bad=value" user="John Doe"` + "\n"
+	if line != expected {
+		t.Errorf("Expected %q, got %q", expected, line)
+	}
+}
+
+func TestNewEncoder_LogfmtTrace(t *testing.T) {
+	encoder, err := NewEncoder(Encoding{Format: FormatLogfmt, Keys: map[string]string{KeyTime: OmitKey, KeyName: OmitKey}})
+	if err != nil {
+		t.Fatalf("NewEncoder failed: %v", err)
+	}
+	line := encode(t, encoder, TraceLevel, "message", zap.Any("nested", map[string]any{"a": 1}))
+	expected := `level=trace msg=message nested="{\"a\":1}"` + "\n"
+	if line != expected {
+		t.Errorf("Expected %q, got %q", expected, line)
+	}
+}
+
+func TestNewEncoder_Pretty(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+	encoder, err := NewEncoder(Encoding{Format: FormatPretty, NoColor: true})
+	if err != nil {
+		t.Fatalf("NewEncoder failed: %v", err)
+	}
+	line := encode(t, encoder, zapcore.WarnLevel, "slow query", zap.Duration("took", time.Second), zap.Any("user", user{Name: "John"}))
+	expected := "2026-10-17 10:30:00.000 WARN  db slow query took=1s\n" +
+		"    user: {\n" +
+		"      \"name\": \"John\"\n" +
+		"    }\n"
+	if line != expected {
+		t.Errorf("Expected %q, got %q", expected, line)
+	}
+
+	colored, err := NewEncoder(Encoding{Format: FormatPretty})
+	if err != nil {
+		t.Fatalf("NewEncoder failed: %v", err)
+	}
+	if line = encode(t, colored, zapcore.ErrorLevel, "failed"); !strings.Contains(line, colorRed+"ERROR"+colorReset) {
+		t.Errorf("Expected a red level, got %q", line)
+	}
+}
+
+func TestNewEncoder_JSONKeys(t *testing.T) {
+	encoder, err := NewEncoder(Encoding{TimeFormat: "rfc3339", Keys: map[string]string{KeyTime: "@timestamp", KeyMessage: "message"}})
+	if err != nil {
+		t.Fatalf("NewEncoder failed: %v", err)
+	}
+	line := encode(t, encoder, zapcore.InfoLevel, "message")
+	if !strings.HasPrefix(line, `{"level":"info","@timestamp":"2026-10-17T10:30:00Z","logger":"db","message":"message"}`) {
+		t.Errorf("Unexpected JSON entry: %s", line)
+	}
+}
+
+func TestEncoding_Validate(t *testing.T) {
+	if err := (Encoding{Format: "xml"}).Validate(); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+	if _, err := ParseKeys("message=msg,timestamp=ts"); err == nil {
+		t.Error("Expected an error for an unknown key name")
+	}
+	keys, err := ParseKeys("message=msg, time=@timestamp")
+	if err != nil || keys[KeyMessage] != "msg" || keys[KeyTime] != "@timestamp" {
+		t.Errorf("Unexpected keys %v (%v)", keys, err)
+	}
+}
+
+func TestZapEncoding(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatLogfmt, FormatPretty} {
+		zapLogger, err := Initialize(ZapEncoding(Encoding{Format: format, NoColor: true}), func(cfg *zap.Config) {
+			cfg.OutputPaths = []string{}
+		})
+		if err != nil {
+			t.Fatalf("Initialize failed for %s: %v", format, err)
+		}
+		zapLogger.Info("message", zap.String("key", "value"))
+	}
+}
+
+func encode(t *testing.T, encoder zapcore.Encoder, level zapcore.Level, msg string, fields ...zapcore.Field) string {
+	t.Helper()
+	entry := zapcore.Entry{
+		Level:      level,
+		Time:       time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC),
+		LoggerName: "db",
+		Message:    msg,
+	}
+	buf, err := encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		t.Fatalf("EncodeEntry failed: %v", err)
+	}
+	defer buf.Free()
+	return buf.String()
+}
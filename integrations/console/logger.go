@@ -3,8 +3,11 @@ package console
 import (
 	"context"
 	"fmt"
+	"runtime"
+	"strings"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/ensarkovankaya/go-logging/core"
 )
@@ -17,25 +20,59 @@ const Type = "console"
 
 type Option func(l *Logger)
 
+// Logger writes entries through zap. Level is the level set by New and the options, the level
+// changed at runtime with SetLevel is shared by every logger derived from the same New call and is
+// reported by Levels.
 type Logger struct {
 	Transport *zap.Logger
 	Name      string
+	Level     core.Level
+	level     *core.AtomicLevel
 }
 
 func New(opts ...Option) *Logger {
-	zapLogger, err := Initialize()
+	zapLogger, err := Initialize(func(cfg *zap.Config) {
+		// Level is enforced by Logger.Level so it can be lowered at runtime.
+		cfg.Level = zap.NewAtomicLevelAt(TraceLevel)
+	})
 	if err != nil {
 		panic(fmt.Sprintf("Logger initialization failed: %v", err))
 	}
 	logger := &Logger{
 		Transport: zapLogger,
+		Level:     logLevel,
 	}
 	for _, opt := range opts {
 		opt(logger)
 	}
+	logger.level = core.NewAtomicLevel(logger.Level)
 	return logger
 }
 
+// WithLevel writes the entries at level and above instead of the CONSOLE_LOG_LEVEL level.
+func WithLevel(level core.Level) Option {
+	return func(l *Logger) {
+		l.Level = level
+	}
+}
+
+// WithEncoding writes the entries in the encoding instead of the one configured by the environment.
+// It panics when the encoding is invalid, see Encoding.Validate.
+func WithEncoding(e Encoding) Option {
+	return func(l *Logger) {
+		if err := e.Validate(); err != nil {
+			panic(fmt.Sprintf("Logger initialization failed: %v", err))
+		}
+		zapLogger, err := Initialize(ZapEncoding(e), func(cfg *zap.Config) {
+			cfg.Level = zap.NewAtomicLevelAt(TraceLevel)
+		})
+		if err != nil {
+			panic(fmt.Sprintf("Logger initialization failed: %v", err))
+		}
+		l.Transport = zapLogger.Named(l.Name)
+	}
+}
+
 func (l *Logger) Type() string {
 	return Type
 }
@@ -62,28 +99,113 @@ func (l *Logger) Named(name string) core.Interface {
 	return &_l
 }
 
+func (l *Logger) Trace(ctx context.Context, msg string, fields ...core.Field) {
+	if !l.getLevel().Enabled(core.LevelTrace) {
+		return
+	}
+	logger := l.getLogger(ctx)
+	if !l.atCallSite(ctx, logger, TraceLevel, msg, fields) {
+		logger.Log(TraceLevel, msg, l.entry(ctx, fields)...)
+	}
+}
+
 func (l *Logger) Debug(ctx context.Context, msg string, fields ...core.Field) {
-	l.getLogger(ctx).Debug(msg, l.serialize(fields...)...)
+	if !l.getLevel().Enabled(core.LevelDebug) {
+		return
+	}
+	logger := l.getLogger(ctx)
+	if !l.atCallSite(ctx, logger, zapcore.DebugLevel, msg, fields) {
+		logger.Debug(msg, l.entry(ctx, fields)...)
+	}
 }
 
 func (l *Logger) Info(ctx context.Context, msg string, fields ...core.Field) {
-	l.getLogger(ctx).Info(msg, l.serialize(fields...)...)
+	if !l.getLevel().Enabled(core.LevelInfo) {
+		return
+	}
+	logger := l.getLogger(ctx)
+	if !l.atCallSite(ctx, logger, zapcore.InfoLevel, msg, fields) {
+		logger.Info(msg, l.entry(ctx, fields)...)
+	}
 }
 
 func (l *Logger) Warning(ctx context.Context, msg string, fields ...core.Field) {
-	l.getLogger(ctx).Warn(msg, l.serialize(fields...)...)
+	if !l.getLevel().Enabled(core.LevelWarning) {
+		return
+	}
+	logger := l.getLogger(ctx)
+	if !l.atCallSite(ctx, logger, zapcore.WarnLevel, msg, fields) {
+		logger.Warn(msg, l.entry(ctx, fields)...)
+	}
 }
 
 func (l *Logger) Error(ctx context.Context, msg string, fields ...core.Field) {
-	l.getLogger(ctx).Error(msg, l.serialize(fields...)...)
+	if !l.getLevel().Enabled(core.LevelError) {
+		return
+	}
+	logger := l.getLogger(ctx)
+	if !l.atCallSite(ctx, logger, zapcore.ErrorLevel, msg, fields) {
+		logger.Error(msg, l.entry(ctx, fields)...)
+	}
+}
+
+// Fatal writes the entry at fatal level without exiting, batch.Logger terminates the process.
+func (l *Logger) Fatal(ctx context.Context, msg string, fields ...core.Field) {
+	if !l.getLevel().Enabled(core.LevelFatal) {
+		return
+	}
+	logger := l.getLogger(ctx).WithOptions(zap.WithFatalHook(noopHook{}))
+	if !l.atCallSite(ctx, logger, zapcore.FatalLevel, msg, fields) {
+		logger.Fatal(msg, l.entry(ctx, fields)...)
+	}
+}
+
+// Panic writes the entry at panic level without panicking, batch.Logger panics after flushing.
+func (l *Logger) Panic(ctx context.Context, msg string, fields ...core.Field) {
+	if !l.getLevel().Enabled(core.LevelPanic) {
+		return
+	}
+	logger := l.getLogger(ctx).WithOptions(zap.WithPanicHook(noopHook{}))
+	if !l.atCallSite(ctx, logger, zapcore.PanicLevel, msg, fields) {
+		logger.Panic(msg, l.entry(ctx, fields)...)
+	}
 }
 
 func (l *Logger) Flush(ctx context.Context) error {
 	return l.getLogger(ctx).Sync()
 }
 
+// SetLevel changes the level of every logger derived from the same New call.
 func (l *Logger) SetLevel(level core.Level) {
-	l.Transport = l.Transport.WithOptions(zap.IncreaseLevel(getZapLevel(level)))
+	if l.level == nil {
+		l.Level = level
+		return
+	}
+	l.level.Store(level)
+}
+
+func (l *Logger) Levels() map[string]core.Level {
+	return map[string]core.Level{core.LogLevelName: l.getLevel()}
+}
+
+func (l *Logger) SetLevels(levels map[string]core.Level) error {
+	for name := range levels {
+		if name != core.LogLevelName {
+			return fmt.Errorf("unknown %s level: %s", Type, name)
+		}
+	}
+	for _, level := range levels {
+		l.SetLevel(level)
+	}
+	return nil
+}
+
+// getLevel returns the level changed at runtime, Level when the logger was not created by New.
+func (l *Logger) getLevel() core.Level {
+	if l.level == nil {
+		return l.Level
+	}
+	return l.level.Load()
 }
 
 func (l *Logger) getLogger(ctx context.Context) *zap.Logger {
@@ -94,6 +216,57 @@ func (l *Logger) getLogger(ctx context.Context) *zap.Logger {
 	return transport
 }
 
+// entry serializes the trace fields of ctx followed by fields, without copying fields when ctx
+// carries no span.
+func (l *Logger) entry(ctx context.Context, fields []core.Field) []zap.Field {
+	traceFields := core.TraceFields(ctx)
+	if len(traceFields) == 0 {
+		return l.serialize(fields...)
+	}
+	return l.serialize(append(traceFields, fields...)...)
+}
+
+// atCallSite writes the entry at the call site carried by ctx, see core.WithCallers, and reports
+// whether ctx carries one. The caller is the frame callerSkip levels above the logger as when zap
+// resolves it.
+func (l *Logger) atCallSite(ctx context.Context, logger *zap.Logger, level zapcore.Level, msg string, fields []core.Field) bool {
+	pcs := core.Callers(ctx)
+	if pcs == nil {
+		return false
+	}
+	checked := logger.Check(level, msg)
+	if checked == nil {
+		return true
+	}
+	if checked.Caller.Defined || checked.Stack != "" {
+		frames := runtime.CallersFrames(pcs[min(max(callerSkip-1, 0), len(pcs)-1):])
+		frame, more := frames.Next()
+		if checked.Caller.Defined {
+			checked.Caller = zapcore.EntryCaller{
+				Defined:  true,
+				PC:       frame.PC,
+				File:     frame.File,
+				Line:     frame.Line,
+				Function: frame.Function,
+			}
+		}
+		if checked.Stack != "" {
+			var stack strings.Builder
+			for {
+				_, _ = fmt.Fprintf(&stack, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+				if !more {
+					break
+				}
+				stack.WriteByte('\n')
+				frame, more = frames.Next()
+			}
+			checked.Stack = stack.String()
+		}
+	}
+	checked.Write(l.entry(ctx, fields)...)
+	return true
+}
+
 func (l *Logger) serialize(fields ...core.Field) []zap.Field {
 	serialized := make([]zap.Field, 0, len(fields))
 	for _, field := range fields {
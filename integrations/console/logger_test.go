@@ -6,7 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/ensarkovankaya/go-logging/core"
 )
@@ -103,3 +106,59 @@ func getFields() []core.Field {
 		core.F("duration", twoMinutesTwentyThree),
 	}
 }
+
+func TestLogger_TraceFields(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	logger := New(func(l *Logger) {
+		l.Transport = zap.New(observed)
+	})
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03},
+		SpanID:     trace.SpanID{0x04, 0x05},
+		TraceFlags: trace.FlagsSampled,
+	})
+	logger.Info(trace.ContextWithSpanContext(context.Background(), spanContext), "info message")
+	logger.Info(context.Background(), "info message without span")
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	expected := map[string]string{
+		core.TraceIDKey:    spanContext.TraceID().String(),
+		core.SpanIDKey:     spanContext.SpanID().String(),
+		core.TraceFlagsKey: spanContext.TraceFlags().String(),
+	}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("expected field %s=%s, got %v", key, value, fields[key])
+		}
+	}
+	if _, ok := entries[1].ContextMap()[core.TraceIDKey]; ok {
+		t.Errorf("expected no %s field without an active span", core.TraceIDKey)
+	}
+}
+
+func TestLogger_TraceFatalPanic(t *testing.T) {
+	observed, logs := observer.New(TraceLevel)
+	logger := New(func(l *Logger) {
+		l.Transport = zap.New(observed)
+		l.Level = core.LevelTrace
+	})
+	ctx := context.Background()
+	logger.Trace(ctx, "trace message")
+	logger.Panic(ctx, "panic message")
+	logger.Fatal(ctx, "fatal message")
+
+	expected := []zapcore.Level{TraceLevel, zapcore.PanicLevel, zapcore.FatalLevel}
+	entries := logs.All()
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(entries))
+	}
+	for i, level := range expected {
+		if entries[i].Level != level {
+			t.Errorf("expected entry %d level %s, got %s", i, level, entries[i].Level)
+		}
+	}
+}
@@ -14,6 +14,9 @@ import (
 type ZapConfigOption = func(cfg *zap.Config)
 type Level = zapcore.Level
 
+// TraceLevel is the zap level used for core.LevelTrace, zap has no level below debug.
+const TraceLevel = zapcore.DebugLevel - 1
+
 var (
 	logLevel   = core.LevelDebug
 	callerSkip = 2
@@ -34,6 +37,7 @@ func Initialize(cfgOptions ...ZapConfigOption) (*zap.Logger, error) {
 	cfg := zap.NewProductionConfig()
 	cfg.Level = zap.NewAtomicLevelAt(getZapLevel(logLevel))
 	cfg.Development = debug
+	ZapEncoding(DefaultEncoding())(&cfg)
 	options := []zap.Option{
 		zap.AddStacktrace(zapcore.ErrorLevel),
 	}
@@ -70,8 +74,24 @@ func init() {
 	}
 }
 
+// LevelEncoder encodes TraceLevel as "trace" and other levels like zapcore.LowercaseLevelEncoder.
+func LevelEncoder(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	if level == TraceLevel {
+		enc.AppendString("trace")
+		return
+	}
+	zapcore.LowercaseLevelEncoder(level, enc)
+}
+
+// noopHook lets Fatal and Panic entries be written without terminating the process.
+type noopHook struct{}
+
+func (noopHook) OnWrite(_ *zapcore.CheckedEntry, _ []zapcore.Field) {}
+
 func getZapLevel(level core.Level) zapcore.Level {
 	switch level {
+	case core.LevelTrace:
+		return TraceLevel
 	case core.LevelDebug:
 		return zapcore.DebugLevel
 	case core.LevelInfo:
@@ -80,6 +100,10 @@ func getZapLevel(level core.Level) zapcore.Level {
 		return zapcore.WarnLevel
 	case core.LevelError:
 		return zapcore.ErrorLevel
+	case core.LevelPanic:
+		return zapcore.PanicLevel
+	case core.LevelFatal:
+		return zapcore.FatalLevel
 	default:
 		return zapcore.InvalidLevel
 	}
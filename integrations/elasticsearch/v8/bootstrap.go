@@ -0,0 +1,413 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// BootstrapVersion is the version of the index template and ILM policy installed by Bootstrap. It is
+// increased whenever their content changes, so running applications upgrade them on their next start.
+const BootstrapVersion = 1
+
+// builtinDataStreams are the index patterns of the data streams Elasticsearch manages itself, a
+// template of time-suffixed indices matching one of them would take over their data streams.
+var builtinDataStreams = []string{"logs-*-*", "metrics-*-*", "traces-*-*", "synthetics-*-*"}
+
+type BootstrapOption func(b *Bootstrap)
+
+// Bootstrap installs a composable index template with explicit mappings for the documents written
+// by the Logger, and an ILM policy the template assigns to its indices.
+//
+// Run is idempotent and safe to call on every start: the template and the policy are only written
+// when they are missing, carry a lower Version, or carry the same Version with a different content
+// such as a changed Policy, so an application never downgrades what a newer release installed.
+//
+// The index patterns of time-suffixed indices must not overlap the built-in logs-*-*, metrics-*-*,
+// traces-*-* and synthetics-*-* data streams, Run fails for the default logs name for instance.
+type Bootstrap struct {
+	Client *elasticsearch.Client
+	// Name is the name of the template and the policy.
+	Name string
+	// IndexPatterns are the index patterns of the template, Name followed by -* by default.
+	IndexPatterns []string
+	// DataStream makes the template create data streams, see WithDataStream.
+	DataStream bool
+	// ECS maps the documents of ECSDocumentBuilder instead of DefaultDocumentBuilder.
+	ECS      bool
+	Priority int
+	Version  int
+	Policy   Policy
+}
+
+// Policy holds the phases of the ILM policy. Rollover only applies to data streams, the indices of
+// NewIndexBuilder are time-suffixed and only deleted.
+type Policy struct {
+	// RolloverMaxSize is the primary shard size rolling a data stream over, such as 50gb.
+	RolloverMaxSize string
+	// RolloverMaxAge is the age rolling a data stream over, such as 1d.
+	RolloverMaxAge string
+	// DeleteAfter is the age indices are deleted at, such as 30d. Empty keeps them.
+	DeleteAfter string
+}
+
+// NewBootstrap returns a Bootstrap for the indices of NewIndexBuilder(name), or of
+// DefaultIndexBuilder when name is empty, using the global client.
+func NewBootstrap(name string, opts ...BootstrapOption) *Bootstrap {
+	if name == "" {
+		name = defaultIndexName
+	}
+	bootstrap := &Bootstrap{
+		Client:        globalClient,
+		Name:          name,
+		IndexPatterns: []string{name + "-*"},
+		Priority:      200,
+		Version:       BootstrapVersion,
+		Policy: Policy{
+			RolloverMaxSize: "50gb",
+			RolloverMaxAge:  "1d",
+			DeleteAfter:     "30d",
+		},
+	}
+	for _, opt := range opts {
+		opt(bootstrap)
+	}
+	return bootstrap
+}
+
+func WithBootstrapClient(client *elasticsearch.Client) BootstrapOption {
+	return func(b *Bootstrap) {
+		b.Client = client
+	}
+}
+
+// WithBootstrapDataStream installs a data stream template for the documents written by
+// WithDataStream with the name of the Bootstrap.
+func WithBootstrapDataStream() BootstrapOption {
+	return func(b *Bootstrap) {
+		b.DataStream = true
+		b.ECS = true
+		b.IndexPatterns = []string{b.Name}
+	}
+}
+
+func WithECSMappings() BootstrapOption {
+	return func(b *Bootstrap) {
+		b.ECS = true
+	}
+}
+
+func WithPolicy(policy Policy) BootstrapOption {
+	return func(b *Bootstrap) {
+		b.Policy = policy
+	}
+}
+
+// Run installs the ILM policy, then the index template referencing it.
+func (b *Bootstrap) Run(ctx context.Context) error {
+	if b.Client == nil {
+		return fmt.Errorf("elasticsearch client is not initialized")
+	}
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	if err := b.installPolicy(ctx); err != nil {
+		return fmt.Errorf("failed to install ILM policy %s: %w", b.Name, err)
+	}
+	if err := b.installTemplate(ctx); err != nil {
+		return fmt.Errorf("failed to install index template %s: %w", b.Name, err)
+	}
+	return nil
+}
+
+// Validate reports the index patterns of time-suffixed indices overlapping a built-in data stream.
+func (b *Bootstrap) Validate() error {
+	if b.DataStream {
+		return nil
+	}
+	errs := make([]error, 0)
+	for _, pattern := range b.IndexPatterns {
+		for _, builtin := range builtinDataStreams {
+			if patternsOverlap(pattern, builtin) {
+				errs = append(errs, fmt.Errorf("index pattern %s of %s overlaps the built-in %s data streams, use another index name", pattern, b.Name, builtin))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PolicyBody returns the ILM policy document.
+func (b *Bootstrap) PolicyBody() map[string]any {
+	hot := map[string]any{}
+	if b.DataStream && (b.Policy.RolloverMaxSize != "" || b.Policy.RolloverMaxAge != "") {
+		rollover := map[string]any{}
+		if b.Policy.RolloverMaxSize != "" {
+			rollover["max_primary_shard_size"] = b.Policy.RolloverMaxSize
+		}
+		if b.Policy.RolloverMaxAge != "" {
+			rollover["max_age"] = b.Policy.RolloverMaxAge
+		}
+		hot["rollover"] = rollover
+	}
+	phases := map[string]any{
+		"hot": map[string]any{"min_age": "0ms", "actions": hot},
+	}
+	if b.Policy.DeleteAfter != "" {
+		phases["delete"] = map[string]any{
+			"min_age": b.Policy.DeleteAfter,
+			"actions": map[string]any{"delete": map[string]any{}},
+		}
+	}
+	meta := map[string]any{"version": b.Version, "managed_by": "go-logging"}
+	policy := map[string]any{
+		"policy": map[string]any{
+			"_meta":  meta,
+			"phases": phases,
+		},
+	}
+	meta["hash"] = contentHash(policy)
+	return policy
+}
+
+// TemplateBody returns the composable index template document.
+func (b *Bootstrap) TemplateBody() map[string]any {
+	mappings := defaultMappings()
+	if b.ECS {
+		mappings = ecsMappings()
+	}
+	meta := map[string]any{"managed_by": "go-logging"}
+	template := map[string]any{
+		"index_patterns": b.IndexPatterns,
+		"priority":       b.Priority,
+		"version":        b.Version,
+		"_meta":          meta,
+		"template": map[string]any{
+			"settings": map[string]any{"index.lifecycle.name": b.Name},
+			"mappings": mappings,
+		},
+	}
+	if b.DataStream {
+		template["data_stream"] = map[string]any{}
+	}
+	meta["hash"] = contentHash(template)
+	return template
+}
+
+func (b *Bootstrap) installPolicy(ctx context.Context) error {
+	res, err := b.Client.ILM.GetLifecycle(b.Client.ILM.GetLifecycle.WithPolicy(b.Name), b.Client.ILM.GetLifecycle.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	var policies map[string]struct {
+		Policy struct {
+			Meta bootstrapMeta `json:"_meta"`
+		} `json:"policy"`
+	}
+	found, err := decodeResponse(res, &policies)
+	if err != nil {
+		return err
+	}
+	document := b.PolicyBody()
+	if policy, ok := policies[b.Name]; found && ok && policy.Policy.Meta.installed(b.Version, document) {
+		return nil
+	}
+	body, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	res, err = b.Client.ILM.PutLifecycle(b.Name, b.Client.ILM.PutLifecycle.WithBody(bytes.NewReader(body)), b.Client.ILM.PutLifecycle.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	_, err = decodeResponse(res, nil)
+	return err
+}
+
+func (b *Bootstrap) installTemplate(ctx context.Context) error {
+	res, err := b.Client.Indices.GetIndexTemplate(b.Client.Indices.GetIndexTemplate.WithName(b.Name), b.Client.Indices.GetIndexTemplate.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	var templates struct {
+		IndexTemplates []struct {
+			Name          string `json:"name"`
+			IndexTemplate struct {
+				Version int           `json:"version"`
+				Meta    bootstrapMeta `json:"_meta"`
+			} `json:"index_template"`
+		} `json:"index_templates"`
+	}
+	found, err := decodeResponse(res, &templates)
+	if err != nil {
+		return err
+	}
+	document := b.TemplateBody()
+	if found {
+		for _, template := range templates.IndexTemplates {
+			meta := template.IndexTemplate.Meta
+			meta.Version = template.IndexTemplate.Version
+			if template.Name == b.Name && meta.installed(b.Version, document) {
+				return nil
+			}
+		}
+	}
+	body, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	res, err = b.Client.Indices.PutIndexTemplate(b.Name, bytes.NewReader(body), b.Client.Indices.PutIndexTemplate.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	_, err = decodeResponse(res, nil)
+	return err
+}
+
+// bootstrapMeta is the version and content hash of an installed policy or template.
+type bootstrapMeta struct {
+	Version int    `json:"version"`
+	Hash    string `json:"hash"`
+}
+
+// installed reports whether the installed document is newer than version, or of the same version
+// and content as document.
+func (m bootstrapMeta) installed(version int, document map[string]any) bool {
+	if m.Version != version {
+		return m.Version > version
+	}
+	return m.Hash == documentMeta(document)["hash"]
+}
+
+// contentHash returns the hash of document, json.Marshal sorts the keys of maps so equal documents
+// have equal hashes.
+func contentHash(document map[string]any) string {
+	encoded, _ := json.Marshal(document)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// documentMeta returns the _meta of a template or policy document.
+func documentMeta(document map[string]any) map[string]any {
+	if policy, ok := document["policy"].(map[string]any); ok {
+		document = policy
+	}
+	meta, _ := document["_meta"].(map[string]any)
+	return meta
+}
+
+// patternsOverlap reports whether an index name can match both index patterns, * matching any
+// sequence of characters.
+func patternsOverlap(a, b string) bool {
+	switch {
+	case a == "" && b == "":
+		return true
+	case strings.HasPrefix(a, "*"):
+		return patternsOverlap(a[1:], b) || b != "" && patternsOverlap(a, b[1:])
+	case strings.HasPrefix(b, "*"):
+		return patternsOverlap(a, b[1:]) || a != "" && patternsOverlap(a[1:], b)
+	case a == "" || b == "":
+		return false
+	default:
+		return a[0] == b[0] && patternsOverlap(a[1:], b[1:])
+	}
+}
+
+// decodeResponse decodes the body of a successful response into target when not nil. It reports
+// false without error for a 404 response.
+func decodeResponse(res *esapi.Response, target any) (bool, error) {
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return false, fmt.Errorf("unexpected response %s: %s", res.Status(), body)
+	}
+	if target == nil {
+		return true, nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(target); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return true, nil
+}
+
+// keywordStrings maps the string fields added through Logger.With to keywords rather than text
+// with a keyword subfield, halving the fields created for them.
+var keywordStrings = map[string]any{
+	"strings_as_keyword": map[string]any{
+		"match_mapping_type": "string",
+		"mapping":            map[string]any{"type": "keyword", "ignore_above": 1024},
+	},
+}
+
+// defaultMappings maps the documents of DefaultDocumentBuilder. The entry fields under data are
+// flattened, so they never add fields to the mapping or conflict across services.
+func defaultMappings() map[string]any {
+	return map[string]any{
+		"dynamic_templates": []any{keywordStrings},
+		"properties": map[string]any{
+			"timestamp":   map[string]any{"type": "date"},
+			"level":       map[string]any{"type": "keyword"},
+			"message":     map[string]any{"type": "text"},
+			"name":        map[string]any{"type": "keyword"},
+			"trace_id":    map[string]any{"type": "keyword"},
+			"span_id":     map[string]any{"type": "keyword"},
+			"trace_flags": map[string]any{"type": "keyword"},
+			"data":        map[string]any{"type": "flattened"},
+		},
+	}
+}
+
+// ecsMappings maps the documents of NewECSDocumentBuilder following the Elastic Common Schema.
+func ecsMappings() map[string]any {
+	keyword := map[string]any{"type": "keyword", "ignore_above": 1024}
+	return map[string]any{
+		"dynamic_templates": []any{
+			map[string]any{
+				"labels": map[string]any{
+					"path_match":         "labels.*",
+					"match_mapping_type": "string",
+					"mapping":            keyword,
+				},
+			},
+			keywordStrings,
+		},
+		"properties": map[string]any{
+			"@timestamp": map[string]any{"type": "date"},
+			"message":    map[string]any{"type": "match_only_text"},
+			"ecs": map[string]any{"properties": map[string]any{
+				"version": keyword,
+			}},
+			"log": map[string]any{"properties": map[string]any{
+				"level":  keyword,
+				"logger": keyword,
+			}},
+			"error": map[string]any{"properties": map[string]any{
+				"message":     map[string]any{"type": "match_only_text"},
+				"type":        keyword,
+				"stack_trace": map[string]any{"type": "wildcard"},
+			}},
+			"trace": map[string]any{"properties": map[string]any{"id": keyword}},
+			"span":  map[string]any{"properties": map[string]any{"id": keyword}},
+			"service": map[string]any{"properties": map[string]any{
+				"name":        keyword,
+				"version":     keyword,
+				"environment": keyword,
+			}},
+			"labels": map[string]any{"type": "object"},
+		},
+	}
+}
@@ -0,0 +1,218 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mockCluster stores the ILM policies and index templates put to it.
+type mockCluster struct {
+	T         *testing.T
+	lock      sync.Mutex
+	Policies  map[string]map[string]any
+	Templates map[string]map[string]any
+	Puts      []string
+}
+
+func newMockCluster(t *testing.T) *mockCluster {
+	return &mockCluster{
+		T:         t,
+		Policies:  make(map[string]map[string]any),
+		Templates: make(map[string]map[string]any),
+	}
+}
+
+func (c *mockCluster) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var document map[string]any
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			c.T.Errorf("Failed to read request body: %v", err)
+			return nil, err
+		}
+		if err = json.Unmarshal(body, &document); err != nil {
+			c.T.Errorf("Failed to unmarshal request body: %v", err)
+			return nil, err
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/_ilm/policy/"):
+		name := strings.TrimPrefix(req.URL.Path, "/_ilm/policy/")
+		if req.Method == http.MethodPut {
+			c.Policies[name] = document
+			c.Puts = append(c.Puts, req.URL.Path)
+			return buildHTTPResponse(c.T, http.StatusOK, map[string]any{"acknowledged": true}), nil
+		}
+		policy, ok := c.Policies[name]
+		if !ok {
+			return buildHTTPResponse(c.T, http.StatusNotFound, map[string]any{}), nil
+		}
+		return buildHTTPResponse(c.T, http.StatusOK, map[string]any{name: policy}), nil
+	case strings.HasPrefix(req.URL.Path, "/_index_template/"):
+		name := strings.TrimPrefix(req.URL.Path, "/_index_template/")
+		if req.Method == http.MethodPut {
+			c.Templates[name] = document
+			c.Puts = append(c.Puts, req.URL.Path)
+			return buildHTTPResponse(c.T, http.StatusOK, map[string]any{"acknowledged": true}), nil
+		}
+		template, ok := c.Templates[name]
+		if !ok {
+			return buildHTTPResponse(c.T, http.StatusNotFound, map[string]any{}), nil
+		}
+		return buildHTTPResponse(c.T, http.StatusOK, map[string]any{
+			"index_templates": []any{map[string]any{"name": name, "index_template": template}},
+		}), nil
+	}
+	c.T.Errorf("Unexpected request %s %s", req.Method, req.URL.Path)
+	return buildHTTPResponse(c.T, http.StatusBadRequest, map[string]any{}), nil
+}
+
+func Test_Bootstrap_Run(t *testing.T) {
+	cluster := newMockCluster(t)
+	client := getTestClient(t, cluster)
+
+	bootstrap := NewBootstrap("app-logs", WithBootstrapClient(client))
+	if err := bootstrap.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(cluster.Puts) != 2 || cluster.Puts[0] != "/_ilm/policy/app-logs" || cluster.Puts[1] != "/_index_template/app-logs" {
+		t.Fatalf("Expected the policy then the template to be put, got %v", cluster.Puts)
+	}
+	template := cluster.Templates["app-logs"]
+	if patterns := template["index_patterns"].([]any); len(patterns) != 1 || patterns[0] != "app-logs-*" {
+		t.Errorf("Expected index pattern app-logs-*, got %v", patterns)
+	}
+	if _, ok := template["data_stream"]; ok {
+		t.Error("Expected no data stream for time-suffixed indices")
+	}
+	properties := template["template"].(map[string]any)["mappings"].(map[string]any)["properties"].(map[string]any)
+	if data := properties["data"].(map[string]any); data["type"] != "flattened" {
+		t.Errorf("Expected data to be flattened, got %v", data)
+	}
+	phases := cluster.Policies["app-logs"]["policy"].(map[string]any)["phases"].(map[string]any)
+	if _, ok := phases["hot"].(map[string]any)["actions"].(map[string]any)["rollover"]; ok {
+		t.Error("Expected no rollover for time-suffixed indices")
+	}
+	if deletePhase := phases["delete"].(map[string]any); deletePhase["min_age"] != "30d" {
+		t.Errorf("Expected delete after 30d, got %v", deletePhase)
+	}
+
+	// An installed version equal to the bootstrap version is kept.
+	if err := bootstrap.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(cluster.Puts) != 2 {
+		t.Errorf("Expected no update of the same version, got %v", cluster.Puts)
+	}
+
+	// An older bootstrap never downgrades a newer version.
+	bootstrap.Version = BootstrapVersion - 1
+	if err := bootstrap.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(cluster.Puts) != 2 {
+		t.Errorf("Expected no downgrade, got %v", cluster.Puts)
+	}
+
+	bootstrap.Version = BootstrapVersion + 1
+	if err := bootstrap.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(cluster.Puts) != 4 {
+		t.Errorf("Expected the policy and the template to be upgraded, got %v", cluster.Puts)
+	}
+
+	// A changed policy of the same version is applied, the unchanged template is kept.
+	bootstrap.Policy.DeleteAfter = "7d"
+	if err := bootstrap.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(cluster.Puts) != 5 || cluster.Puts[4] != "/_ilm/policy/app-logs" {
+		t.Errorf("Expected only the changed policy to be put, got %v", cluster.Puts)
+	}
+	phases = cluster.Policies["app-logs"]["policy"].(map[string]any)["phases"].(map[string]any)
+	if deletePhase := phases["delete"].(map[string]any); deletePhase["min_age"] != "7d" {
+		t.Errorf("Expected delete after 7d, got %v", deletePhase)
+	}
+}
+
+func Test_Bootstrap_BuiltinDataStreams(t *testing.T) {
+	cluster := newMockCluster(t)
+	client := getTestClient(t, cluster)
+
+	for _, name := range []string{"logs", "metrics-app", "*"} {
+		err := NewBootstrap(name, WithBootstrapClient(client)).Run(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "overlaps the built-in") {
+			t.Errorf("Expected %s to be refused, got %v", name, err)
+		}
+	}
+	if len(cluster.Puts) != 0 {
+		t.Errorf("Expected nothing to be installed, got %v", cluster.Puts)
+	}
+	for _, name := range []string{"app-logs", "logsapp"} {
+		if err := NewBootstrap(name).Validate(); err != nil {
+			t.Errorf("Expected %s to be accepted, got %v", name, err)
+		}
+	}
+	if err := NewBootstrap("logs-app-default", WithBootstrapDataStream()).Validate(); err != nil {
+		t.Errorf("Expected a data stream of the built-in pattern to be accepted, got %v", err)
+	}
+}
+
+func Test_Bootstrap_DataStream(t *testing.T) {
+	cluster := newMockCluster(t)
+	client := getTestClient(t, cluster)
+
+	bootstrap := NewBootstrap("logs-app-default", WithBootstrapClient(client), WithBootstrapDataStream(), WithPolicy(Policy{
+		RolloverMaxSize: "10gb",
+		DeleteAfter:     "7d",
+	}))
+	if err := bootstrap.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	template := cluster.Templates["logs-app-default"]
+	if patterns := template["index_patterns"].([]any); len(patterns) != 1 || patterns[0] != "logs-app-default" {
+		t.Errorf("Expected the data stream name as index pattern, got %v", patterns)
+	}
+	if _, ok := template["data_stream"]; !ok {
+		t.Error("Expected a data stream template")
+	}
+	properties := template["template"].(map[string]any)["mappings"].(map[string]any)["properties"].(map[string]any)
+	if _, ok := properties["@timestamp"]; !ok {
+		t.Errorf("Expected ECS mappings, got %v", properties)
+	}
+	hot := cluster.Policies["logs-app-default"]["policy"].(map[string]any)["phases"].(map[string]any)["hot"].(map[string]any)
+	rollover := hot["actions"].(map[string]any)["rollover"].(map[string]any)
+	if rollover["max_primary_shard_size"] != "10gb" || rollover["max_age"] != nil {
+		t.Errorf("Unexpected rollover action: %v", rollover)
+	}
+}
+
+func Test_Bootstrap_Error(t *testing.T) {
+	if err := NewBootstrap("app-logs", WithBootstrapClient(nil)).Run(context.Background()); err == nil {
+		t.Error("Expected error without client")
+	}
+
+	client := getTestClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return buildHTTPResponse(t, http.StatusForbidden, map[string]any{"error": "forbidden"}), nil
+	}))
+	err := NewBootstrap("app-logs", WithBootstrapClient(client)).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "ILM policy app-logs") || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected forbidden policy error, got %v", err)
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,369 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+const (
+	DefaultMaxRetries     = 3
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = 30 * time.Second
+)
+
+type DeadLetterOption func(d *DeadLetter)
+
+// DeadLetterCounters counts the documents of a DeadLetter. Rejected counts every failed attempt,
+// Retried the attempts made again and DeadLettered the documents given up on.
+type DeadLetterCounters struct {
+	Rejected     uint64
+	Retried      uint64
+	DeadLettered uint64
+}
+
+// DeadLetter is a bulk indexer retrying the documents Sink fails to index and dead-lettering the
+// documents it gives up on.
+//
+// Documents failing with a transport error, a 429 or a 5xx status are added to Sink again after
+// Backoff, up to MaxRetries times. Other failures, such as mapping conflicts, are not retried.
+// Dead-lettered documents are logged to Fallback and written as JSON lines to Writer, which may be
+// a file.Writer, then reported to the OnFailure callback of their item.
+//
+// Close makes a last attempt for the documents waiting for a retry before closing Sink, documents
+// failing from then on are dead-lettered. Writer is closed last when CloseWriter is set, the
+// documents dead-lettered afterwards are only logged to Fallback.
+type DeadLetter struct {
+	Sink       esutil.BulkIndexer
+	MaxRetries int
+	Backoff    func(attempt int) time.Duration
+	Fallback   core.Interface
+	Writer     io.Writer
+	// CloseWriter closes Writer with the DeadLetter when it is an io.Closer.
+	CloseWriter bool
+	NowFunc     func() time.Time
+
+	rejected     atomic.Uint64
+	retried      atomic.Uint64
+	deadLettered atomic.Uint64
+
+	// mu is held for reading while adding to Sink and for writing while marking it closed.
+	mu     sync.RWMutex
+	closed bool
+
+	retryMu sync.Mutex
+	closing bool
+	nextID  uint64
+	pending map[uint64]*pendingRetry
+	retryWG sync.WaitGroup
+
+	writeMu      sync.Mutex
+	writerClosed bool
+}
+
+type pendingRetry struct {
+	timer   *time.Timer
+	item    esutil.BulkIndexerItem
+	attempt int
+}
+
+// deadLetterRecord is the line written to DeadLetter.Writer.
+type deadLetterRecord struct {
+	Time       time.Time `json:"time"`
+	Index      string    `json:"index"`
+	Action     string    `json:"action"`
+	DocumentID string    `json:"document_id,omitempty"`
+	Status     int       `json:"status,omitempty"`
+	Reason     string    `json:"reason"`
+	Document   any       `json:"document"`
+}
+
+func NewDeadLetter(sink esutil.BulkIndexer, opts ...DeadLetterOption) *DeadLetter {
+	deadLetter := &DeadLetter{
+		Sink:       sink,
+		MaxRetries: DefaultMaxRetries,
+		Backoff:    ExponentialBackoff(DefaultInitialBackoff, DefaultMaxBackoff),
+		NowFunc:    time.Now,
+		pending:    make(map[uint64]*pendingRetry),
+	}
+	for _, opt := range opts {
+		opt(deadLetter)
+	}
+	return deadLetter
+}
+
+// WithDeadLetter wraps the Sink of the logger in a DeadLetter. Use it after setting the Sink.
+func WithDeadLetter(opts ...DeadLetterOption) Option {
+	return func(l *Logger) {
+		l.Sink = NewDeadLetter(l.Sink, opts...)
+	}
+}
+
+func WithMaxRetries(maxRetries int) DeadLetterOption {
+	return func(d *DeadLetter) {
+		d.MaxRetries = maxRetries
+	}
+}
+
+func WithBackoff(backoff func(attempt int) time.Duration) DeadLetterOption {
+	return func(d *DeadLetter) {
+		d.Backoff = backoff
+	}
+}
+
+func WithFallback(fallback core.Interface) DeadLetterOption {
+	return func(d *DeadLetter) {
+		d.Fallback = fallback
+	}
+}
+
+func WithDeadLetterWriter(w io.Writer) DeadLetterOption {
+	return func(d *DeadLetter) {
+		d.Writer = w
+	}
+}
+
+// WithDeadLetterWriteCloser writes the dead-lettered documents to w and closes it with the
+// DeadLetter, such as a file.Writer owned by the DeadLetter.
+func WithDeadLetterWriteCloser(w io.WriteCloser) DeadLetterOption {
+	return func(d *DeadLetter) {
+		d.Writer = w
+		d.CloseWriter = true
+	}
+}
+
+// ExponentialBackoff returns a backoff doubling from initial up to max.
+func ExponentialBackoff(initial, maxBackoff time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		backoff := initial
+		for i := 1; i < attempt && backoff < maxBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > maxBackoff {
+			return maxBackoff
+		}
+		return backoff
+	}
+}
+
+func (d *DeadLetter) Add(ctx context.Context, item esutil.BulkIndexerItem) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		d.deadLetter(ctx, item, esutil.BulkIndexerResponseItem{}, ErrSinkClosed)
+		return nil
+	}
+	return d.Sink.Add(ctx, d.wrap(item, 0))
+}
+
+func (d *DeadLetter) Close(ctx context.Context) error {
+	d.retryMu.Lock()
+	if d.closing {
+		d.retryMu.Unlock()
+		return nil
+	}
+	d.closing = true
+	pending := d.pending
+	d.pending = make(map[uint64]*pendingRetry)
+	d.retryMu.Unlock()
+
+	for _, retry := range pending {
+		if retry.timer.Stop() {
+			d.retryWG.Done()
+		}
+		d.retried.Add(1)
+		d.add(ctx, retry.item, retry.attempt)
+	}
+
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	err := d.Sink.Close(ctx)
+	d.retryWG.Wait()
+	return errors.Join(err, d.closeWriter())
+}
+
+// closeWriter closes Writer when CloseWriter is set, nothing is written to it afterwards.
+func (d *DeadLetter) closeWriter() error {
+	closer, ok := d.Writer.(io.Closer)
+	if !ok || !d.CloseWriter {
+		return nil
+	}
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+	if d.writerClosed {
+		return nil
+	}
+	d.writerClosed = true
+	return closer.Close()
+}
+
+// Flush flushes Sink when it implements Flusher. The documents waiting for a retry are not waited for.
+func (d *DeadLetter) Flush(ctx context.Context) error {
+	if flusher, ok := d.Sink.(Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+func (d *DeadLetter) Stats() esutil.BulkIndexerStats {
+	return d.Sink.Stats()
+}
+
+func (d *DeadLetter) Counters() DeadLetterCounters {
+	return DeadLetterCounters{
+		Rejected:     d.rejected.Load(),
+		Retried:      d.retried.Load(),
+		DeadLettered: d.deadLettered.Load(),
+	}
+}
+
+// wrap returns a copy of item reporting its failures to the DeadLetter. The original item is kept
+// to be retried or dead-lettered with its own callbacks.
+func (d *DeadLetter) wrap(item esutil.BulkIndexerItem, attempt int) esutil.BulkIndexerItem {
+	wrapped := item
+	wrapped.OnFailure = func(ctx context.Context, _ esutil.BulkIndexerItem, resp esutil.BulkIndexerResponseItem, err error) {
+		d.onFailure(ctx, item, attempt, resp, err)
+	}
+	return wrapped
+}
+
+// add adds item to Sink again, or dead-letters it when Sink is closed.
+func (d *DeadLetter) add(ctx context.Context, item esutil.BulkIndexerItem, attempt int) {
+	if _, err := item.Body.Seek(0, io.SeekStart); err != nil {
+		d.deadLetter(ctx, item, esutil.BulkIndexerResponseItem{}, err)
+		return
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		d.deadLetter(ctx, item, esutil.BulkIndexerResponseItem{}, ErrSinkClosed)
+		return
+	}
+	if err := d.Sink.Add(ctx, d.wrap(item, attempt)); err != nil {
+		d.deadLetter(ctx, item, esutil.BulkIndexerResponseItem{}, err)
+	}
+}
+
+func (d *DeadLetter) onFailure(ctx context.Context, item esutil.BulkIndexerItem, attempt int, resp esutil.BulkIndexerResponseItem, err error) {
+	d.rejected.Add(1)
+	if attempt >= d.MaxRetries || !retryable(resp, err) {
+		d.deadLetter(ctx, item, resp, err)
+		return
+	}
+
+	d.retryMu.Lock()
+	if d.closing {
+		d.retryMu.Unlock()
+		d.deadLetter(ctx, item, resp, err)
+		return
+	}
+	defer d.retryMu.Unlock()
+	d.nextID++
+	id := d.nextID
+	d.retryWG.Add(1)
+	d.pending[id] = &pendingRetry{
+		item:    item,
+		attempt: attempt + 1,
+		timer: time.AfterFunc(d.Backoff(attempt+1), func() {
+			defer d.retryWG.Done()
+			d.retryMu.Lock()
+			retry, ok := d.pending[id]
+			delete(d.pending, id)
+			d.retryMu.Unlock()
+			if !ok {
+				// Close took over the retry.
+				return
+			}
+			d.retried.Add(1)
+			d.add(context.Background(), retry.item, retry.attempt)
+		}),
+	}
+}
+
+// Reject dead-letters item without retrying it. It has the signature of the OnFailure callbacks so
+// the documents rejected on a Spool replay can be dead-lettered, see WithSpoolOnFailure.
+func (d *DeadLetter) Reject(ctx context.Context, item esutil.BulkIndexerItem, resp esutil.BulkIndexerResponseItem, err error) {
+	d.rejected.Add(1)
+	d.deadLetter(ctx, item, resp, err)
+}
+
+// retryable reports whether a failure may succeed later: transport errors, throttling and server
+// errors are, rejected documents are not.
+func retryable(resp esutil.BulkIndexerResponseItem, err error) bool {
+	if resp.Status == 0 {
+		return err != nil
+	}
+	return resp.Status == http.StatusTooManyRequests || resp.Status >= http.StatusInternalServerError
+}
+
+func (d *DeadLetter) deadLetter(ctx context.Context, item esutil.BulkIndexerItem, resp esutil.BulkIndexerResponseItem, err error) {
+	d.deadLettered.Add(1)
+
+	reason := failureReason(resp, err)
+	var document any
+	if item.Body != nil {
+		if _, seekErr := item.Body.Seek(0, io.SeekStart); seekErr == nil {
+			if body, readErr := io.ReadAll(item.Body); readErr == nil {
+				if json.Valid(body) {
+					document = json.RawMessage(body)
+				} else {
+					document = string(body)
+				}
+			}
+		}
+	}
+
+	if d.Fallback != nil {
+		d.Fallback.Error(ctx, "Elasticsearch document dead-lettered",
+			core.F("index", item.Index),
+			core.F("action", item.Action),
+			core.F("document_id", item.DocumentID),
+			core.F("status", resp.Status),
+			core.F("reason", reason),
+			core.F("document", document),
+		)
+	}
+	if d.Writer != nil {
+		line, marshalErr := json.Marshal(deadLetterRecord{
+			Time:       d.NowFunc(),
+			Index:      item.Index,
+			Action:     item.Action,
+			DocumentID: item.DocumentID,
+			Status:     resp.Status,
+			Reason:     reason,
+			Document:   document,
+		})
+		if marshalErr == nil {
+			d.writeMu.Lock()
+			if !d.writerClosed {
+				_, _ = d.Writer.Write(append(line, '\n'))
+			}
+			d.writeMu.Unlock()
+		}
+	}
+	if item.OnFailure != nil {
+		item.OnFailure(ctx, item, resp, err)
+	}
+}
+
+func failureReason(resp esutil.BulkIndexerResponseItem, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if resp.Error.Type != "" {
+		return fmt.Sprintf("%s: %s", resp.Error.Type, resp.Error.Reason)
+	}
+	return fmt.Sprintf("status %d", resp.Status)
+}
@@ -0,0 +1,230 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+// mockSink records the items added to it, failures are reported by the tests.
+type mockSink struct {
+	lock   sync.Mutex
+	items  []esutil.BulkIndexerItem
+	added  chan struct{}
+	closed bool
+}
+
+func newMockSink() *mockSink {
+	return &mockSink{added: make(chan struct{}, 16)}
+}
+
+func (s *mockSink) Add(_ context.Context, item esutil.BulkIndexerItem) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		panic("add to closed sink")
+	}
+	s.items = append(s.items, item)
+	s.added <- struct{}{}
+	return nil
+}
+
+func (s *mockSink) Close(_ context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *mockSink) Stats() esutil.BulkIndexerStats {
+	return esutil.BulkIndexerStats{}
+}
+
+func (s *mockSink) last(t *testing.T) esutil.BulkIndexerItem {
+	select {
+	case <-s.added:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an item")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.items[len(s.items)-1]
+}
+
+// errorRecorder records the messages and fields of Error entries.
+type errorRecorder struct {
+	noopLogger
+	lock    sync.Mutex
+	entries [][]core.Field
+}
+
+func (r *errorRecorder) Error(_ context.Context, _ string, fields ...core.Field) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.entries = append(r.entries, fields)
+}
+
+func testItem(document string, failures *int) esutil.BulkIndexerItem {
+	return esutil.BulkIndexerItem{
+		Action: ActionIndex,
+		Index:  testIndex,
+		Body:   strings.NewReader(document),
+		OnFailure: func(_ context.Context, _ esutil.BulkIndexerItem, _ esutil.BulkIndexerResponseItem, _ error) {
+			*failures++
+		},
+	}
+}
+
+func Test_DeadLetter_Rejected(t *testing.T) {
+	sink := newMockSink()
+	fallback := &errorRecorder{}
+	buffer := &bytes.Buffer{}
+	deadLetter := NewDeadLetter(sink, WithFallback(fallback), WithDeadLetterWriter(buffer))
+	deadLetter.NowFunc = testNowFunc
+
+	failures := 0
+	if err := deadLetter.Add(context.Background(), testItem(`{"message":"rejected"}`, &failures)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	item := sink.last(t)
+	resp := esutil.BulkIndexerResponseItem{Status: 400}
+	resp.Error.Type = "mapper_parsing_exception"
+	resp.Error.Reason = "failed to parse field [data.id]"
+	item.OnFailure(context.Background(), item, resp, nil)
+
+	if counters := deadLetter.Counters(); counters != (DeadLetterCounters{Rejected: 1, DeadLettered: 1}) {
+		t.Errorf("Unexpected counters: %+v", counters)
+	}
+	if failures != 1 {
+		t.Errorf("Expected the item callback to be called once, got %d", failures)
+	}
+	if len(fallback.entries) != 1 {
+		t.Fatalf("Expected one fallback entry, got %d", len(fallback.entries))
+	}
+	var record map[string]any
+	if err := json.Unmarshal(buffer.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to unmarshal dead letter line %q: %v", buffer.String(), err)
+	}
+	if record["reason"] != "mapper_parsing_exception: failed to parse field [data.id]" || record["status"] != float64(400) {
+		t.Errorf("Unexpected dead letter record: %v", record)
+	}
+	if document, ok := record["document"].(map[string]any); !ok || document["message"] != "rejected" {
+		t.Errorf("Expected the document in the dead letter record, got %v", record["document"])
+	}
+}
+
+func Test_DeadLetter_Retry(t *testing.T) {
+	sink := newMockSink()
+	buffer := &bytes.Buffer{}
+	deadLetter := NewDeadLetter(sink, WithMaxRetries(2), WithDeadLetterWriter(buffer), WithBackoff(func(int) time.Duration {
+		return time.Millisecond
+	}))
+
+	failures := 0
+	if err := deadLetter.Add(context.Background(), testItem(`{"message":"unavailable"}`, &failures)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		item := sink.last(t)
+		item.OnFailure(context.Background(), item, esutil.BulkIndexerResponseItem{}, errors.New("connection refused"))
+	}
+	item := sink.last(t)
+	item.OnFailure(context.Background(), item, esutil.BulkIndexerResponseItem{Status: 503}, nil)
+
+	if counters := deadLetter.Counters(); counters != (DeadLetterCounters{Rejected: 3, Retried: 2, DeadLettered: 1}) {
+		t.Errorf("Unexpected counters: %+v", counters)
+	}
+	if failures != 1 || !strings.Contains(buffer.String(), `"document":{"message":"unavailable"}`) {
+		t.Errorf("Expected the document to be dead-lettered once, got %d failures and %q", failures, buffer.String())
+	}
+}
+
+func Test_DeadLetter_Close(t *testing.T) {
+	sink := newMockSink()
+	buffer := &bytes.Buffer{}
+	deadLetter := NewDeadLetter(sink, WithDeadLetterWriter(buffer), WithBackoff(func(int) time.Duration {
+		return time.Hour
+	}))
+
+	failures := 0
+	if err := deadLetter.Add(context.Background(), testItem(`{"message":"pending"}`, &failures)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	item := sink.last(t)
+	item.OnFailure(context.Background(), item, esutil.BulkIndexerResponseItem{Status: 429}, nil)
+
+	if err := deadLetter.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if retried := sink.last(t); retried.Index != testIndex {
+		t.Errorf("Expected the pending retry to be added before closing, got %+v", retried)
+	}
+	if !sink.closed {
+		t.Error("Expected the sink to be closed")
+	}
+
+	if err := deadLetter.Add(context.Background(), testItem(`{"message":"late"}`, &failures)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if failures != 1 || !strings.Contains(buffer.String(), ErrSinkClosed.Error()) {
+		t.Errorf("Expected the late document to be dead-lettered, got %d failures and %q", failures, buffer.String())
+	}
+}
+
+// closingBuffer is a bytes.Buffer recording its closes.
+type closingBuffer struct {
+	bytes.Buffer
+	closed int
+}
+
+func (b *closingBuffer) Close() error {
+	b.closed++
+	return nil
+}
+
+func Test_DeadLetter_CloseWriter(t *testing.T) {
+	shared, owned := &closingBuffer{}, &closingBuffer{}
+	if err := NewDeadLetter(newMockSink(), WithDeadLetterWriter(shared)).Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if shared.closed != 0 {
+		t.Errorf("Expected the writer set by WithDeadLetterWriter to be left open, got %d closes", shared.closed)
+	}
+
+	deadLetter := NewDeadLetter(newMockSink(), WithDeadLetterWriteCloser(owned))
+	if err := deadLetter.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := deadLetter.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if owned.closed != 1 {
+		t.Errorf("Expected the owned writer to be closed once, got %d closes", owned.closed)
+	}
+	failures := 0
+	if err := deadLetter.Add(context.Background(), testItem(`{"message":"late"}`, &failures)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if failures != 1 || owned.Len() != 0 {
+		t.Errorf("Expected the late document to be reported without writing to the closed writer, got %d failures and %q", failures, owned.String())
+	}
+}
+
+func Test_ExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 5*time.Second)
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, duration := range expected {
+		if actual := backoff(i + 1); actual != duration {
+			t.Errorf("Expected backoff %s for attempt %d, got %s", duration, i+1, actual)
+		}
+	}
+}
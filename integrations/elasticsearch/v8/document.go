@@ -0,0 +1,179 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+// ECSVersion is the Elastic Common Schema version of the documents built by NewECSDocumentBuilder.
+const ECSVersion = "8.11.0"
+
+const (
+	envAppName     = "APP_NAME"
+	envAppVersion  = "APP_VERSION"
+	envEnvironment = "ENVIRONMENT"
+	envEnv         = "ENV"
+)
+
+// DocumentBuilder returns the document indexed for an entry.
+type DocumentBuilder func(ctx context.Context, logger *Logger, level core.Level, msg string, fields []core.Field) (map[string]any, error)
+
+// DefaultDocumentBuilder writes the timestamp, level, message and name of the entry, the trace
+// fields and the logger fields at the top level and the entry fields under data.
+var DefaultDocumentBuilder DocumentBuilder = func(ctx context.Context, logger *Logger, level core.Level, msg string, fields []core.Field) (map[string]any, error) {
+	payload := map[string]any{
+		"timestamp": logger.NowFunc().Format(time.RFC3339),
+		"level":     level.String(),
+	}
+	if msg != "" {
+		payload["message"] = msg
+	}
+	if logger.Name != "" {
+		payload["name"] = logger.Name
+	}
+	for _, field := range core.TraceFields(ctx) {
+		payload[field.Key] = field.Value
+	}
+
+	for _, field := range logger.Extra {
+		if _, ok := payload[field.Key]; ok {
+			logger.DebugLogger.Warning(ctx, "Field already exists in payload, overwriting", core.F("key", field.Key))
+		}
+		payload[field.Key] = field.Value
+	}
+
+	if len(fields) > 0 {
+		data := make(map[string]any, len(fields))
+		for _, field := range fields {
+			data[field.Key] = field.Value
+		}
+		payload["data"] = data
+	}
+	return payload, nil
+}
+
+// ECSService identifies the service writing the entries in ECS documents.
+type ECSService struct {
+	Name        string
+	Version     string
+	Environment string
+}
+
+// DefaultECSService is read from the APP_NAME, APP_VERSION and ENVIRONMENT or ENV environment variables.
+var DefaultECSService ECSService
+
+// ECSDocumentBuilder builds Elastic Common Schema documents for DefaultECSService.
+var ECSDocumentBuilder DocumentBuilder = func(ctx context.Context, logger *Logger, level core.Level, msg string, fields []core.Field) (map[string]any, error) {
+	return NewECSDocumentBuilder(DefaultECSService)(ctx, logger, level, msg, fields)
+}
+
+// NewECSDocumentBuilder returns a DocumentBuilder writing Elastic Common Schema documents, which
+// the Kibana Logs UI reads without further mapping. The entry is written to @timestamp, log.level,
+// log.logger and message, the active span to trace.id and span.id and service to service.*.
+//
+// The first error field is written to error.message and error.type, with error.stack_trace when
+// the error formats a stack with %+v. The other fields, of the logger first, are written to
+// labels.*, as strings since ECS labels are keywords. Dots in their keys are replaced with
+// underscores, maps, slices and structs are written as JSON.
+func NewECSDocumentBuilder(service ECSService) DocumentBuilder {
+	return func(ctx context.Context, logger *Logger, level core.Level, msg string, fields []core.Field) (map[string]any, error) {
+		log := map[string]any{"level": strings.ToLower(level.String())}
+		if logger.Name != "" {
+			log["logger"] = logger.Name
+		}
+		document := map[string]any{
+			"@timestamp": logger.NowFunc().Format(time.RFC3339Nano),
+			"message":    msg,
+			"log":        log,
+			"ecs":        map[string]any{"version": ECSVersion},
+		}
+		if svc := service.document(); len(svc) > 0 {
+			document["service"] = svc
+		}
+		for _, field := range core.TraceFields(ctx) {
+			switch field.Key {
+			case core.TraceIDKey:
+				document["trace"] = map[string]any{"id": field.Value}
+			case core.SpanIDKey:
+				document["span"] = map[string]any{"id": field.Value}
+			}
+		}
+		labels := make(map[string]any)
+		for _, field := range append(append([]core.Field{}, logger.Extra...), fields...) {
+			if err, ok := field.Value.(error); ok && err != nil {
+				if _, exists := document["error"]; !exists {
+					document["error"] = ecsError(err)
+					continue
+				}
+			}
+			labels[strings.ReplaceAll(field.Key, ".", "_")] = labelValue(field.Value)
+		}
+		if len(labels) > 0 {
+			document["labels"] = labels
+		}
+		return document, nil
+	}
+}
+
+func (s ECSService) document() map[string]any {
+	document := make(map[string]any)
+	if s.Name != "" {
+		document["name"] = s.Name
+	}
+	if s.Version != "" {
+		document["version"] = s.Version
+	}
+	if s.Environment != "" {
+		document["environment"] = s.Environment
+	}
+	return document
+}
+
+func ecsError(err error) map[string]any {
+	document := map[string]any{
+		"message": err.Error(),
+		"type":    fmt.Sprintf("%T", err),
+	}
+	if verbose := fmt.Sprintf("%+v", err); verbose != err.Error() {
+		document["stack_trace"] = verbose
+	}
+	return document
+}
+
+// labelValue formats a field value as an ECS label.
+func labelValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%+v", value)
+	}
+	return string(encoded)
+}
+
+func init() {
+	DefaultECSService.Name = os.Getenv(envAppName)
+	DefaultECSService.Version = os.Getenv(envAppVersion)
+	DefaultECSService.Environment = os.Getenv(envEnvironment)
+	if DefaultECSService.Environment == "" {
+		DefaultECSService.Environment = os.Getenv(envEnv)
+	}
+}
@@ -0,0 +1,263 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+// Field modes of a FieldGuard.
+const (
+	// FieldModeTyped suffixes the keys with the kind of their value, user_str or user_obj.
+	FieldModeTyped = "typed"
+	// FieldModeFlattened writes every value under its dotted key path followed by its kind,
+	// user.name.str, without nesting objects.
+	FieldModeFlattened = "flattened"
+)
+
+const (
+	DefaultMaxFieldDepth = 5
+	DefaultMaxFields     = 100
+)
+
+// Value kinds written by a FieldGuard. Values of another kind, or nested deeper than MaxDepth, are
+// written as JSON strings.
+const (
+	kindString = "str"
+	kindInt    = "int"
+	kindFloat  = "float"
+	kindBool   = "bool"
+	kindTime   = "time"
+	kindJSON   = "json"
+	kindObject = "obj"
+)
+
+var fieldKinds = map[string]bool{
+	kindString: true, kindInt: true, kindFloat: true, kindBool: true, kindTime: true, kindJSON: true, kindObject: true,
+}
+
+// droppedKey counts the fields dropped beyond MaxFields.
+const droppedKey = "_dropped"
+
+// FieldGuard rewrites the entry fields written under data so that documents of every service share
+// one mapping: every value is written under a name carrying its kind, so a key logged as a string
+// by one service and as an object by another maps to two fields instead of rejecting the document.
+//
+// The kind suffixes do not stop Elasticsearch from guessing the type of a new field: with the
+// default date_detection, a user_str or user.name.str field whose first value looks like a date is
+// mapped as a date, and the documents with another string under that name are rejected. The index
+// template must disable date_detection, or map data as flattened as the Bootstrap template does.
+//
+// Objects nested deeper than MaxDepth are written as JSON strings, and the fields beyond MaxFields
+// are dropped and counted in _dropped. Dots in keys are replaced with underscores. Mode defaults to
+// FieldModeTyped.
+type FieldGuard struct {
+	Mode      string
+	MaxDepth  int
+	MaxFields int
+}
+
+// NewGuardedDocumentBuilder returns DefaultDocumentBuilder with the logger fields and the entry
+// fields rewritten by guard. The logger fields are written under data with the entry fields rather
+// than at the top level, so they cannot conflict in the mapping either.
+func NewGuardedDocumentBuilder(guard FieldGuard) DocumentBuilder {
+	return func(ctx context.Context, logger *Logger, level core.Level, msg string, fields []core.Field) (map[string]any, error) {
+		unbound := *logger
+		unbound.Extra = nil
+		payload, err := DefaultDocumentBuilder(ctx, &unbound, level, msg, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(logger.Extra)+len(fields) > 0 {
+			payload["data"] = guard.Apply(append(append([]core.Field{}, logger.Extra...), fields...))
+		}
+		return payload, nil
+	}
+}
+
+// WithFieldGuard writes the entry fields rewritten by guard.
+func WithFieldGuard(guard FieldGuard) Option {
+	return func(l *Logger) {
+		l.DocumentBuilder = NewGuardedDocumentBuilder(guard)
+	}
+}
+
+// Apply returns the fields rewritten according to the mode of the guard.
+func (g FieldGuard) Apply(fields []core.Field) map[string]any {
+	if g.MaxDepth <= 0 {
+		g.MaxDepth = DefaultMaxFieldDepth
+	}
+	if g.MaxFields <= 0 {
+		g.MaxFields = DefaultMaxFields
+	}
+	w := &guardWriter{guard: g}
+	data := make(map[string]any, len(fields))
+	for _, field := range fields {
+		w.write(data, "", w.fieldName(field.Key), normalizeValue(field.Value), 1)
+	}
+	if w.dropped > 0 {
+		data[w.name("", droppedKey, kindInt)] = w.dropped
+	}
+	return data
+}
+
+type guardWriter struct {
+	guard   FieldGuard
+	count   int
+	dropped int
+}
+
+// name returns the key of a value of kind at key under the path prefix.
+func (w *guardWriter) name(prefix, key, kind string) string {
+	if w.guard.Mode == FieldModeFlattened {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		return key + "." + kind
+	}
+	return key + "_" + kind
+}
+
+func (w *guardWriter) write(target map[string]any, prefix, key string, value any, depth int) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case map[string]any:
+		if len(v) == 0 {
+			return
+		}
+		if depth >= w.guard.MaxDepth {
+			w.leaf(target, prefix, key, kindJSON, jsonString(v))
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if w.guard.Mode == FieldModeFlattened {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			for _, k := range keys {
+				w.write(target, path, w.fieldName(k), v[k], depth+1)
+			}
+			return
+		}
+		child := make(map[string]any, len(v))
+		for _, k := range keys {
+			w.write(child, "", w.fieldName(k), v[k], depth+1)
+		}
+		if len(child) > 0 {
+			target[w.name("", key, kindObject)] = child
+		}
+	case []any:
+		if len(v) == 0 {
+			return
+		}
+		kind := ""
+		for _, element := range v {
+			elementKind := valueKind(element)
+			if kind == kindFloat && elementKind == kindInt || kind == kindInt && elementKind == kindFloat {
+				// Integers are indexed into float fields without loss.
+				kind = kindFloat
+				continue
+			}
+			if kind != "" && elementKind != kind || elementKind == kindJSON {
+				kind = kindJSON
+				break
+			}
+			kind = elementKind
+		}
+		if kind == kindJSON {
+			w.leaf(target, prefix, key, kindJSON, jsonString(v))
+			return
+		}
+		w.leaf(target, prefix, key, kind, v)
+	default:
+		w.leaf(target, prefix, key, valueKind(v), v)
+	}
+}
+
+func (w *guardWriter) leaf(target map[string]any, prefix, key, kind string, value any) {
+	if w.count >= w.guard.MaxFields {
+		w.dropped++
+		return
+	}
+	w.count++
+	if kind == kindJSON {
+		if _, ok := value.(string); !ok {
+			value = jsonString(value)
+		}
+	}
+	target[w.name(prefix, key, kind)] = value
+}
+
+// fieldName replaces the dots Elasticsearch reads as object paths. Flattened keys equal to a kind
+// are suffixed, so a key never collides with the kind of a value.
+func (w *guardWriter) fieldName(key string) string {
+	key = strings.ReplaceAll(key, ".", "_")
+	if key == "" || w.guard.Mode == FieldModeFlattened && fieldKinds[key] {
+		key += "_"
+	}
+	return key
+}
+
+// valueKind returns the kind of a normalized scalar value.
+func valueKind(value any) string {
+	switch v := value.(type) {
+	case string:
+		return kindString
+	case bool:
+		return kindBool
+	case time.Time:
+		return kindTime
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return kindInt
+		}
+		return kindFloat
+	}
+	return kindJSON
+}
+
+// normalizeValue converts a field value to the values decoded from its JSON encoding, keeping
+// times and using the message of errors.
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.UTC()
+	case string, bool:
+		return v
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%+v", value)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded any
+	if err = decoder.Decode(&decoded); err != nil {
+		return string(encoded)
+	}
+	return decoded
+}
+
+func jsonString(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%+v", value)
+	}
+	return string(encoded)
+}
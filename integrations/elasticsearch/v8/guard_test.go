@@ -0,0 +1,111 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+type testUser struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
+func guardJSON(t *testing.T, data map[string]any) string {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("Failed to marshal data: %v", err)
+	}
+	return string(encoded)
+}
+
+func Test_FieldGuard_Typed(t *testing.T) {
+	guard := FieldGuard{Mode: FieldModeTyped}
+	data := guard.Apply([]core.Field{
+		core.F("user", testUser{ID: 7, Name: "ada", Roles: []string{"admin"}}),
+		core.F("user.email", "ada@example.com"),
+		core.F("ratio", 0.5),
+		core.F("mixed", []any{1, "one"}),
+		core.F("at", time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)),
+		core.E(errors.New("boom")),
+		core.F("nothing", nil),
+	})
+	expected := `{"at_time":"2023-10-01T12:00:00Z","error_str":"boom","mixed_json":"[1,\"one\"]","ratio_float":0.5,` +
+		`"user_email_str":"ada@example.com","user_obj":{"id_int":7,"name_str":"ada","roles_str":["admin"]}}`
+	if actual := guardJSON(t, data); actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+
+	// A key logged as a string and as an object maps to different fields.
+	asString := guard.Apply([]core.Field{core.F("user", "ada")})
+	if _, ok := asString["user_str"]; !ok {
+		t.Errorf("Expected user_str, got %v", asString)
+	}
+}
+
+func Test_FieldGuard_Flattened(t *testing.T) {
+	guard := FieldGuard{Mode: FieldModeFlattened}
+	data := guard.Apply([]core.Field{
+		core.F("user", map[string]any{"name": "ada", "str": true, "tags": []any{1, 2.5}}),
+		core.F("count", 3),
+	})
+	expected := map[string]any{
+		"user.name.str":   "ada",
+		"user.str_.bool":  true,
+		"user.tags.float": []any{json.Number("1"), json.Number("2.5")},
+		"count.int":       json.Number("3"),
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %v, got %v", expected, data)
+	}
+}
+
+func Test_FieldGuard_Limits(t *testing.T) {
+	guard := FieldGuard{MaxDepth: 2, MaxFields: 3}
+	fields := []core.Field{
+		core.F("nested", map[string]any{"a": map[string]any{"b": 1}, "c": 2}),
+	}
+	for i := 0; i < 4; i++ {
+		fields = append(fields, core.F(fmt.Sprintf("field%d", i), i))
+	}
+	expected := `{"_dropped_int":3,"field0_int":0,"nested_obj":{"a_json":"{\"b\":1}","c_int":2}}`
+	if actual := guardJSON(t, guard.Apply(fields)); actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+}
+
+func Test_Logger_FieldGuard(t *testing.T) {
+	logger := New(WithFieldGuard(FieldGuard{}))
+	logger.NowFunc = testNowFunc
+	document, err := logger.DocumentBuilder(context.Background(), logger, core.LevelInfo, "message", []core.Field{core.F("user", "ada")})
+	if err != nil {
+		t.Fatalf("DocumentBuilder failed: %v", err)
+	}
+	if document["message"] != "message" || !reflect.DeepEqual(document["data"], map[string]any{"user_str": "ada"}) {
+		t.Errorf("Unexpected document: %v", document)
+	}
+}
+
+func Test_Logger_FieldGuardExtra(t *testing.T) {
+	logger := New(WithFieldGuard(FieldGuard{MaxFields: 2})).With(core.F("service", map[string]any{"name": "billing"})).(*Logger)
+	logger.NowFunc = testNowFunc
+	fields := []core.Field{core.F("user", "ada"), core.F("order", 42)}
+	document, err := logger.DocumentBuilder(context.Background(), logger, core.LevelInfo, "message", fields)
+	if err != nil {
+		t.Fatalf("DocumentBuilder failed: %v", err)
+	}
+	if _, ok := document["service"]; ok {
+		t.Errorf("Expected no unguarded logger field at the top level, got %v", document)
+	}
+	expected := map[string]any{"service_obj": map[string]any{"name_str": "billing"}, "user_str": "ada", "_dropped_int": 1}
+	if !reflect.DeepEqual(document["data"], expected) {
+		t.Errorf("Expected the logger fields to be guarded with the entry fields, got %v", document["data"])
+	}
+}
@@ -17,7 +17,12 @@ import (
 const (
 	envIndexName = "ELASTICSEARCH_INDEX_NAME"
 	envLogLevel  = "ELASTICSEARCH_LOG_LEVEL"
-	actionType   = "index"
+)
+
+// Bulk actions of the indexed documents, data streams only accept ActionCreate.
+const (
+	ActionIndex  = "index"
+	ActionCreate = "create"
 )
 
 var (
@@ -32,20 +37,56 @@ type DocumentIDBuilder func() string
 const Type = "elasticsearch"
 
 var (
-	DefaultIndexBuilder IndexBuilder = func(_ context.Context, _ *Logger, _ core.Level, _ string, _ []core.Field) (string, error) {
-		return fmt.Sprintf("%v-%v", defaultIndexName, time.Now().Format("2006-01-02-15-01-05")), nil
+	DefaultIndexBuilder IndexBuilder = func(ctx context.Context, logger *Logger, level core.Level, msg string, fields []core.Field) (string, error) {
+		return NewIndexBuilder(defaultIndexName)(ctx, logger, level, msg, fields)
 	}
 	DefaultDocumentIDBuilder DocumentIDBuilder = func() string {
 		return ""
 	}
 )
 
+// IndexTimeLayout is the layout of the date suffix of the indices of NewIndexBuilder, one index per day.
+const IndexTimeLayout = "2006.01.02"
+
+// NewIndexBuilder returns an IndexBuilder that writes to daily indices starting with indexName.
+func NewIndexBuilder(indexName string) IndexBuilder {
+	return func(_ context.Context, logger *Logger, _ core.Level, _ string, _ []core.Field) (string, error) {
+		return fmt.Sprintf("%v-%v", indexName, logger.NowFunc().Format(IndexTimeLayout)), nil
+	}
+}
+
+// WithLevel indexes the entries at level and above instead of the ELASTICSEARCH_LOG_LEVEL level.
+func WithLevel(level core.Level) Option {
+	return func(l *Logger) {
+		l.Level = level
+	}
+}
+
+// WithDataStream writes the entries to the data stream name with the create action, as ECS
+// documents since data streams require an @timestamp field. Set DocumentBuilder after this option
+// to write other documents.
+func WithDataStream(name string) Option {
+	return func(l *Logger) {
+		l.Action = ActionCreate
+		l.DocumentBuilder = ECSDocumentBuilder
+		l.IndexBuilder = func(_ context.Context, _ *Logger, _ core.Level, _ string, _ []core.Field) (string, error) {
+			return name, nil
+		}
+	}
+}
+
+// Logger indexes entries into Elasticsearch. Level is the level set by New and the options, the
+// level changed at runtime with SetLevel is shared by every logger derived from the same New call
+// and is reported by Levels.
 type Logger struct {
 	Name              string
 	NowFunc           func() time.Time
 	Extra             []core.Field
 	Level             core.Level
+	level             *core.AtomicLevel
+	Action            string
 	IndexBuilder      IndexBuilder
+	DocumentBuilder   DocumentBuilder
 	DocumentIDBuilder DocumentIDBuilder
 	DebugLogger       core.Interface
 	Sink              esutil.BulkIndexer
@@ -58,7 +99,9 @@ func New(options ...Option) *Logger {
 	logger := &Logger{
 		NowFunc:           time.Now,
 		Level:             defaultLevel,
+		Action:            ActionIndex,
 		IndexBuilder:      DefaultIndexBuilder,
+		DocumentBuilder:   DefaultDocumentBuilder,
 		DocumentIDBuilder: DefaultDocumentIDBuilder,
 		DebugLogger:       debugLogger,
 		Sink:              globalSink,
@@ -72,6 +115,7 @@ func New(options ...Option) *Logger {
 	for _, opt := range options {
 		opt(logger)
 	}
+	logger.level = core.NewAtomicLevel(logger.Level)
 	return logger
 }
 
@@ -102,6 +146,12 @@ func (l *Logger) With(fields ...core.Field) core.Interface {
 	return _l
 }
 
+func (l *Logger) Trace(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanLog(core.LevelTrace) {
+		l.Log(ctx, core.LevelTrace, msg, fields)
+	}
+}
+
 func (l *Logger) Debug(ctx context.Context, msg string, fields ...core.Field) {
 	if l.CanLog(core.LevelDebug) {
 		l.Log(ctx, core.LevelDebug, msg, fields)
@@ -126,12 +176,41 @@ func (l *Logger) Error(ctx context.Context, msg string, fields ...core.Field) {
 	}
 }
 
+func (l *Logger) Fatal(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanLog(core.LevelFatal) {
+		l.Log(ctx, core.LevelFatal, msg, fields)
+	}
+}
+
+func (l *Logger) Panic(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanLog(core.LevelPanic) {
+		l.Log(ctx, core.LevelPanic, msg, fields)
+	}
+}
+
+// Flush delivers the buffered documents and waits for their results when Sink implements Flusher,
+// as the sinks of NewSink do. Other sinks deliver them on their own flush interval.
 func (l *Logger) Flush(ctx context.Context) error {
+	flusher, ok := l.Sink.(Flusher)
+	if !ok {
+		return nil
+	}
+	if err := flusher.Flush(ctx); err != nil {
+		l.DebugLogger.Error(ctx, "Failed to flush indexer", core.E(err))
+		return err
+	}
+	l.DebugLogger.Debug(ctx, "Flushed indexer", core.F("stats", l.Sink.Stats()))
+	return nil
+}
+
+// Close delivers the buffered documents and closes Sink, which is shared by the loggers derived
+// from this one and, by default, by every logger using the global sink.
+func (l *Logger) Close(ctx context.Context) error {
 	if err := l.Sink.Close(ctx); err != nil {
 		l.DebugLogger.Error(ctx, "Failed to close indexer", core.E(err))
 		return err
 	}
-	l.DebugLogger.Debug(ctx, "Flushed indexer", core.F("stats", l.Sink.Stats()))
+	l.DebugLogger.Debug(ctx, "Closed indexer", core.F("stats", l.Sink.Stats()))
 	return nil
 }
 
@@ -148,7 +227,7 @@ func (l *Logger) Log(ctx context.Context, level core.Level, msg string, fields [
 	}
 	if err = l.Sink.Add(ctx, esutil.BulkIndexerItem{
 		DocumentID: l.DocumentIDBuilder(),
-		Action:     actionType,
+		Action:     l.Action,
 		Index:      index,
 		Body:       body,
 		OnSuccess:  l.OnSuccess,
@@ -159,44 +238,52 @@ func (l *Logger) Log(ctx context.Context, level core.Level, msg string, fields [
 }
 
 func (l *Logger) buildDocument(ctx context.Context, level core.Level, msg string, fields []core.Field) (io.ReadSeeker, error) {
-	payload := map[string]any{
-		"timestamp": l.NowFunc().Format(time.RFC3339),
-		"level":     level.String(),
-	}
-	if msg != "" {
-		payload["message"] = msg
+	payload, err := l.DocumentBuilder(ctx, l, level, msg, fields)
+	if err != nil {
+		return nil, err
 	}
-	if l.Name != "" {
-		payload["name"] = l.Name
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal log payload: %w", err)
 	}
+	return bytes.NewReader(body), nil
+}
 
-	if len(l.Extra) > 0 {
-		for _, field := range l.Extra {
-			if _, ok := payload[field.Key]; ok {
-				l.DebugLogger.Warning(ctx, "Field already exists in payload, overwriting", core.F("key", field.Key))
-			}
-			payload[field.Key] = field.Value
-		}
+func (l *Logger) CanLog(level core.Level) bool {
+	return l.getLevel().Enabled(level)
+}
+
+// SetLevel changes the level of every logger derived from the same New call.
+func (l *Logger) SetLevel(level core.Level) {
+	if l.level == nil {
+		l.Level = level
+		return
 	}
+	l.level.Store(level)
+}
+
+func (l *Logger) Levels() map[string]core.Level {
+	return map[string]core.Level{core.LogLevelName: l.getLevel()}
+}
 
-	if len(fields) > 0 {
-		data := make(map[string]any, len(fields))
-		for _, field := range fields {
-			data[field.Key] = field.Value
+func (l *Logger) SetLevels(levels map[string]core.Level) error {
+	for name := range levels {
+		if name != core.LogLevelName {
+			return fmt.Errorf("unknown %s level: %s", Type, name)
 		}
-		payload["data"] = data
 	}
-
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal log payload: %w", err)
+	for _, level := range levels {
+		l.SetLevel(level)
 	}
-
-	return bytes.NewReader(body), nil
+	return nil
 }
 
-func (l *Logger) CanLog(level core.Level) bool {
-	return l.Level <= level
+// getLevel returns the level changed at runtime, Level when the logger was not created by New.
+func (l *Logger) getLevel() core.Level {
+	if l.level == nil {
+		return l.Level
+	}
+	return l.level.Load()
 }
 
 func (l *Logger) clone() *Logger {
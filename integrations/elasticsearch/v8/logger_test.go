@@ -2,11 +2,15 @@ package elastic
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"testing"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/ensarkovankaya/go-logging/core"
 )
@@ -82,9 +86,26 @@ func Test_Logger_With(t *testing.T) {
 	}
 }
 
+func Test_Logger_SetLevel(t *testing.T) {
+	logger, _ := getTestLogger(t, func(l *Logger) {
+		l.Level = core.LevelError
+	})
+	named := logger.Named("child").(*Logger)
+	if named.CanLog(core.LevelWarning) || !named.CanLog(core.LevelError) {
+		t.Errorf("Expected the level set by the option, got %v", named.Levels())
+	}
+	named.SetLevel(core.LevelDebug)
+	if !logger.CanLog(core.LevelDebug) || logger.Levels()[core.LogLevelName] != core.LevelDebug {
+		t.Errorf("Expected the level to be shared with the parent logger, got %v", logger.Levels())
+	}
+	if logger.Level != core.LevelError || named.Level != core.LevelError {
+		t.Errorf("Expected Level to keep the level set by the option, got %v and %v", logger.Level, named.Level)
+	}
+}
+
 func Test_Logger_CanLog_LevelDisabled(t *testing.T) {
 	logger, _ := getTestLogger(t)
-	logger.Level = core.LevelDisabled
+	logger.SetLevel(core.LevelDisabled)
 	if logger.CanLog(core.LevelError) {
 		t.Error("Expected logger to not log at LevelError when Level is set to LevelDisabled")
 	}
@@ -101,7 +122,7 @@ func Test_Logger_CanLog_LevelDisabled(t *testing.T) {
 
 func Test_Logger_CanLog_LevelError(t *testing.T) {
 	logger, _ := getTestLogger(t)
-	logger.Level = core.LevelError
+	logger.SetLevel(core.LevelError)
 	if !logger.CanLog(core.LevelError) {
 		t.Error("Expected logger to log at LevelError when Level is set to LevelError")
 	}
@@ -118,7 +139,7 @@ func Test_Logger_CanLog_LevelError(t *testing.T) {
 
 func Test_Logger_CanLog_LevelWarning(t *testing.T) {
 	logger, _ := getTestLogger(t)
-	logger.Level = core.LevelWarning
+	logger.SetLevel(core.LevelWarning)
 	if !logger.CanLog(core.LevelError) {
 		t.Error("Expected logger to log at LevelError when Level is set to LevelWarning")
 	}
@@ -135,7 +156,7 @@ func Test_Logger_CanLog_LevelWarning(t *testing.T) {
 
 func Test_Logger_CanLog_LevelInfo(t *testing.T) {
 	logger, _ := getTestLogger(t)
-	logger.Level = core.LevelInfo
+	logger.SetLevel(core.LevelInfo)
 	if !logger.CanLog(core.LevelError) {
 		t.Error("Expected logger to log at LevelError when Level is set to LevelInfo")
 	}
@@ -152,7 +173,7 @@ func Test_Logger_CanLog_LevelInfo(t *testing.T) {
 
 func Test_Logger_CanLog_LevelDebug(t *testing.T) {
 	logger, _ := getTestLogger(t)
-	logger.Level = core.LevelDebug
+	logger.SetLevel(core.LevelDebug)
 	if !logger.CanLog(core.LevelError) {
 		t.Error("Expected logger to log at LevelError when Level is set to LevelDebug")
 	}
@@ -467,10 +488,36 @@ func Test_Logger_Debug_Multiple(t *testing.T) {
 	})
 }
 
+func Test_Logger_TraceFields(t *testing.T) {
+	logger, transport := getTestLogger(t)
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03},
+		SpanID:     trace.SpanID{0x04, 0x05},
+		TraceFlags: trace.FlagsSampled,
+	})
+	logger.Info(trace.ContextWithSpanContext(context.Background(), spanContext), "Info message")
+	if err := logger.Sink.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+	if len(transport.IndexRequests) != 1 {
+		t.Fatalf("Expected 1 indexed log, got %d", len(transport.IndexRequests))
+	}
+	expected := map[string]string{
+		core.TraceIDKey:    spanContext.TraceID().String(),
+		core.SpanIDKey:     spanContext.SpanID().String(),
+		core.TraceFlagsKey: spanContext.TraceFlags().String(),
+	}
+	for key, value := range expected {
+		if transport.IndexRequests[0][key] != value {
+			t.Errorf("Expected indexed log %s '%s', got '%v'", key, value, transport.IndexRequests[0][key])
+		}
+	}
+}
+
 //nolint:gocyclo
 func testLogger(t *testing.T, loggerLevel core.Level, logs []testCase) {
 	logger, transport := getTestLogger(t)
-	logger.Level = loggerLevel
+	logger.SetLevel(loggerLevel)
 
 	expectedStats := esutil.BulkIndexerStats{}
 
@@ -605,8 +652,8 @@ func testLogger(t *testing.T, loggerLevel core.Level, logs []testCase) {
 	}
 }
 
-func getTestLogger(t *testing.T, options ...Option) (*Logger, *mockRoundTrip) {
-	transport := newMockTransport(t)
+func getTestLogger(t *testing.T, options ...Option) (*Logger, *mockBulkRoundTrip) {
+	transport := newMockBulkTransport(t)
 	sink := getTestSink(t, transport)
 	options = append([]Option{func(l *Logger) {
 		l.Sink = sink
@@ -631,3 +678,80 @@ func getTestSink(t *testing.T, transport http.RoundTripper, options ...SinkOptio
 	}
 	return sink
 }
+
+func Test_NewIndexBuilder(t *testing.T) {
+	logger := New(func(l *Logger) {
+		l.NowFunc = func() time.Time {
+			return time.Date(2023, 10, 1, 12, 34, 56, 0, time.UTC)
+		}
+	})
+	index, err := NewIndexBuilder("logs")(context.Background(), logger, core.LevelInfo, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to build index: %v", err)
+	}
+	if index != "logs-2023.10.01" {
+		t.Errorf("Expected index 'logs-2023.10.01', got '%s'", index)
+	}
+}
+
+func Test_Logger_DataStreamECS(t *testing.T) {
+	logger, transport := getTestLogger(t, WithDataStream(testIndex), func(l *Logger) {
+		l.DocumentBuilder = NewECSDocumentBuilder(ECSService{Name: "billing", Version: "1.2.0", Environment: "production"})
+	})
+	transport.ActionLine = fmt.Sprintf("{\"create\":{\"_index\":\"%v\"}}", testIndex)
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x01, 0x02, 0x03},
+		SpanID:  trace.SpanID{0x04, 0x05},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+	logger.With(core.F("request.id", "abc")).Named("db").Error(ctx, "Query failed",
+		core.E(errors.New("connection refused")), core.F("attempt", 3), core.F("query", map[string]any{"table": "users"}))
+	if err := logger.Sink.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+	if len(transport.IndexRequests) != 1 {
+		t.Fatalf("Expected 1 indexed log, got %d", len(transport.IndexRequests))
+	}
+	document, err := json.Marshal(transport.IndexRequests[0])
+	if err != nil {
+		t.Fatalf("Failed to marshal document: %v", err)
+	}
+	expected := `{"@timestamp":"2023-10-01T12:00:00Z",` +
+		`"ecs":{"version":"` + ECSVersion + `"},` +
+		`"error":{"message":"connection refused","type":"*errors.errorString"},` +
+		`"labels":{"attempt":"3","query":"{\"table\":\"users\"}","request_id":"abc"},` +
+		`"log":{"level":"error","logger":"db"},` +
+		`"message":"Query failed",` +
+		`"service":{"environment":"production","name":"billing","version":"1.2.0"},` +
+		`"span":{"id":"` + spanContext.SpanID().String() + `"},` +
+		`"trace":{"id":"` + spanContext.TraceID().String() + `"}}`
+	if string(document) != expected {
+		t.Errorf("Expected document\n%s\ngot\n%s", expected, document)
+	}
+}
+
+func Test_Logger_FlushClose(t *testing.T) {
+	logger, transport := getTestLogger(t)
+	for i := 1; i <= 2; i++ {
+		logger.Info(context.Background(), fmt.Sprintf("message %d", i))
+		if err := logger.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush %d failed: %v", i, err)
+		}
+		if len(transport.IndexRequests) != i {
+			t.Fatalf("Expected %d indexed logs after flush %d, got %d", i, i, len(transport.IndexRequests))
+		}
+	}
+	if stats := logger.Sink.Stats(); stats.NumAdded != 2 || stats.NumFlushed != 2 {
+		t.Errorf("Expected the stats of both flushes, got %+v", stats)
+	}
+
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := logger.Sink.Add(context.Background(), esutil.BulkIndexerItem{}); !errors.Is(err, ErrSinkClosed) {
+		t.Errorf("Expected ErrSinkClosed after close, got %v", err)
+	}
+	if err := logger.Flush(context.Background()); !errors.Is(err, ErrSinkClosed) {
+		t.Errorf("Expected ErrSinkClosed when flushing after close, got %v", err)
+	}
+}
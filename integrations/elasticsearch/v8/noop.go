@@ -29,6 +29,9 @@ func (l *noopLogger) With(_ ...core.Field) core.Interface {
 	return l
 }
 
+func (l *noopLogger) Trace(_ context.Context, _ string, _ ...core.Field) {
+}
+
 func (l *noopLogger) Debug(_ context.Context, _ string, _ ...core.Field) {
 }
 
@@ -41,6 +44,12 @@ func (l *noopLogger) Warning(_ context.Context, _ string, _ ...core.Field) {
 func (l *noopLogger) Error(_ context.Context, _ string, _ ...core.Field) {
 }
 
+func (l *noopLogger) Fatal(_ context.Context, _ string, _ ...core.Field) {
+}
+
+func (l *noopLogger) Panic(_ context.Context, _ string, _ ...core.Field) {
+}
+
 func (l *noopLogger) Flush(_ context.Context) error {
 	return nil
 }
@@ -0,0 +1,257 @@
+package elastic
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const segmentExt = ".seg"
+
+// recordHeaderSize is the size of the length and CRC-32 preceding every record.
+const recordHeaderSize = 8
+
+// maxRecordSize bounds the allocation of a record read from a corrupted length.
+const maxRecordSize = 64 << 20
+
+// ErrSpoolFull is returned when a record does not fit in the disk budget of the spool.
+var ErrSpoolFull = errors.New("spool is full")
+
+// segment is a file of the queue. Records are appended to the last segment only.
+type segment struct {
+	id      uint64
+	path    string
+	size    int64
+	records int
+}
+
+// diskQueue is a write-ahead queue of records stored in numbered segment files of dir. Records are
+// appended to the last segment until it exceeds segmentSize, and read a whole segment at a time
+// from the first one, which is removed once its records are delivered.
+//
+// Every record is prefixed with its length and CRC-32, so a record torn by a crash is detected and
+// truncated when the queue is opened again.
+type diskQueue struct {
+	dir         string
+	segmentSize int64
+	maxBytes    int64
+
+	mu       sync.Mutex
+	segments []*segment
+	active   *os.File
+	bytes    int64
+	records  int
+}
+
+func openDiskQueue(dir string, segmentSize, maxBytes int64) (*diskQueue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	queue := &diskQueue{dir: dir, segmentSize: segmentSize, maxBytes: maxBytes}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seg := &segment{id: id, path: filepath.Join(dir, name)}
+		if err = seg.recover(); err != nil {
+			return nil, fmt.Errorf("failed to recover segment %s: %w", seg.path, err)
+		}
+		if seg.records == 0 {
+			_ = os.Remove(seg.path)
+			continue
+		}
+		queue.segments = append(queue.segments, seg)
+		queue.bytes += seg.size
+		queue.records += seg.records
+	}
+	sort.Slice(queue.segments, func(i, j int) bool {
+		return queue.segments[i].id < queue.segments[j].id
+	})
+	return queue, nil
+}
+
+// recover counts the records of the segment and truncates a torn record at its end.
+func (s *segment) recover() error {
+	file, err := os.OpenFile(s.path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	reader := bufio.NewReader(file)
+	for {
+		n, err := readRecord(reader, nil)
+		if err != nil {
+			break
+		}
+		s.size += n
+		s.records++
+	}
+	return file.Truncate(s.size)
+}
+
+// append writes a record to the last segment, starting a new one when it is full or sealed.
+func (q *diskQueue) append(record []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	size := int64(recordHeaderSize + len(record))
+	if q.maxBytes > 0 && q.bytes+size > q.maxBytes {
+		return ErrSpoolFull
+	}
+	if q.active != nil && q.segments[len(q.segments)-1].size >= q.segmentSize {
+		if err := q.sealLocked(); err != nil {
+			return err
+		}
+	}
+	if q.active == nil {
+		var id uint64 = 1
+		if len(q.segments) > 0 {
+			id = q.segments[len(q.segments)-1].id + 1
+		}
+		seg := &segment{id: id, path: filepath.Join(q.dir, fmt.Sprintf("%020d%s", id, segmentExt))}
+		file, err := os.OpenFile(seg.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return err
+		}
+		q.active = file
+		q.segments = append(q.segments, seg)
+	}
+	buffer := make([]byte, size)
+	binary.BigEndian.PutUint32(buffer[0:4], uint32(len(record)))
+	binary.BigEndian.PutUint32(buffer[4:8], crc32.ChecksumIEEE(record))
+	copy(buffer[recordHeaderSize:], record)
+	if _, err := q.active.Write(buffer); err != nil {
+		return err
+	}
+	seg := q.segments[len(q.segments)-1]
+	seg.size += size
+	seg.records++
+	q.bytes += size
+	q.records++
+	return nil
+}
+
+// sealLocked syncs and closes the last segment, so it can be read.
+func (q *diskQueue) sealLocked() error {
+	if q.active == nil {
+		return nil
+	}
+	file := q.active
+	q.active = nil
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// head returns the first segment, sealing it when records are appended to it.
+func (q *diskQueue) head() (*segment, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.segments) == 0 {
+		return nil, nil
+	}
+	if len(q.segments) == 1 && q.active != nil {
+		if err := q.sealLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return q.segments[0], nil
+}
+
+// remove deletes the first segment once its records are delivered.
+func (q *diskQueue) remove(seg *segment) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.segments) == 0 || q.segments[0] != seg {
+		return fmt.Errorf("segment %s is not the head of the queue", seg.path)
+	}
+	q.segments = q.segments[1:]
+	q.bytes -= seg.size
+	q.records -= seg.records
+	return os.Remove(seg.path)
+}
+
+// len returns the number of records in the queue.
+func (q *diskQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.records
+}
+
+func (q *diskQueue) size() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.bytes
+}
+
+func (q *diskQueue) close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.sealLocked()
+}
+
+// read returns the records of a sealed segment.
+func (s *segment) read() ([][]byte, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	reader := bufio.NewReader(file)
+	records := make([][]byte, 0, s.records)
+	for len(records) < s.records {
+		var record []byte
+		if _, err = readRecord(reader, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readRecord reads and verifies a record, storing it in record when not nil, and returns its size
+// on disk.
+func readRecord(reader io.Reader, record *[]byte) (int64, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return 0, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > maxRecordSize {
+		return 0, errors.New("record length exceeds the maximum")
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return 0, errors.New("record checksum mismatch")
+	}
+	if record != nil {
+		*record = payload
+	}
+	return int64(recordHeaderSize + len(payload)), nil
+}
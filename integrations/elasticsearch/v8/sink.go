@@ -1,10 +1,13 @@
 package elastic
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"runtime"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8/esutil"
@@ -29,6 +32,15 @@ func ReplaceGlobalSink(indexer esutil.BulkIndexer) {
 	globalSink = indexer
 }
 
+// ErrSinkClosed is returned, or given as the failure of the documents, added after a sink is closed.
+var ErrSinkClosed = errors.New("sink is closed")
+
+// Flusher is implemented by the sinks that can deliver their buffered documents without closing.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// NewSink returns an Indexer with the configuration of the ELASTICSEARCH_SINK_* variables.
 func NewSink(options ...SinkOption) (esutil.BulkIndexer, error) {
 	cfg := &esutil.BulkIndexerConfig{
 		Client:        globalClient,
@@ -39,7 +51,95 @@ func NewSink(options ...SinkOption) (esutil.BulkIndexer, error) {
 	for _, opt := range options {
 		opt(cfg)
 	}
-	return esutil.NewBulkIndexer(*cfg)
+	return NewIndexer(*cfg)
+}
+
+// Indexer is a bulk indexer that can be flushed without being closed. esutil.BulkIndexer only
+// delivers its buffered documents on close, so Flush closes the current indexer and starts a new
+// one with the same configuration, the documents added meanwhile go to the new one.
+type Indexer struct {
+	config esutil.BulkIndexerConfig
+
+	// mu is held for reading while adding to indexer and for writing while swapping it.
+	mu      sync.RWMutex
+	indexer esutil.BulkIndexer
+	closed  bool
+	// flushed holds the stats of the indexers closed by Flush.
+	flushed esutil.BulkIndexerStats
+}
+
+func NewIndexer(config esutil.BulkIndexerConfig) (*Indexer, error) {
+	indexer, err := esutil.NewBulkIndexer(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Indexer{config: config, indexer: indexer}, nil
+}
+
+func (i *Indexer) Add(ctx context.Context, item esutil.BulkIndexerItem) error {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if i.closed {
+		return ErrSinkClosed
+	}
+	return i.indexer.Add(ctx, item)
+}
+
+// Flush delivers the documents added before the call and waits for their results.
+func (i *Indexer) Flush(ctx context.Context) error {
+	next, err := esutil.NewBulkIndexer(i.config)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	if i.closed {
+		i.mu.Unlock()
+		_ = next.Close(ctx)
+		return ErrSinkClosed
+	}
+	previous := i.indexer
+	i.indexer = next
+	i.mu.Unlock()
+
+	err = previous.Close(ctx)
+	i.mu.Lock()
+	i.flushed = addStats(i.flushed, previous.Stats())
+	i.mu.Unlock()
+	return err
+}
+
+// Close delivers the buffered documents and stops the indexer, later documents are rejected with
+// ErrSinkClosed.
+func (i *Indexer) Close(ctx context.Context) error {
+	i.mu.Lock()
+	if i.closed {
+		i.mu.Unlock()
+		return nil
+	}
+	i.closed = true
+	i.mu.Unlock()
+	return i.indexer.Close(ctx)
+}
+
+// Stats returns the stats of the documents added since the indexer was created.
+func (i *Indexer) Stats() esutil.BulkIndexerStats {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return addStats(i.flushed, i.indexer.Stats())
+}
+
+func addStats(a, b esutil.BulkIndexerStats) esutil.BulkIndexerStats {
+	return esutil.BulkIndexerStats{
+		NumAdded:     a.NumAdded + b.NumAdded,
+		NumFlushed:   a.NumFlushed + b.NumFlushed,
+		NumFailed:    a.NumFailed + b.NumFailed,
+		NumIndexed:   a.NumIndexed + b.NumIndexed,
+		NumCreated:   a.NumCreated + b.NumCreated,
+		NumUpdated:   a.NumUpdated + b.NumUpdated,
+		NumDeleted:   a.NumDeleted + b.NumDeleted,
+		NumRequests:  a.NumRequests + b.NumRequests,
+		FlushedBytes: a.FlushedBytes + b.FlushedBytes,
+	}
 }
 
 func init() {
@@ -0,0 +1,440 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+const (
+	DefaultSpoolMaxBytes       = int64(1 << 30) // 1 GB
+	DefaultSpoolSegmentSize    = int64(8 << 20) // 8 MB
+	DefaultSpoolReplayInterval = 5 * time.Second
+	DefaultSpoolBatchSize      = 500
+)
+
+type SpoolOption func(s *Spool)
+
+// SpoolCounters counts the documents of a Spool. Pending and Bytes describe the documents waiting
+// on disk.
+type SpoolCounters struct {
+	Spooled  uint64
+	Replayed uint64
+	Dropped  uint64
+	Pending  int
+	Bytes    int64
+}
+
+// Spool is a bulk indexer spilling the documents Sink fails to deliver to a segment-based
+// write-ahead queue in Dir while the cluster is unreachable, and replaying them in order with the
+// Bulk API of Client once it is reachable again.
+//
+// Documents failing with a transport error, a 429 or a 5xx status are spooled, as are the documents
+// added while the spool is not empty, so they are delivered after the spooled ones. The spool keeps
+// at most MaxBytes on disk, documents beyond it are reported to the OnFailure callback of their
+// item with ErrSpoolFull. Spooled documents lose their callbacks, documents rejected on replay are
+// reported to OnFailure. Failed replay requests, such as the ones failing authentication, keep the
+// documents spooled until the next replay, see ReplayInterval.
+//
+// The spool survives restarts: documents left in Dir are replayed by the next Spool opened on it.
+// Delivery is at least once, documents of a segment interrupted by a crash are sent again. The
+// segments are only synced to disk when they are sealed, so the spooled documents survive a crash
+// of the process but the ones of the open segment may be lost when the host crashes. Records that
+// cannot be decoded on replay are skipped and counted as Dropped.
+type Spool struct {
+	Sink           esutil.BulkIndexer
+	Client         *elasticsearch.Client
+	Dir            string
+	MaxBytes       int64
+	SegmentSize    int64
+	ReplayInterval time.Duration
+	BatchSize      int
+	OnFailure      func(ctx context.Context, item esutil.BulkIndexerItem, resp esutil.BulkIndexerResponseItem, err error)
+
+	queue *diskQueue
+
+	spooled  atomic.Uint64
+	replayed atomic.Uint64
+	dropped  atomic.Uint64
+
+	// mu is held for reading while adding to Sink and for writing while marking it closed.
+	mu     sync.RWMutex
+	closed bool
+
+	// replayMu serializes the replays, which resume from the head records left by the previous one.
+	replayMu sync.Mutex
+	head     *segment
+	records  [][]byte
+	position int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// spoolRecord is a spooled document.
+type spoolRecord struct {
+	Action     string          `json:"action"`
+	Index      string          `json:"index"`
+	DocumentID string          `json:"id,omitempty"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// NewSpool opens the spool in dir, creating the directory when missing, and starts replaying the
+// documents left in it.
+func NewSpool(dir string, sink esutil.BulkIndexer, opts ...SpoolOption) (*Spool, error) {
+	spool := &Spool{
+		Sink:           sink,
+		Client:         globalClient,
+		Dir:            dir,
+		MaxBytes:       DefaultSpoolMaxBytes,
+		SegmentSize:    DefaultSpoolSegmentSize,
+		ReplayInterval: DefaultSpoolReplayInterval,
+		BatchSize:      DefaultSpoolBatchSize,
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(spool)
+	}
+	if spool.Client == nil {
+		return nil, fmt.Errorf("elasticsearch client is not initialized")
+	}
+	if spool.ReplayInterval <= 0 {
+		spool.ReplayInterval = DefaultSpoolReplayInterval
+	}
+	if spool.BatchSize <= 0 {
+		spool.BatchSize = DefaultSpoolBatchSize
+	}
+	queue, err := openDiskQueue(spool.Dir, spool.SegmentSize, spool.MaxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool %s: %w", spool.Dir, err)
+	}
+	spool.queue = queue
+	go spool.run()
+	return spool, nil
+}
+
+// WithSpool wraps the Sink of the logger in a Spool. Use it after setting the Sink, and before
+// WithDeadLetter so the dead letter only sees the documents the spool does not take.
+func WithSpool(dir string, opts ...SpoolOption) Option {
+	return func(l *Logger) {
+		spool, err := NewSpool(dir, l.Sink, opts...)
+		if err != nil {
+			panic(err)
+		}
+		l.Sink = spool
+	}
+}
+
+func WithSpoolClient(client *elasticsearch.Client) SpoolOption {
+	return func(s *Spool) {
+		s.Client = client
+	}
+}
+
+// WithSpoolMaxBytes sets the disk budget of the spool, 0 disables it.
+func WithSpoolMaxBytes(maxBytes int64) SpoolOption {
+	return func(s *Spool) {
+		s.MaxBytes = maxBytes
+	}
+}
+
+func WithReplayInterval(interval time.Duration) SpoolOption {
+	return func(s *Spool) {
+		s.ReplayInterval = interval
+	}
+}
+
+// WithSpoolOnFailure reports the documents rejected on replay to onFailure, such as
+// DeadLetter.Reject.
+func WithSpoolOnFailure(onFailure func(ctx context.Context, item esutil.BulkIndexerItem, resp esutil.BulkIndexerResponseItem, err error)) SpoolOption {
+	return func(s *Spool) {
+		s.OnFailure = onFailure
+	}
+}
+
+func (s *Spool) Add(ctx context.Context, item esutil.BulkIndexerItem) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed || s.queue.len() > 0 {
+		if err := s.spool(item); err != nil && item.OnFailure != nil {
+			item.OnFailure(ctx, item, esutil.BulkIndexerResponseItem{}, err)
+		}
+		return nil
+	}
+	return s.Sink.Add(ctx, s.wrap(item))
+}
+
+// Close stops the replays and closes Sink, spooling the documents it fails to deliver, then makes
+// a last replay. The documents left, and the documents added after Close, are replayed by the next
+// Spool opened on Dir.
+func (s *Spool) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	<-s.done
+	err := s.Sink.Close(ctx)
+	if s.queue.len() > 0 {
+		if replayErr := s.Replay(ctx); replayErr != nil && !isUnreachable(replayErr) {
+			err = errors.Join(err, replayErr)
+		}
+	}
+	return errors.Join(err, s.queue.close())
+}
+
+// Flush flushes Sink when it implements Flusher, spooling the documents it fails to deliver, then
+// replays the spool. Documents left in the spool because the cluster is unreachable are not an error.
+func (s *Spool) Flush(ctx context.Context) error {
+	var err error
+	if flusher, ok := s.Sink.(Flusher); ok {
+		err = flusher.Flush(ctx)
+	}
+	if s.queue.len() > 0 {
+		if replayErr := s.Replay(ctx); replayErr != nil && !isUnreachable(replayErr) {
+			err = errors.Join(err, replayErr)
+		}
+	}
+	return err
+}
+
+func (s *Spool) Stats() esutil.BulkIndexerStats {
+	return s.Sink.Stats()
+}
+
+func (s *Spool) Counters() SpoolCounters {
+	return SpoolCounters{
+		Spooled:  s.spooled.Load(),
+		Replayed: s.replayed.Load(),
+		Dropped:  s.dropped.Load(),
+		Pending:  s.queue.len(),
+		Bytes:    s.queue.size(),
+	}
+}
+
+// wrap returns a copy of item spooling it when the cluster cannot be reached.
+func (s *Spool) wrap(item esutil.BulkIndexerItem) esutil.BulkIndexerItem {
+	wrapped := item
+	wrapped.OnFailure = func(ctx context.Context, _ esutil.BulkIndexerItem, resp esutil.BulkIndexerResponseItem, err error) {
+		if retryable(resp, err) {
+			if err = s.spool(item); err == nil {
+				return
+			}
+		}
+		if item.OnFailure != nil {
+			item.OnFailure(ctx, item, resp, err)
+		}
+	}
+	return wrapped
+}
+
+func (s *Spool) spool(item esutil.BulkIndexerItem) error {
+	if _, err := item.Body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	body, err := io.ReadAll(item.Body)
+	if err != nil {
+		return err
+	}
+	record, err := json.Marshal(spoolRecord{
+		Action:     item.Action,
+		Index:      item.Index,
+		DocumentID: item.DocumentID,
+		Body:       body,
+	})
+	if err != nil {
+		return err
+	}
+	if err = s.queue.append(record); err != nil {
+		s.dropped.Add(1)
+		return err
+	}
+	s.spooled.Add(1)
+	return nil
+}
+
+func (s *Spool) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.ReplayInterval)
+	defer ticker.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if s.queue.len() > 0 {
+				_ = s.Replay(ctx)
+			}
+		}
+	}
+}
+
+// Replay sends the spooled documents in order until the spool is empty, or stops at the first
+// batch the cluster cannot take and resumes from it on the next call.
+func (s *Spool) Replay(ctx context.Context) error {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+	for {
+		if s.head == nil {
+			head, err := s.queue.head()
+			if err != nil || head == nil {
+				return err
+			}
+			if s.records, err = head.read(); err != nil {
+				return fmt.Errorf("failed to read spool segment %s: %w", head.path, err)
+			}
+			s.head, s.position = head, 0
+		}
+		for s.position < len(s.records) {
+			end := min(s.position+s.BatchSize, len(s.records))
+			if err := s.send(ctx, s.records[s.position:end]); err != nil {
+				return err
+			}
+			s.position = end
+		}
+		if err := s.queue.remove(s.head); err != nil {
+			return err
+		}
+		s.head, s.records = nil, nil
+	}
+}
+
+// unreachableError reports a batch the cluster could not take, because it was unreachable, throttled
+// the batch or failed the request, it is sent again on the next replay.
+type unreachableError struct {
+	err error
+}
+
+func (e *unreachableError) Error() string {
+	return fmt.Sprintf("cluster unreachable: %v", e.err)
+}
+
+func (e *unreachableError) Unwrap() error {
+	return e.err
+}
+
+func isUnreachable(err error) bool {
+	var unreachable *unreachableError
+	return errors.As(err, &unreachable)
+}
+
+// send indexes a batch of records with the Bulk API, see bulk. Records that cannot be decoded are
+// skipped and counted as dropped.
+func (s *Spool) send(ctx context.Context, batch [][]byte) error {
+	records := make([]spoolRecord, 0, len(batch))
+	for _, data := range batch {
+		var record spoolRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			// A record written by this package is valid JSON, the spool was tampered with.
+			s.dropped.Add(1)
+			continue
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return s.bulk(ctx, records)
+}
+
+// bulk indexes records with the Bulk API. Records rejected by the cluster are reported to OnFailure.
+// Throttled or failed records and failed requests, such as the ones failing authentication, make
+// the whole batch be sent again, which may duplicate the documents indexed by this attempt. A batch
+// too large for the cluster is sent in halves, a single record too large is reported to OnFailure.
+func (s *Spool) bulk(ctx context.Context, records []spoolRecord) error {
+	items := make([]esutil.BulkIndexerItem, 0, len(records))
+	body := &bytes.Buffer{}
+	for _, record := range records {
+		meta := map[string]any{"_index": record.Index}
+		if record.DocumentID != "" {
+			meta["_id"] = record.DocumentID
+		}
+		line, err := json.Marshal(map[string]any{record.Action: meta})
+		if err != nil {
+			return err
+		}
+		body.Write(line)
+		body.WriteByte('\n')
+		body.Write(record.Body)
+		body.WriteByte('\n')
+		items = append(items, esutil.BulkIndexerItem{
+			Action:     record.Action,
+			Index:      record.Index,
+			DocumentID: record.DocumentID,
+			Body:       bytes.NewReader(record.Body),
+		})
+	}
+
+	res, err := s.Client.Bulk(body, s.Client.Bulk.WithContext(ctx))
+	if err != nil {
+		return &unreachableError{err: err}
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	switch {
+	case res.StatusCode == http.StatusRequestEntityTooLarge && len(records) > 1:
+		half := len(records) / 2
+		if err = s.bulk(ctx, records[:half]); err != nil {
+			return err
+		}
+		return s.bulk(ctx, records[half:])
+	case res.StatusCode == http.StatusRequestEntityTooLarge:
+		err = fmt.Errorf("unexpected response %s", res.Status())
+		s.reject(ctx, items[0], esutil.BulkIndexerResponseItem{Status: res.StatusCode}, err)
+		return nil
+	case res.IsError():
+		return &unreachableError{err: fmt.Errorf("unexpected response %s", res.Status())}
+	}
+
+	var response esutil.BulkIndexerResponse
+	if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return &unreachableError{err: fmt.Errorf("failed to decode response: %w", err)}
+	}
+	for _, result := range response.Items {
+		for _, info := range result {
+			if info.Status == http.StatusTooManyRequests || info.Status >= http.StatusInternalServerError {
+				return &unreachableError{err: fmt.Errorf("%s: %s", info.Error.Type, info.Error.Reason)}
+			}
+		}
+	}
+	for i, result := range response.Items {
+		if i >= len(items) {
+			break
+		}
+		for _, info := range result {
+			if info.Error.Type != "" || info.Status > http.StatusCreated {
+				s.reject(ctx, items[i], info, nil)
+			}
+		}
+	}
+	s.replayed.Add(uint64(len(items)))
+	return nil
+}
+
+func (s *Spool) reject(ctx context.Context, item esutil.BulkIndexerItem, resp esutil.BulkIndexerResponseItem, err error) {
+	if s.OnFailure != nil {
+		s.OnFailure(ctx, item, resp, err)
+	}
+}
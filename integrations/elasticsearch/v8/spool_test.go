@@ -0,0 +1,328 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+// mockBulk answers bulk requests while up, recording the documents, and fails them while down.
+// Requests fail with requestStatus when set, and with a 413 when they have more than maxDocuments.
+type mockBulk struct {
+	T             *testing.T
+	lock          sync.Mutex
+	down          bool
+	status        map[string]int
+	requestStatus int
+	maxDocuments  int
+	documents     []string
+}
+
+func (b *mockBulk) setRequestStatus(status int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.requestStatus = status
+}
+
+func (b *mockBulk) setDown(down bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.down = down
+}
+
+func (b *mockBulk) RoundTrip(req *http.Request) (*http.Response, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.down {
+		return nil, errors.New("connection refused")
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		b.T.Errorf("Failed to read request body: %v", err)
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if b.requestStatus != 0 {
+		return buildHTTPResponse(b.T, b.requestStatus, map[string]any{"error": "request failed"}), nil
+	}
+	if b.maxDocuments > 0 && len(lines)/2 > b.maxDocuments {
+		return buildHTTPResponse(b.T, http.StatusRequestEntityTooLarge, map[string]any{"error": "too large"}), nil
+	}
+	items := make([]map[string]any, 0, len(lines)/2)
+	for i := 1; i < len(lines); i += 2 {
+		var document struct {
+			Message string `json:"message"`
+		}
+		if err = json.Unmarshal([]byte(lines[i]), &document); err != nil {
+			b.T.Errorf("Failed to unmarshal document %s: %v", lines[i], err)
+		}
+		status := http.StatusCreated
+		if code, ok := b.status[document.Message]; ok {
+			status = code
+		} else {
+			b.documents = append(b.documents, document.Message)
+		}
+		items = append(items, map[string]any{"index": map[string]any{"status": status}})
+	}
+	return buildHTTPResponse(b.T, http.StatusOK, map[string]any{"errors": false, "items": items}), nil
+}
+
+func (b *mockBulk) delivered() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return append([]string{}, b.documents...)
+}
+
+func newTestSpool(t *testing.T, dir string, transport http.RoundTripper, opts ...SpoolOption) (*Spool, *mockSink) {
+	sink := newMockSink()
+	client := getTestClient(t, transport, func(cfg *elasticsearch.Config) {
+		cfg.DisableRetry = true
+	})
+	opts = append([]SpoolOption{WithSpoolClient(client), WithReplayInterval(time.Hour)}, opts...)
+	spool, err := NewSpool(dir, sink, opts...)
+	if err != nil {
+		t.Fatalf("NewSpool failed: %v", err)
+	}
+	return spool, sink
+}
+
+func spoolItem(message string) esutil.BulkIndexerItem {
+	return esutil.BulkIndexerItem{
+		Action: ActionIndex,
+		Index:  testIndex,
+		Body:   strings.NewReader(`{"message":"` + message + `"}`),
+	}
+}
+
+func Test_Spool_Replay(t *testing.T) {
+	bulk := &mockBulk{T: t, down: true, status: map[string]int{"conflict": http.StatusBadRequest}}
+	spool, sink := newTestSpool(t, t.TempDir(), bulk)
+	var rejected []string
+	spool.OnFailure = func(_ context.Context, item esutil.BulkIndexerItem, resp esutil.BulkIndexerResponseItem, _ error) {
+		body, _ := io.ReadAll(item.Body)
+		rejected = append(rejected, string(body))
+	}
+
+	if err := spool.Add(context.Background(), spoolItem("first")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	item := sink.last(t)
+	item.OnFailure(context.Background(), item, esutil.BulkIndexerResponseItem{}, errors.New("connection refused"))
+	for _, message := range []string{"second", "conflict", "third"} {
+		if err := spool.Add(context.Background(), spoolItem(message)); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if len(sink.items) != 1 {
+		t.Errorf("Expected the documents added while spooling to skip the sink, got %d items", len(sink.items))
+	}
+
+	if err := spool.Replay(context.Background()); !isUnreachable(err) {
+		t.Fatalf("Expected an unreachable cluster, got %v", err)
+	}
+	if counters := spool.Counters(); counters.Spooled != 4 || counters.Pending != 4 || counters.Replayed != 0 {
+		t.Errorf("Unexpected counters: %+v", counters)
+	}
+
+	bulk.setDown(false)
+	if err := spool.Replay(context.Background()); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if delivered := bulk.delivered(); strings.Join(delivered, ",") != "first,second,third" {
+		t.Errorf("Expected the documents in order, got %v", delivered)
+	}
+	if len(rejected) != 1 || rejected[0] != `{"message":"conflict"}` {
+		t.Errorf("Expected the conflicting document to be rejected, got %v", rejected)
+	}
+	if counters := spool.Counters(); counters.Pending != 0 || counters.Bytes != 0 || counters.Replayed != 4 {
+		t.Errorf("Unexpected counters: %+v", counters)
+	}
+
+	if err := spool.Add(context.Background(), spoolItem("direct")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if len(sink.items) != 2 {
+		t.Errorf("Expected the sink to be used again once the spool is empty, got %d items", len(sink.items))
+	}
+	if err := spool.Close(context.Background()); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func Test_Spool_RequestFailure(t *testing.T) {
+	bulk := &mockBulk{T: t, down: true, maxDocuments: 1}
+	spool, sink := newTestSpool(t, t.TempDir(), bulk)
+	defer func() {
+		_ = spool.Close(context.Background())
+	}()
+	var rejected int
+	spool.OnFailure = func(context.Context, esutil.BulkIndexerItem, esutil.BulkIndexerResponseItem, error) {
+		rejected++
+	}
+	if err := spool.Add(context.Background(), spoolItem("first")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	item := sink.last(t)
+	item.OnFailure(context.Background(), item, esutil.BulkIndexerResponseItem{}, errors.New("connection refused"))
+	for _, message := range []string{"second", "third"} {
+		if err := spool.Add(context.Background(), spoolItem(message)); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	bulk.setDown(false)
+
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout} {
+		bulk.setRequestStatus(status)
+		if err := spool.Replay(context.Background()); !isUnreachable(err) {
+			t.Fatalf("Expected the %d request to keep the batch spooled, got %v", status, err)
+		}
+		if counters := spool.Counters(); rejected != 0 || counters.Pending != 3 {
+			t.Fatalf("Expected the %d request to reject nothing, got %d rejections and %+v", status, rejected, counters)
+		}
+	}
+
+	bulk.setRequestStatus(0)
+	if err := spool.Replay(context.Background()); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if delivered := bulk.delivered(); rejected != 0 || strings.Join(delivered, ",") != "first,second,third" {
+		t.Errorf("Expected the too large batch to be sent in halves, got %d rejections and %v", rejected, delivered)
+	}
+	if counters := spool.Counters(); counters.Pending != 0 || counters.Replayed != 3 {
+		t.Errorf("Unexpected counters: %+v", counters)
+	}
+
+	bulk.setDown(true)
+	if err := spool.Add(context.Background(), spoolItem("huge")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	item = sink.last(t)
+	item.OnFailure(context.Background(), item, esutil.BulkIndexerResponseItem{}, errors.New("connection refused"))
+	bulk.setDown(false)
+	bulk.setRequestStatus(http.StatusRequestEntityTooLarge)
+	if err := spool.Replay(context.Background()); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if counters := spool.Counters(); rejected != 1 || counters.Pending != 0 {
+		t.Errorf("Expected the single too large document to be rejected, got %d rejections and %+v", rejected, counters)
+	}
+}
+
+func Test_Spool_Restart(t *testing.T) {
+	dir := t.TempDir()
+	bulk := &mockBulk{T: t, down: true}
+	spool, _ := newTestSpool(t, dir, bulk)
+	if err := spool.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	for _, message := range []string{"first", "second"} {
+		if err := spool.Add(context.Background(), spoolItem(message)); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := spool.queue.close(); err != nil {
+		t.Fatalf("Failed to close the queue: %v", err)
+	}
+
+	// A torn record at the end of the last segment is dropped.
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if len(segments) != 1 {
+		t.Fatalf("Expected one segment, got %v", segments)
+	}
+	file, err := os.OpenFile(segments[0], os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("Failed to open segment: %v", err)
+	}
+	_, _ = file.Write([]byte{0, 0, 0, 42, 1, 2})
+	_ = file.Close()
+
+	bulk.setDown(false)
+	spool, _ = newTestSpool(t, dir, bulk)
+	if pending := spool.Counters().Pending; pending != 2 {
+		t.Fatalf("Expected 2 documents left from the previous run, got %d", pending)
+	}
+	if err = spool.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if delivered := bulk.delivered(); strings.Join(delivered, ",") != "first,second" {
+		t.Errorf("Expected the documents of the previous run to be replayed on close, got %v", delivered)
+	}
+	if segments, _ = filepath.Glob(filepath.Join(dir, "*"+segmentExt)); len(segments) != 0 {
+		t.Errorf("Expected the segments to be removed, got %v", segments)
+	}
+}
+
+func Test_Spool_MaxBytes(t *testing.T) {
+	bulk := &mockBulk{T: t, down: true}
+	spool, sink := newTestSpool(t, t.TempDir(), bulk, WithSpoolMaxBytes(100))
+	defer func() {
+		_ = spool.Close(context.Background())
+	}()
+
+	var failures []error
+	for _, message := range []string{"first", "second", "third"} {
+		item := spoolItem(message)
+		item.OnFailure = func(_ context.Context, _ esutil.BulkIndexerItem, _ esutil.BulkIndexerResponseItem, err error) {
+			failures = append(failures, err)
+		}
+		if err := spool.Add(context.Background(), item); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if message == "first" {
+			added := sink.last(t)
+			added.OnFailure(context.Background(), added, esutil.BulkIndexerResponseItem{Status: http.StatusServiceUnavailable}, nil)
+		}
+	}
+	counters := spool.Counters()
+	if counters.Spooled != 1 || counters.Dropped != 2 || counters.Bytes > 100 {
+		t.Errorf("Unexpected counters: %+v", counters)
+	}
+	if len(failures) != 2 || !errors.Is(failures[0], ErrSpoolFull) || !errors.Is(failures[1], ErrSpoolFull) {
+		t.Errorf("Expected the documents beyond the budget to fail, got %v", failures)
+	}
+}
+
+func Test_Spool_DeadLetterRejected(t *testing.T) {
+	bulk := &mockBulk{T: t, down: true, status: map[string]int{"conflict": http.StatusBadRequest}}
+	buffer := &bytes.Buffer{}
+	deadLetter := NewDeadLetter(nil, WithDeadLetterWriter(buffer))
+	spool, sink := newTestSpool(t, t.TempDir(), bulk, WithSpoolOnFailure(deadLetter.Reject))
+	defer func() {
+		_ = spool.Close(context.Background())
+	}()
+
+	if err := spool.Add(context.Background(), spoolItem("conflict")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	item := sink.last(t)
+	item.OnFailure(context.Background(), item, esutil.BulkIndexerResponseItem{}, errors.New("connection refused"))
+	if err := spool.queue.append([]byte("not a record")); err != nil {
+		t.Fatalf("Failed to append to the queue: %v", err)
+	}
+
+	bulk.setDown(false)
+	if err := spool.Replay(context.Background()); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if counters := deadLetter.Counters(); counters.DeadLettered != 1 {
+		t.Errorf("Expected the rejected document to be dead-lettered, got %+v", counters)
+	}
+	if !strings.Contains(buffer.String(), `"message":"conflict"`) {
+		t.Errorf("Expected the rejected document in the dead letter, got %q", buffer.String())
+	}
+	if counters := spool.Counters(); counters.Dropped != 1 || counters.Pending != 0 {
+		t.Errorf("Expected the undecodable record to be dropped, got %+v", counters)
+	}
+}
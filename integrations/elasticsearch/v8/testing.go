@@ -108,6 +108,62 @@ func (t *mockRoundTrip) RoundTrip(req *http.Request) (*http.Response, error) {
 	return buildIndexResponse(t.T, len(lines)-1), nil
 }
 
+// mockBulkRoundTrip records the documents of bulk requests holding several action and document
+// line pairs, mockRoundTrip only accepts a single action line.
+type mockBulkRoundTrip struct {
+	T             *testing.T
+	lock          sync.Locker
+	IndexRequests []map[string]any
+	// ActionLine is the expected action line of every document.
+	ActionLine string
+}
+
+func newMockBulkTransport(t *testing.T) *mockBulkRoundTrip {
+	return &mockBulkRoundTrip{
+		T:          t,
+		lock:       &sync.Mutex{},
+		ActionLine: fmt.Sprintf("{\"index\":{\"_index\":\"%v\"}}", testIndex),
+	}
+}
+
+func (t *mockBulkRoundTrip) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	expectedURL := testAddress + "/_bulk"
+	if req.Method != http.MethodPost || req.URL.String() != expectedURL {
+		t.T.Errorf("Expected POST request to %s, got %s %s", expectedURL, req.Method, req.URL.String())
+	}
+	if req.Body == nil {
+		t.T.Error("Expected request body, got nil")
+		return nil, fmt.Errorf("request body is nil")
+	}
+	bodyBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.T.Errorf("Failed to read request body: %v", err)
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(bodyBytes)), "\n")
+	if len(lines) < 2 || len(lines)%2 != 0 {
+		t.T.Errorf("Expected action and document line pairs in request body, got %d lines", len(lines))
+		return nil, fmt.Errorf("invalid request body, expected action and document line pairs")
+	}
+	for i := 0; i < len(lines); i += 2 {
+		if lines[i] != t.ActionLine {
+			t.T.Errorf("Unexpected action line in request body: %s", lines[i])
+			return nil, fmt.Errorf("unexpected action line in request body")
+		}
+		var doc map[string]any
+		if err = json.Unmarshal([]byte(lines[i+1]), &doc); err != nil {
+			t.T.Errorf("Failed to unmarshal line:\n%s\nError: %v", lines[i+1], err)
+			return nil, fmt.Errorf("failed to unmarshal line: %w", err)
+		}
+		t.IndexRequests = append(t.IndexRequests, doc)
+	}
+	return buildIndexResponse(t.T, len(lines)/2), nil
+}
+
 func getTestClient(t *testing.T, transport http.RoundTripper, options ...ClientOption) *elasticsearch.Client {
 	options = append([]ClientOption{func(cfg *elasticsearch.Config) {
 		cfg.Addresses = []string{testAddress}
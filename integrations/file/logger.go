@@ -1,45 +1,198 @@
 package file
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/ensarkovankaya/go-logging/core"
 	"github.com/ensarkovankaya/go-logging/integrations/console"
 )
 
+// Type is the integration type of the file logger. The file logger reported console.Type before,
+// the integrations of a batch.Logger are looked up, replaced and removed by this type instead.
+const Type = "file"
+
+var defaultLevel = core.LevelTrace
+
+var envLogLevel = "FILE_LOG_LEVEL"
+
 type Option func(*Logger)
 
+// DefaultEncoding writes JSON entries with ISO8601 times.
+var DefaultEncoding = console.Encoding{Format: console.FormatJSON, TimeFormat: "iso8601"}
+
+// Logger writes entries to the file at Path through a Writer. The options set the fields below Path,
+// the file is opened once every option is applied.
 type Logger struct {
 	*console.Logger
-	File *os.File
-	Path string
+	Writer *Writer
+	Path   string
+
+	Encoding console.Encoding
+	Rotation Rotation
+	// DirMode creates the missing parent directories of Path with the mode when not zero.
+	DirMode os.FileMode
+	// ReopenSignals reopens the file when the process receives one of the signals.
+	ReopenSignals []os.Signal
+	// CheckInterval reopens the file when a check finds it was moved or removed, zero disables it.
+	CheckInterval time.Duration
+	// Fallback receives the entries while the file is unwritable, see Writer.Fallback.
+	Fallback io.Writer
 }
 
-func New(filePath string, options ...Option) *Logger {
+// Open returns a Logger writing to the file at filePath, creating the file when it does not exist.
+func Open(filePath string, options ...Option) (*Logger, error) {
 	logger := &Logger{
-		Logger: console.New(),
-		Path:   filePath,
+		Logger:   console.New(),
+		Path:     filePath,
+		Encoding: DefaultEncoding,
 	}
-	if logger.Path != "" {
-		file, err := os.OpenFile(logger.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			panic(fmt.Errorf("failed to open log file: %w", err))
+	// The file has its own level, the one of console.New follows CONSOLE_LOG_LEVEL.
+	logger.Level = defaultLevel
+	for _, opt := range options {
+		opt(logger)
+	}
+	logger.SetLevel(logger.Level)
+	if logger.Path == "" {
+		return nil, errors.New("log file path is required")
+	}
+	encoder, err := console.NewEncoder(logger.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log file encoding: %w", err)
+	}
+	if logger.DirMode != 0 {
+		if err = os.MkdirAll(filepath.Dir(logger.Path), logger.DirMode); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %w", err)
 		}
-		logger.File = file
 	}
+	writer, err := NewWriter(logger.Path, logger.Rotation)
+	if err != nil {
+		return nil, err
+	}
+	writer.Fallback = logger.Fallback
+	writer.ReopenOn(logger.ReopenSignals...)
+	writer.CheckEvery(logger.CheckInterval)
+	logger.Writer = writer
+	logger.Transport = zap.New(zapcore.NewCore(encoder, writer, console.TraceLevel))
+	return logger, nil
+}
 
-	cfg := zap.NewProductionEncoderConfig()
-	cfg.EncodeTime = zapcore.ISO8601TimeEncoder
-	encoder := zapcore.NewJSONEncoder(cfg)
-	writer := zapcore.AddSync(logger.File)
-	core := zapcore.NewCore(encoder, writer, zapcore.DebugLevel)
-	logger.Transport = zap.New(core)
-
-	for _, opt := range options {
-		opt(logger)
+// New is like Open but panics when the file cannot be opened.
+func New(filePath string, options ...Option) *Logger {
+	logger, err := Open(filePath, options...)
+	if err != nil {
+		panic(err)
 	}
 	return logger
 }
+
+// WithEncoding writes the entries in the encoding instead of DefaultEncoding.
+func WithEncoding(e console.Encoding) Option {
+	return func(l *Logger) {
+		l.Encoding = e
+	}
+}
+
+// WithLevel writes the entries at level and above instead of the FILE_LOG_LEVEL level.
+func WithLevel(level core.Level) Option {
+	return func(l *Logger) {
+		l.Level = level
+	}
+}
+
+// WithRotation rotates the file by size, at interval boundaries or both, see Rotation.
+func WithRotation(rotation Rotation) Option {
+	return func(l *Logger) {
+		l.Rotation = rotation
+	}
+}
+
+// WithReopenSignals reopens the file when the process receives one of signals, ReopenSignals when
+// none are given, so the file can be moved by an external rotation tool.
+func WithReopenSignals(signals ...os.Signal) Option {
+	return func(l *Logger) {
+		if len(signals) == 0 {
+			signals = ReopenSignals
+		}
+		l.ReopenSignals = signals
+	}
+}
+
+// WithCheckInterval checks every interval whether the file was moved or removed and reopens it.
+func WithCheckInterval(interval time.Duration) Option {
+	return func(l *Logger) {
+		l.CheckInterval = interval
+	}
+}
+
+// WithCreateDirs creates the missing parent directories of the file with mode.
+func WithCreateDirs(mode os.FileMode) Option {
+	return func(l *Logger) {
+		l.DirMode = mode
+	}
+}
+
+// WithFallback writes the entries to fallback, typically os.Stderr, while the file is unwritable
+// instead of dropping them. Health reports the failure meanwhile.
+func WithFallback(fallback io.Writer) Option {
+	return func(l *Logger) {
+		l.Fallback = fallback
+	}
+}
+
+// Reopen reopens the file at Path, see Writer.Reopen.
+func (l *Logger) Reopen() error {
+	return l.Writer.Reopen()
+}
+
+// Health returns the error that made the logger write to its fallback, nil while it writes to the file.
+func (l *Logger) Health() error {
+	return l.Writer.Health()
+}
+
+// Close flushes the entries and closes the file, stopping the reopen signal and check goroutines.
+func (l *Logger) Close(ctx context.Context) error {
+	err := l.Flush(ctx)
+	return errors.Join(err, l.Writer.Close())
+}
+
+func (l *Logger) Type() string {
+	return Type
+}
+
+func (l *Logger) Named(name string) core.Interface {
+	return l.wrap(l.Logger.Named(name))
+}
+
+func (l *Logger) With(fields ...core.Field) core.Interface {
+	return l.wrap(l.Logger.With(fields...))
+}
+
+func (l *Logger) Clone() core.Interface {
+	return l.wrap(l.Logger.Clone())
+}
+
+// wrap keeps the file logger type on loggers derived from the embedded console logger.
+func (l *Logger) wrap(logger core.Interface) *Logger {
+	_l := *l
+	_l.Logger = logger.(*console.Logger)
+	return &_l
+}
+
+func init() {
+	if os.Getenv(envLogLevel) != "" {
+		if level, err := core.ParseLevel(os.Getenv(envLogLevel)); err == nil {
+			defaultLevel = level
+		} else {
+			_, _ = fmt.Fprintf(os.Stderr, "Invalid %s environment value, using default: %s\n", envLogLevel, defaultLevel.String())
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package file
+
+import "os"
+
+// ReopenSignals are the signals WithReopenSignals listens for when called without signals. There
+// are none on platforms without SIGHUP and SIGUSR1.
+var ReopenSignals []os.Signal
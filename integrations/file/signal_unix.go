@@ -0,0 +1,11 @@
+//go:build unix
+
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// ReopenSignals are the signals WithReopenSignals listens for when called without signals.
+var ReopenSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR1}
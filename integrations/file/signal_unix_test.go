@@ -0,0 +1,47 @@
+//go:build unix
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestLogger_WithReopenSignals(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	logger := New(path, WithReopenSignals(syscall.SIGUSR1))
+	defer func() {
+		_ = logger.Writer.Close()
+	}()
+	stat := func() os.FileInfo {
+		t.Helper()
+		logger.Writer.mu.Lock()
+		defer logger.Writer.mu.Unlock()
+		info, err := logger.Writer.file.Stat()
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		return info
+	}
+	previous := stat()
+	if err := os.Rename(path, filepath.Join(dir, "app.log.1")); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("Kill failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for os.SameFile(stat(), previous) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the file to be reopened on SIGUSR1")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !exists(path) {
+		t.Error("Expected the file to be created again at its path")
+	}
+}
@@ -0,0 +1,486 @@
+package file
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BackupTimeFormat is the layout of the time in rotated file names, app.log is rotated to
+// app-2006-01-02T15-04-05.000.log, followed by .gz when compressed.
+const BackupTimeFormat = "2006-01-02T15-04-05.000"
+
+const compressSuffix = ".gz"
+
+// DefaultFallbackRetry is the interval at which a Writer writing to its fallback tries the file again.
+var DefaultFallbackRetry = 10 * time.Second
+
+type Interval string
+
+const (
+	IntervalNone   Interval = ""
+	IntervalHourly Interval = "hourly"
+	IntervalDaily  Interval = "daily"
+)
+
+// Rotation configures when a Writer rotates its file and which backups it keeps.
+type Rotation struct {
+	// MaxSize rotates the file before a write would grow it past MaxSize bytes, zero disables it.
+	MaxSize int64
+	// Interval rotates the file at every hour or day boundary in local time.
+	Interval Interval
+	// MaxBackups is the number of rotated files to keep, zero keeps them all.
+	MaxBackups int
+	// MaxAge removes rotated files older than MaxAge, zero keeps them all.
+	MaxAge time.Duration
+	// Compress gzips rotated files in the background.
+	Compress bool
+}
+
+// Writer is an io.Writer appending to the file at Path and rotating it following Rotation.
+// The file is swapped under the same lock as writes, so no write is lost or split across files.
+//
+// External rotation tools are supported through Reopen: after logrotate moved the file, Reopen
+// opens a new file at Path, after copytruncate the writer keeps appending to the truncated file.
+//
+// When Fallback is set, writes failing on the file, because the disk is full or the permissions were
+// revoked, go to Fallback instead and Health reports the failure. The file is reopened and used again
+// on the first write after FallbackRetry. Fallback and FallbackRetry must be set before the writer is
+// used.
+type Writer struct {
+	Path          string
+	Rotation      Rotation
+	NowFunc       func() time.Time
+	Fallback      io.Writer
+	FallbackRetry time.Duration
+
+	mu           sync.Mutex
+	file         *os.File
+	size         int64
+	nextRotation time.Time
+	isClosed     bool
+	// failure is the error that made the writer use Fallback, nil while it writes to the file.
+	failure  error
+	failedAt time.Time
+
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	// millMu serializes the background compression and removal of backups.
+	millMu sync.Mutex
+	millWG sync.WaitGroup
+}
+
+// NewWriter opens the file at path for appending, creating it when it does not exist.
+func NewWriter(path string, rotation Rotation) (*Writer, error) {
+	writer := &Writer{
+		Path:          path,
+		Rotation:      rotation,
+		NowFunc:       time.Now,
+		FallbackRetry: DefaultFallbackRetry,
+		closed:        make(chan struct{}),
+	}
+	if err := writer.open(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.isClosed {
+		return 0, os.ErrClosed
+	}
+	if w.failure != nil {
+		if w.NowFunc().Sub(w.failedAt) < w.FallbackRetry {
+			return w.Fallback.Write(p)
+		}
+		if err := w.reopen(); err != nil {
+			return w.fail(p, err)
+		}
+		w.failure = nil
+		_, _ = fmt.Fprintf(w.Fallback, "Log file %s is writable again\n", w.Path)
+	}
+	if w.file == nil {
+		// A failed rotation left no file open.
+		if err := w.open(); err != nil {
+			return w.fail(p, err)
+		}
+	}
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return w.fail(p, err)
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err != nil {
+		return w.fail(p, err)
+	}
+	return n, nil
+}
+
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.isClosed {
+		return os.ErrClosed
+	}
+	if w.failure != nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Health returns the error that made the writer use its fallback, nil while it writes to the file.
+func (w *Writer) Health() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failure != nil {
+		return fmt.Errorf("log file %s unwritable since %s, writing to fallback: %w", w.Path, w.failedAt.Format(time.RFC3339), w.failure)
+	}
+	return nil
+}
+
+// SetRotation changes the rotation settings, the next interval boundary is computed from now.
+func (w *Writer) SetRotation(rotation Rotation) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Rotation = rotation
+	w.nextRotation = w.boundary(w.NowFunc())
+}
+
+// Rotate rotates the file regardless of the Rotation thresholds.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate()
+}
+
+// Reopen closes the file and opens Path again, creating it when it was moved or removed. The
+// current file is kept when Path cannot be opened.
+func (w *Writer) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.isClosed {
+		return os.ErrClosed
+	}
+	if err := w.reopen(); err != nil {
+		return err
+	}
+	w.failure = nil
+	return nil
+}
+
+// ReopenIfMoved reopens the file when Path was moved, removed or now points to another file, and
+// reports whether it did. It notices a truncated file as well, so size rotation restarts from the
+// truncated size.
+func (w *Writer) ReopenIfMoved() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.isClosed {
+		return false, os.ErrClosed
+	}
+	if w.file == nil {
+		// A failed rotation left no file open, the next write reopens Path.
+		return false, nil
+	}
+	current, err := w.file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat log file: %w", err)
+	}
+	info, err := os.Stat(w.Path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && !os.SameFile(info, current)) {
+		return true, w.reopen()
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat log file: %w", err)
+	}
+	if current.Size() < w.size {
+		w.size = current.Size()
+	}
+	return false, nil
+}
+
+// ReopenOn reopens the file whenever the process receives one of signals, until the writer is closed.
+func (w *Writer) ReopenOn(signals ...os.Signal) {
+	if len(signals) == 0 {
+		return
+	}
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	go func() {
+		defer signal.Stop(received)
+		for {
+			select {
+			case <-w.closed:
+				return
+			case <-received:
+				if err := w.Reopen(); err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "Failed to reopen log file %s: %v\n", w.Path, err)
+				}
+			}
+		}
+	}()
+}
+
+// CheckEvery calls ReopenIfMoved every interval until the writer is closed.
+func (w *Writer) CheckEvery(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.closed:
+				return
+			case <-ticker.C:
+				if _, err := w.ReopenIfMoved(); err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "Failed to reopen log file %s: %v\n", w.Path, err)
+				}
+			}
+		}
+	}()
+}
+
+// Close closes the file, stops the signal and stat checks and waits for the background compression
+// to complete.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.isClosed = true
+	w.mu.Unlock()
+	w.closeOnce.Do(func() {
+		close(w.closed)
+	})
+	w.millWG.Wait()
+	return err
+}
+
+// reopen opens Path and closes the previous file. It must be called with w.mu held.
+func (w *Writer) reopen() error {
+	previous := w.file
+	if err := w.open(); err != nil {
+		return err
+	}
+	if previous != nil {
+		// The new file is in use already, a failure closing a broken previous file does not matter.
+		_ = previous.Close()
+	}
+	return nil
+}
+
+// fail writes p to Fallback after err, or returns err without Fallback. It must be called with w.mu held.
+func (w *Writer) fail(p []byte, err error) (int, error) {
+	if w.Fallback == nil {
+		return 0, err
+	}
+	if w.failure == nil {
+		_, _ = fmt.Fprintf(w.Fallback, "Log file %s is unwritable, writing to fallback: %v\n", w.Path, err)
+	}
+	w.failure = err
+	w.failedAt = w.NowFunc()
+	if _, fallbackErr := w.Fallback.Write(p); fallbackErr != nil {
+		return 0, errors.Join(err, fallbackErr)
+	}
+	return len(p), nil
+}
+
+func (w *Writer) open() error {
+	file, err := os.OpenFile(w.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	w.file = file
+	w.size = info.Size()
+	w.nextRotation = w.boundary(w.NowFunc())
+	return nil
+}
+
+func (w *Writer) shouldRotate(size int64) bool {
+	if w.Rotation.MaxSize > 0 && w.size > 0 && w.size+size > w.Rotation.MaxSize {
+		return true
+	}
+	return !w.nextRotation.IsZero() && !w.NowFunc().Before(w.nextRotation)
+}
+
+// rotate renames the current file to a backup and opens a new one. It must be called with w.mu held.
+func (w *Writer) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %w", err)
+		}
+		w.file = nil
+	}
+	now := w.NowFunc()
+	if err := os.Rename(w.Path, w.backupName(now)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		// Keep writing to the current file rather than losing entries.
+		if openErr := w.open(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		return fmt.Errorf("failed to rename log file: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.millWG.Add(1)
+	go w.mill(w.Rotation, now)
+	return nil
+}
+
+// boundary returns the next interval boundary after now, or the zero time without interval.
+func (w *Writer) boundary(now time.Time) time.Time {
+	switch w.Rotation.Interval {
+	case IntervalHourly:
+		return time.Date(now.Year(), now.Month(), now.Day(), now.Hour()+1, 0, 0, 0, now.Location())
+	case IntervalDaily:
+		return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	default:
+		return time.Time{}
+	}
+}
+
+func (w *Writer) backupName(t time.Time) string {
+	prefix, ext := w.prefixAndExt()
+	name := prefix + t.Format(BackupTimeFormat)
+	// Keep the names unique when rotating twice within a millisecond.
+	candidate := name + ext
+	for i := 1; exists(candidate) || exists(candidate+compressSuffix); i++ {
+		candidate = fmt.Sprintf("%s.%d%s", name, i, ext)
+	}
+	return candidate
+}
+
+// prefixAndExt splits Path into the backup name prefix, /var/log/app-, and the extension, .log.
+func (w *Writer) prefixAndExt() (string, string) {
+	ext := filepath.Ext(w.Path)
+	return strings.TrimSuffix(w.Path, ext) + "-", ext
+}
+
+type backup struct {
+	path       string
+	time       time.Time
+	compressed bool
+}
+
+// backups returns the rotated files of Path, newest first.
+func (w *Writer) backups() ([]backup, error) {
+	prefix, ext := w.prefixAndExt()
+	entries, err := os.ReadDir(filepath.Dir(w.Path))
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Base(prefix)
+	backups := make([]backup, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, base) {
+			continue
+		}
+		compressed := strings.HasSuffix(name, compressSuffix)
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, base), compressSuffix), ext)
+		if len(stamp) < len(BackupTimeFormat) {
+			continue
+		}
+		t, err := time.ParseInLocation(BackupTimeFormat, stamp[:len(BackupTimeFormat)], time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(filepath.Dir(w.Path), name), time: t, compressed: compressed})
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+	return backups, nil
+}
+
+// mill removes the backups beyond MaxBackups or older than MaxAge at now and compresses the others.
+func (w *Writer) mill(rotation Rotation, now time.Time) {
+	defer w.millWG.Done()
+	w.millMu.Lock()
+	defer w.millMu.Unlock()
+	backups, err := w.backups()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to list log file backups: %v\n", err)
+		return
+	}
+	cutoff := time.Time{}
+	if rotation.MaxAge > 0 {
+		cutoff = now.Add(-rotation.MaxAge)
+	}
+	for i, b := range backups {
+		if (rotation.MaxBackups > 0 && i >= rotation.MaxBackups) || (!cutoff.IsZero() && b.time.Before(cutoff)) {
+			if err := os.Remove(b.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				_, _ = fmt.Fprintf(os.Stderr, "Failed to remove log file backup: %v\n", err)
+			}
+			continue
+		}
+		if rotation.Compress && !b.compressed {
+			if err := compress(b.path); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Failed to compress log file backup: %v\n", err)
+			}
+		}
+	}
+}
+
+// compress writes path to path.gz and removes path. The archive is written to a temporary file
+// first, so a partial archive is never left behind.
+func compress(path string) (err error) {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+	temporary := path + compressSuffix + ".tmp"
+	target, err := os.OpenFile(temporary, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = target.Close()
+			_ = os.Remove(temporary)
+		}
+	}()
+	writer := gzip.NewWriter(target)
+	if _, err = io.Copy(writer, source); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	if err = target.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(temporary, path+compressSuffix); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
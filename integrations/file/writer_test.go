@@ -0,0 +1,367 @@
+package file
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ensarkovankaya/go-logging/core"
+	"github.com/ensarkovankaya/go-logging/integrations/console"
+)
+
+func TestWriter_RotateBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writer, now := newTestWriter(t, path, Rotation{MaxSize: 10})
+	write(t, writer, "12345678\n")
+	*now = now.Add(time.Second)
+	write(t, writer, "abcdefgh\n")
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	assertContent(t, path, "abcdefgh\n")
+	assertContent(t, filepath.Join(filepath.Dir(path), "app-2026-10-17T10-30-01.000.log"), "12345678\n")
+}
+
+func TestWriter_RotateByInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writer, now := newTestWriter(t, path, Rotation{Interval: IntervalHourly})
+	write(t, writer, "first\n")
+	*now = now.Add(29 * time.Minute)
+	write(t, writer, "second\n")
+	*now = now.Add(time.Minute)
+	write(t, writer, "third\n")
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	assertContent(t, path, "third\n")
+	assertContent(t, filepath.Join(filepath.Dir(path), "app-2026-10-17T11-00-00.000.log"), "first\nsecond\n")
+}
+
+func TestWriter_Backups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writer, now := newTestWriter(t, path, Rotation{MaxBackups: 2, MaxAge: 3 * time.Hour, Compress: true})
+	for i := 0; i < 4; i++ {
+		write(t, writer, "entry\n")
+		*now = now.Add(time.Hour)
+		if err := writer.Rotate(); err != nil {
+			t.Fatalf("Rotate failed: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	expected := []string{"app-2026-10-17T13-30-00.000.log.gz", "app-2026-10-17T14-30-00.000.log.gz", "app.log"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected files %v, got %v", expected, names)
+	}
+	file, err := os.Open(filepath.Join(dir, expected[1]))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip.NewReader failed: %v", err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil || string(content) != "entry\n" {
+		t.Errorf("Expected the compressed backup to hold 'entry', got '%s' (%v)", content, err)
+	}
+}
+
+func TestWriter_ConcurrentRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writer, err := NewWriter(path, Rotation{MaxSize: 100})
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := writer.Write([]byte("0123456789\n")); err != nil {
+					t.Errorf("Write failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err = writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	lines := 0
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		if len(content) > 100 {
+			t.Errorf("Expected %s to stay within the max size, got %d bytes", entry.Name(), len(content))
+		}
+		lines += strings.Count(string(content), "0123456789\n")
+	}
+	if lines != 200 {
+		t.Errorf("Expected 200 lines across the files, got %d", lines)
+	}
+}
+
+func TestWriter_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	moved := filepath.Join(dir, "app.log.1")
+	writer, _ := newTestWriter(t, path, Rotation{})
+	write(t, writer, "before\n")
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	write(t, writer, "moved\n")
+	if err := writer.Reopen(); err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	write(t, writer, "after\n")
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	assertContent(t, moved, "before\nmoved\n")
+	assertContent(t, path, "after\n")
+	if err := writer.Reopen(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestWriter_ReopenIfMoved(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writer, _ := newTestWriter(t, path, Rotation{MaxSize: 20})
+	defer func() {
+		_ = writer.Close()
+	}()
+	write(t, writer, "0123456789\n")
+	if reopened, err := writer.ReopenIfMoved(); reopened || err != nil {
+		t.Fatalf("Expected no reopen for an unchanged file, got %v (%v)", reopened, err)
+	}
+
+	// copytruncate keeps the file, the size restarts from zero.
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if reopened, err := writer.ReopenIfMoved(); reopened || err != nil {
+		t.Fatalf("Expected no reopen for a truncated file, got %v (%v)", reopened, err)
+	}
+	write(t, writer, "0123456789\n")
+	assertContent(t, path, "0123456789\n")
+
+	if err := os.Rename(path, filepath.Join(dir, "app.log.1")); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if reopened, err := writer.ReopenIfMoved(); !reopened || err != nil {
+		t.Fatalf("Expected a reopen for a moved file, got %v (%v)", reopened, err)
+	}
+	write(t, writer, "after\n")
+	assertContent(t, path, "after\n")
+}
+
+func TestWriter_CheckEvery(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writer, _ := newTestWriter(t, path, Rotation{})
+	defer func() {
+		_ = writer.Close()
+	}()
+	writer.CheckEvery(5 * time.Millisecond)
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !exists(path) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the removed file to be created again")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLogger_WithRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger := New(path, WithRotation(Rotation{MaxSize: 1}))
+	logger.Info(t.Context(), "first")
+	logger.Info(t.Context(), "second")
+	if err := logger.Writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !strings.Contains(string(content), `"second"`) || strings.Contains(string(content), `"first"`) {
+		t.Errorf("Expected the file to hold the second entry only, got %s", content)
+	}
+}
+
+func TestLogger_WithEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger := New(path, WithEncoding(console.Encoding{Format: console.FormatLogfmt, Keys: map[string]string{console.KeyTime: console.OmitKey}}))
+	logger.Named("db").Info(t.Context(), "query", core.F("rows", 3))
+	if err := logger.Writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	assertContent(t, path, "level=info logger=db msg=query rows=3\n")
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Open(""); err == nil {
+		t.Error("Expected an error for an empty path")
+	}
+	path := filepath.Join(dir, "logs", "app", "app.log")
+	if _, err := Open(path); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+	if _, err := Open(path, WithEncoding(console.Encoding{Format: "xml"})); err == nil || !strings.Contains(err.Error(), "encoding") {
+		t.Errorf("Expected an encoding error, got %v", err)
+	}
+	logger, err := Open(path, WithCreateDirs(0750))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() {
+		_ = logger.Writer.Close()
+	}()
+	info, err := os.Stat(filepath.Dir(path))
+	if err != nil || info.Mode().Perm() != 0750 {
+		t.Errorf("Expected the directory to be created with mode 0750, got %v (%v)", info, err)
+	}
+}
+
+// TestOpen_IgnoresConsoleLevel runs in a child process, the console level is read from
+// CONSOLE_LOG_LEVEL when the console package is initialised.
+func TestOpen_IgnoresConsoleLevel(t *testing.T) {
+	if os.Getenv(envLogLevel) == "" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestOpen_IgnoresConsoleLevel$")
+		cmd.Env = append(os.Environ(), "CONSOLE_LOG_LEVEL=disabled", envLogLevel+"=info")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Child test failed: %v\n%s", err, output)
+		}
+		return
+	}
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	logger.Debug(context.Background(), "Debug message")
+	logger.Info(context.Background(), "Info message")
+	if err = logger.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the file: %v", err)
+	}
+	if strings.Contains(string(content), "Debug message") || !strings.Contains(string(content), "Info message") {
+		t.Errorf("Expected only the info entry of FILE_LOG_LEVEL, got %q", content)
+	}
+}
+
+func TestWriter_Fallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writer, now := newTestWriter(t, path, Rotation{})
+	defer func() {
+		_ = writer.Close()
+	}()
+	fallback := &strings.Builder{}
+	writer.Fallback = fallback
+	writer.FallbackRetry = time.Minute
+
+	write(t, writer, "first\n")
+	// Closing the file behind the writer makes every write fail, like a revoked permission.
+	_ = writer.file.Close()
+	write(t, writer, "second\n")
+	if err := writer.Health(); err == nil {
+		t.Error("Expected an unhealthy writer")
+	}
+	*now = now.Add(30 * time.Second)
+	write(t, writer, "third\n")
+	if !strings.HasSuffix(fallback.String(), "second\nthird\n") {
+		t.Errorf("Expected the entries in the fallback, got %q", fallback.String())
+	}
+
+	*now = now.Add(time.Minute)
+	write(t, writer, "fourth\n")
+	if err := writer.Health(); err != nil {
+		t.Errorf("Expected the writer to recover, got %v", err)
+	}
+	assertContent(t, path, "first\nfourth\n")
+}
+
+func TestWriter_NoFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writer, _ := newTestWriter(t, path, Rotation{})
+	defer func() {
+		_ = writer.Close()
+	}()
+	_ = writer.file.Close()
+	if _, err := writer.Write([]byte("entry\n")); err == nil {
+		t.Error("Expected the write to fail without fallback")
+	}
+	if err := writer.Health(); err != nil {
+		t.Errorf("Expected no health failure without fallback, got %v", err)
+	}
+}
+
+// newTestWriter returns a writer whose clock is read from the returned time, starting at 10:30 local time.
+func newTestWriter(t *testing.T, path string, rotation Rotation) (*Writer, *time.Time) {
+	t.Helper()
+	now := time.Date(2026, 10, 17, 10, 30, 0, 0, time.Local)
+	writer, err := NewWriter(path, Rotation{})
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	writer.NowFunc = func() time.Time { return now }
+	writer.SetRotation(rotation)
+	return writer, &now
+}
+
+func write(t *testing.T, writer *Writer, content string) {
+	t.Helper()
+	if _, err := writer.Write([]byte(content)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+}
+
+func assertContent(t *testing.T, path, expected string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(content) != expected {
+		t.Errorf("Expected %s to hold '%s', got '%s'", filepath.Base(path), expected, content)
+	}
+}
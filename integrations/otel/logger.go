@@ -0,0 +1,328 @@
+// Package otel provides a core.Interface implementation that emits OpenTelemetry log records.
+package otel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+const Type = "otel"
+
+// ScopeName is the instrumentation scope used by loggers without a name.
+const ScopeName = "github.com/ensarkovankaya/go-logging"
+
+type Option func(l *Logger)
+
+// Logger emits OpenTelemetry log records. Level is the level set by New and the options, the level
+// changed at runtime with SetLevel is shared by every logger derived from the same New call and is
+// reported by Levels.
+type Logger struct {
+	Provider  otellog.LoggerProvider
+	Transport otellog.Logger
+	Name      string
+	Level     core.Level
+	level     *core.AtomicLevel
+	Extra     []core.Field
+	NowFunc   func() time.Time
+}
+
+// New returns a Logger emitting records through the provider selected by OTEL_LOGS_EXPORTER, see
+// Initialize. It panics when the provider cannot be built.
+func New(opts ...Option) *Logger {
+	provider, err := Initialize()
+	if err != nil {
+		panic(fmt.Sprintf("OpenTelemetry logger provider initialization failed: %v", err))
+	}
+	return NewWithProvider(provider, opts...)
+}
+
+// NewWithProvider returns a Logger emitting records through provider, OTEL_LOGS_EXPORTER is not
+// read.
+func NewWithProvider(provider otellog.LoggerProvider, opts ...Option) *Logger {
+	logger := &Logger{
+		Provider: provider,
+		Level:    defaultLevel,
+		NowFunc:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(logger)
+	}
+	logger.level = core.NewAtomicLevel(logger.Level)
+	if logger.Transport == nil {
+		logger.Transport = logger.Provider.Logger(logger.scopeName())
+	}
+	return logger
+}
+
+func (l *Logger) Type() string {
+	return Type
+}
+
+func (l *Logger) Named(name string) core.Interface {
+	_l := l.clone()
+	switch {
+	case name == "":
+	case l.Name != "":
+		_l.Name = fmt.Sprintf("%s.%s", l.Name, name)
+	default:
+		_l.Name = name
+	}
+	_l.Transport = _l.Provider.Logger(_l.scopeName())
+	return _l
+}
+
+func (l *Logger) Clone() core.Interface {
+	return l.clone()
+}
+
+func (l *Logger) WithContext(ctx context.Context) context.Context {
+	return ctx
+}
+
+func (l *Logger) With(fields ...core.Field) core.Interface {
+	_l := l.clone()
+	_l.Extra = append(_l.Extra, fields...)
+	return _l
+}
+
+func (l *Logger) Trace(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanLog(core.LevelTrace) {
+		l.Log(ctx, core.LevelTrace, msg, fields)
+	}
+}
+
+func (l *Logger) Debug(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanLog(core.LevelDebug) {
+		l.Log(ctx, core.LevelDebug, msg, fields)
+	}
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanLog(core.LevelInfo) {
+		l.Log(ctx, core.LevelInfo, msg, fields)
+	}
+}
+
+func (l *Logger) Warning(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanLog(core.LevelWarning) {
+		l.Log(ctx, core.LevelWarning, msg, fields)
+	}
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanLog(core.LevelError) {
+		l.Log(ctx, core.LevelError, msg, fields)
+	}
+}
+
+func (l *Logger) Fatal(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanLog(core.LevelFatal) {
+		l.Log(ctx, core.LevelFatal, msg, fields)
+	}
+}
+
+func (l *Logger) Panic(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanLog(core.LevelPanic) {
+		l.Log(ctx, core.LevelPanic, msg, fields)
+	}
+}
+
+func (l *Logger) Flush(ctx context.Context) error {
+	return flushProvider(ctx, l.Provider)
+}
+
+// Close flushes and shuts down the provider, unless it is the global provider, which belongs to the
+// application.
+func (l *Logger) Close(ctx context.Context) error {
+	return shutdownProvider(ctx, l.Provider)
+}
+
+// Log emits a record at the given level. The trace and span IDs are taken from ctx by the SDK.
+func (l *Logger) Log(ctx context.Context, level core.Level, msg string, fields []core.Field) {
+	var record otellog.Record
+	record.SetTimestamp(l.NowFunc())
+	record.SetSeverity(getSeverity(level))
+	record.SetSeverityText(level.String())
+	record.SetBody(otellog.StringValue(msg))
+	record.AddAttributes(l.attributes(l.Extra)...)
+	record.AddAttributes(l.attributes(fields)...)
+	l.Transport.Emit(ctx, record)
+}
+
+func (l *Logger) CanLog(level core.Level) bool {
+	return l.getLevel().Enabled(level)
+}
+
+// SetLevel changes the level of every logger derived from the same New call.
+func (l *Logger) SetLevel(level core.Level) {
+	if l.level == nil {
+		l.Level = level
+		return
+	}
+	l.level.Store(level)
+}
+
+func (l *Logger) Levels() map[string]core.Level {
+	return map[string]core.Level{core.LogLevelName: l.getLevel()}
+}
+
+func (l *Logger) SetLevels(levels map[string]core.Level) error {
+	for name := range levels {
+		if name != core.LogLevelName {
+			return fmt.Errorf("unknown %s level: %s", Type, name)
+		}
+	}
+	for _, level := range levels {
+		l.SetLevel(level)
+	}
+	return nil
+}
+
+// getLevel returns the level changed at runtime, Level when the logger was not created by New.
+func (l *Logger) getLevel() core.Level {
+	if l.level == nil {
+		return l.Level
+	}
+	return l.level.Load()
+}
+
+func (l *Logger) scopeName() string {
+	if l.Name == "" {
+		return ScopeName
+	}
+	return l.Name
+}
+
+func (l *Logger) clone() *Logger {
+	_l := *l
+	_l.Extra = make([]core.Field, 0, len(l.Extra))
+	_l.Extra = append(_l.Extra, l.Extra...)
+	return &_l
+}
+
+func (l *Logger) attributes(fields []core.Field) []otellog.KeyValue {
+	attributes := make([]otellog.KeyValue, 0, len(fields))
+	for _, field := range fields {
+		attributes = append(attributes, otellog.KeyValue{Key: field.Key, Value: toValue(field.Value)})
+	}
+	return attributes
+}
+
+func getSeverity(level core.Level) otellog.Severity {
+	switch level {
+	case core.LevelTrace:
+		return otellog.SeverityTrace
+	case core.LevelDebug:
+		return otellog.SeverityDebug
+	case core.LevelInfo:
+		return otellog.SeverityInfo
+	case core.LevelWarning:
+		return otellog.SeverityWarn
+	case core.LevelError:
+		return otellog.SeverityError
+	case core.LevelPanic:
+		return otellog.SeverityFatal
+	case core.LevelFatal:
+		return otellog.SeverityFatal2
+	default:
+		return otellog.SeverityUndefined
+	}
+}
+
+// toValue converts an arbitrary field value into a typed log.Value.
+// Values without a native representation are converted through their JSON encoding.
+//
+//nolint:gocyclo
+func toValue(value any) otellog.Value {
+	switch v := value.(type) {
+	case nil:
+		return otellog.Value{}
+	case otellog.Value:
+		return v
+	case string:
+		return otellog.StringValue(v)
+	case bool:
+		return otellog.BoolValue(v)
+	case int:
+		return otellog.IntValue(v)
+	case int8:
+		return otellog.Int64Value(int64(v))
+	case int16:
+		return otellog.Int64Value(int64(v))
+	case int32:
+		return otellog.Int64Value(int64(v))
+	case int64:
+		return otellog.Int64Value(v)
+	case uint8:
+		return otellog.Int64Value(int64(v))
+	case uint16:
+		return otellog.Int64Value(int64(v))
+	case uint32:
+		return otellog.Int64Value(int64(v))
+	case uint:
+		return uintValue(uint64(v))
+	case uint64:
+		return uintValue(v)
+	case float32:
+		return otellog.Float64Value(float64(v))
+	case float64:
+		return otellog.Float64Value(v)
+	case []byte:
+		return otellog.BytesValue(v)
+	case error:
+		return otellog.StringValue(v.Error())
+	case time.Time:
+		return otellog.StringValue(v.Format(time.RFC3339Nano))
+	case time.Duration:
+		return otellog.StringValue(v.String())
+	case fmt.Stringer:
+		return otellog.StringValue(v.String())
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return otellog.Value{}
+		}
+		return toValue(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		values := make([]otellog.Value, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, toValue(rv.Index(i).Interface()))
+		}
+		return otellog.SliceValue(values...)
+	case reflect.Map:
+		values := make([]otellog.KeyValue, 0, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			values = append(values, otellog.KeyValue{Key: fmt.Sprint(iter.Key().Interface()), Value: toValue(iter.Value().Interface())})
+		}
+		return otellog.MapValue(values...)
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return otellog.StringValue(fmt.Sprintf("%+v", value))
+		}
+		var decoded any
+		if err = json.Unmarshal(encoded, &decoded); err != nil {
+			return otellog.StringValue(string(encoded))
+		}
+		return toValue(decoded)
+	}
+}
+
+func uintValue(v uint64) otellog.Value {
+	if v > math.MaxInt64 {
+		return otellog.StringValue(fmt.Sprint(v))
+	}
+	return otellog.Int64Value(int64(v))
+}
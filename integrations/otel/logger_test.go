@@ -0,0 +1,209 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+var testTimestamp = time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+type mockExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *mockExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, record := range records {
+		e.records = append(e.records, record.Clone())
+	}
+	return nil
+}
+
+func (e *mockExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func (e *mockExporter) ForceFlush(_ context.Context) error {
+	return nil
+}
+
+func (e *mockExporter) Records() []sdklog.Record {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.records
+}
+
+func Test_NewWithProvider(t *testing.T) {
+	defer func(previous string) { exporter = previous }(exporter)
+	exporter = "invalid"
+	provider := sdklog.NewLoggerProvider()
+	logger := NewWithProvider(provider)
+	if logger.Provider != provider || logger.Transport == nil {
+		t.Errorf("Expected the logger to use the provider, got %+v", logger)
+	}
+}
+
+func Test_Logger_Type(t *testing.T) {
+	logger, _ := getTestLogger(t)
+	if logger.Type() != Type {
+		t.Errorf("Expected logger type '%v', got '%s'", Type, logger.Type())
+	}
+}
+
+func Test_Logger_Levels(t *testing.T) {
+	logger, exporter := getTestLogger(t)
+	logger.SetLevel(core.LevelWarning)
+	ctx := context.Background()
+	logger.Debug(ctx, "Debug message")
+	logger.Info(ctx, "Info message")
+	logger.Warning(ctx, "Warning message")
+	logger.Error(ctx, "Error message")
+	if err := logger.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	records := exporter.Records()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	expected := []struct {
+		severity otellog.Severity
+		text     string
+		body     string
+	}{
+		{otellog.SeverityWarn, "WARNING", "Warning message"},
+		{otellog.SeverityError, "ERROR", "Error message"},
+	}
+	for i, record := range records {
+		if record.Severity() != expected[i].severity {
+			t.Errorf("Expected severity %v, got %v", expected[i].severity, record.Severity())
+		}
+		if record.SeverityText() != expected[i].text {
+			t.Errorf("Expected severity text %s, got %s", expected[i].text, record.SeverityText())
+		}
+		if record.Body().AsString() != expected[i].body {
+			t.Errorf("Expected body %s, got %s", expected[i].body, record.Body().AsString())
+		}
+		if !record.Timestamp().Equal(testTimestamp) {
+			t.Errorf("Expected timestamp %v, got %v", testTimestamp, record.Timestamp())
+		}
+	}
+}
+
+func Test_Logger_Attributes(t *testing.T) {
+	type A struct {
+		F1 string `json:"f_1"`
+		F2 int
+	}
+	logger, exporter := getTestLogger(t)
+	logger.With(core.F("service", "test")).Info(
+		context.Background(),
+		"Info message",
+		core.F("str", "value"),
+		core.F("int", 1),
+		core.F("float", 1.8),
+		core.F("bool", true),
+		core.F("slice", []string{"a", "b"}),
+		core.F("map", map[string]int{"a": 1}),
+		core.F("struct", A{F1: "test", F2: 123}),
+		core.F("duration", time.Second),
+		core.E(errors.New("some error")),
+	)
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	attributes := map[string]otellog.Value{}
+	records[0].WalkAttributes(func(kv otellog.KeyValue) bool {
+		attributes[kv.Key] = kv.Value
+		return true
+	})
+	expected := map[string]otellog.Kind{
+		"service":  otellog.KindString,
+		"str":      otellog.KindString,
+		"int":      otellog.KindInt64,
+		"float":    otellog.KindFloat64,
+		"bool":     otellog.KindBool,
+		"slice":    otellog.KindSlice,
+		"map":      otellog.KindMap,
+		"struct":   otellog.KindMap,
+		"duration": otellog.KindString,
+		"error":    otellog.KindString,
+	}
+	for key, kind := range expected {
+		value, ok := attributes[key]
+		if !ok {
+			t.Errorf("Expected attribute '%s' not found", key)
+			continue
+		}
+		if value.Kind() != kind {
+			t.Errorf("Expected attribute '%s' kind %v, got %v", key, kind, value.Kind())
+		}
+	}
+	if attributes["error"].AsString() != "some error" {
+		t.Errorf("Expected error attribute 'some error', got '%s'", attributes["error"].AsString())
+	}
+}
+
+func Test_Logger_Named(t *testing.T) {
+	logger, exporter := getTestLogger(t)
+	named := logger.Named("test").Named("sub").Named("").(*Logger)
+	if named.Name != "test.sub" {
+		t.Errorf("Expected logger name 'test.sub', got '%s'", named.Name)
+	}
+	named.Info(context.Background(), "Info message")
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if scope := records[0].InstrumentationScope().Name; scope != "test.sub" {
+		t.Errorf("Expected instrumentation scope 'test.sub', got '%s'", scope)
+	}
+}
+
+func Test_Logger_TraceContext(t *testing.T) {
+	logger, exporter := getTestLogger(t)
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03},
+		SpanID:     trace.SpanID{0x04, 0x05},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+	logger.Error(ctx, "Error message")
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if records[0].TraceID() != spanContext.TraceID() {
+		t.Errorf("Expected trace ID %s, got %s", spanContext.TraceID(), records[0].TraceID())
+	}
+	if records[0].SpanID() != spanContext.SpanID() {
+		t.Errorf("Expected span ID %s, got %s", spanContext.SpanID(), records[0].SpanID())
+	}
+	if records[0].TraceFlags() != spanContext.TraceFlags() {
+		t.Errorf("Expected trace flags %s, got %s", spanContext.TraceFlags(), records[0].TraceFlags())
+	}
+}
+
+func getTestLogger(t *testing.T, opts ...Option) (*Logger, *mockExporter) {
+	t.Helper()
+	exporter := &mockExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	opts = append([]Option{func(l *Logger) {
+		l.Level = core.LevelDebug
+		l.NowFunc = func() time.Time {
+			return testTimestamp
+		}
+	}}, opts...)
+	return NewWithProvider(provider, opts...), exporter
+}
@@ -0,0 +1,100 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+type ProviderOption = sdklog.LoggerProviderOption
+
+const (
+	ExporterConsole = "console"
+	ExporterOTLP    = "otlp"
+	ExporterNone    = "none"
+)
+
+var (
+	exporter     = ""
+	defaultLevel = core.LevelDebug
+)
+
+var (
+	envExporter = "OTEL_LOGS_EXPORTER"
+	envLogLevel = "OTEL_LOGS_LEVEL"
+)
+
+// IsActive reports whether a logs exporter is configured through OTEL_LOGS_EXPORTER.
+func IsActive() bool {
+	return exporter != "" && exporter != ExporterNone
+}
+
+// Initialize returns the log.LoggerProvider selected by OTEL_LOGS_EXPORTER.
+//
+// With "console" a new SDK provider is built that writes records to stdout through a batch processor,
+// which honours the standard OTEL_BLRP_* variables. With "otlp" (or when the variable is empty) the
+// globally registered provider is returned, so records flow through the exporter pipeline the
+// application already set up with global.SetLoggerProvider.
+func Initialize(opts ...ProviderOption) (otellog.LoggerProvider, error) {
+	return NewProvider(exporter, opts...)
+}
+
+// NewProvider returns the log.LoggerProvider for the given exporter name, see Initialize.
+func NewProvider(exporter string, opts ...ProviderOption) (otellog.LoggerProvider, error) {
+	switch strings.ToLower(strings.TrimSpace(exporter)) {
+	case ExporterConsole, "stdout":
+		exp, err := stdoutlog.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout log exporter: %w", err)
+		}
+		opts = append([]ProviderOption{sdklog.WithProcessor(sdklog.NewBatchProcessor(exp))}, opts...)
+		return sdklog.NewLoggerProvider(opts...), nil
+	case ExporterOTLP, "":
+		return global.GetLoggerProvider(), nil
+	case ExporterNone:
+		return sdklog.NewLoggerProvider(opts...), nil
+	default:
+		return nil, fmt.Errorf("unsupported %s value: %s", envExporter, exporter)
+	}
+}
+
+// flushProvider flushes the provider if it supports it, the global and no-op providers do not.
+func flushProvider(ctx context.Context, provider otellog.LoggerProvider) error {
+	flusher, ok := provider.(interface {
+		ForceFlush(ctx context.Context) error
+	})
+	if !ok {
+		return nil
+	}
+	return flusher.ForceFlush(ctx)
+}
+
+// shutdownProvider shuts the provider down if it supports it and is not the global provider.
+func shutdownProvider(ctx context.Context, provider otellog.LoggerProvider) error {
+	shutdowner, ok := provider.(interface {
+		Shutdown(ctx context.Context) error
+	})
+	if !ok || provider == global.GetLoggerProvider() {
+		return flushProvider(ctx, provider)
+	}
+	return shutdowner.Shutdown(ctx)
+}
+
+func init() {
+	exporter = strings.ToLower(strings.TrimSpace(os.Getenv(envExporter)))
+	if os.Getenv(envLogLevel) != "" {
+		if level, err := core.ParseLevel(os.Getenv(envLogLevel)); err == nil {
+			defaultLevel = level
+		} else {
+			_, _ = fmt.Fprintf(os.Stderr, "Invalid %s environment value, using default: %s\n", envLogLevel, defaultLevel.String())
+		}
+	}
+}
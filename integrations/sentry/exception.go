@@ -0,0 +1,195 @@
+package sentry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+// defaultMaxErrorDepth limits the errors reported from a chain when the hub has no client.
+const defaultMaxErrorDepth = 10
+
+// Fingerprint placeholders replaced when an event is captured.
+const (
+	// FingerprintDefault is left for Sentry to replace with its default grouping.
+	FingerprintDefault = "{{ default }}"
+	// FingerprintLogger is replaced with the name of the logger.
+	FingerprintLogger = "{{ logger }}"
+	// FingerprintErrorType is replaced with the type of the outermost error of the event, or
+	// removed when the event has no error.
+	FingerprintErrorType = "{{ error.type }}"
+)
+
+// modulePath is the import path of this module, its frames are trimmed from call site stack traces.
+var modulePath = strings.TrimSuffix(reflect.TypeOf(Logger{}).PkgPath(), "/integrations/sentry")
+
+// FingerprintRule sets the fingerprint of the events captured by the loggers whose name matches
+// Pattern, see core.LevelRule for the pattern syntax.
+type FingerprintRule struct {
+	Pattern     string
+	Fingerprint []string
+}
+
+// FingerprintRules is an ordered list of FingerprintRule, the first matching rule applies.
+type FingerprintRules []FingerprintRule
+
+// Fingerprint returns the fingerprint of the first rule matching the logger name.
+func (r FingerprintRules) Fingerprint(name string) ([]string, bool) {
+	for _, rule := range r {
+		if core.MatchName(rule.Pattern, name) {
+			return rule.Fingerprint, true
+		}
+	}
+	return nil, false
+}
+
+// WithFingerprints sets the fingerprint rules of the logger.
+func WithFingerprints(rules ...FingerprintRule) Option {
+	return func(l *Logger) {
+		l.Fingerprints = rules
+	}
+}
+
+// fingerprint returns the fingerprint of event with the placeholders replaced, nil when no rule
+// matches the logger name.
+func (l *Logger) fingerprint(event *sentry.Event) []string {
+	rule, ok := l.Fingerprints.Fingerprint(l.Name)
+	if !ok {
+		return nil
+	}
+	fingerprint := make([]string, 0, len(rule))
+	for _, part := range rule {
+		switch part {
+		case FingerprintLogger:
+			part = l.Name
+		case FingerprintErrorType:
+			if len(event.Exception) == 0 {
+				continue
+			}
+			part = event.Exception[len(event.Exception)-1].Type
+		}
+		fingerprint = append(fingerprint, part)
+	}
+	return fingerprint
+}
+
+// joinErrors returns the error of the error fields, joined like errors.Join when there are several
+// of them.
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return errors.Join(errs...)
+	}
+}
+
+// errorType returns the type reported for err, empty when err is nil.
+func errorType(err error) string {
+	if err == nil {
+		return ""
+	}
+	return reflect.TypeOf(err).String()
+}
+
+// setException sets the exceptions of event from err, see joinErrors.
+func (l *Logger) setException(ctx context.Context, event *sentry.Event, err error) {
+	if err == nil {
+		return
+	}
+	maxDepth := defaultMaxErrorDepth
+	if client := l.Hub.Client(); client != nil {
+		maxDepth = client.Options().MaxErrorDepth
+	}
+	event.Exception = exceptions(err, maxDepth)
+	if outermost := &event.Exception[len(event.Exception)-1]; outermost.Stacktrace == nil {
+		outermost.Stacktrace = callSiteStacktrace(ctx)
+	}
+}
+
+// exceptions returns the chain of err in the order Sentry expects, the outermost error last. The
+// errors wrapped with %w or implementing Cause are chained to the error wrapping them, the errors
+// of an errors.Join are grouped under it. At most maxDepth errors are returned, all of them when
+// maxDepth is negative.
+func exceptions(err error, maxDepth int) []sentry.Exception {
+	list := make([]sentry.Exception, 0)
+	var walk func(err error, parent *int, source string)
+	walk = func(err error, parent *int, source string) {
+		if err == nil || maxDepth >= 0 && len(list) >= maxDepth {
+			return
+		}
+		id := len(list)
+		list = append(list, sentry.Exception{
+			Type:       errorType(err),
+			Value:      err.Error(),
+			Stacktrace: sentry.ExtractStacktrace(err),
+			Mechanism: &sentry.Mechanism{
+				Type:        "chained",
+				Source:      source,
+				ExceptionID: id,
+				ParentID:    parent,
+			},
+		})
+		switch wrapped := err.(type) {
+		case interface{ Unwrap() []error }:
+			list[id].Mechanism.IsExceptionGroup = true
+			for i, child := range wrapped.Unwrap() {
+				walk(child, &id, fmt.Sprintf("errors[%d]", i))
+			}
+		case interface{ Unwrap() error }:
+			walk(wrapped.Unwrap(), &id, "cause")
+		case interface{ Cause() error }:
+			walk(wrapped.Cause(), &id, "cause")
+		}
+	}
+	walk(err, nil, "")
+	if len(list) == 1 {
+		list[0].Mechanism = nil
+	}
+	slices.Reverse(list)
+	return list
+}
+
+// callSiteStacktrace returns the stack trace of the caller of the logger, the one carried by ctx
+// when the entry is written away from its call site, without the frames of this module except the
+// ones of its tests.
+func callSiteStacktrace(ctx context.Context) *sentry.Stacktrace {
+	var stacktrace *sentry.Stacktrace
+	if pcs := core.Callers(ctx); pcs != nil {
+		stacktrace = &sentry.Stacktrace{}
+		callers := runtime.CallersFrames(pcs)
+		for {
+			caller, more := callers.Next()
+			if frame := sentry.NewFrame(caller); frame.Module != "runtime" {
+				stacktrace.Frames = append(stacktrace.Frames, frame)
+			}
+			if !more {
+				break
+			}
+		}
+		slices.Reverse(stacktrace.Frames)
+	} else if stacktrace = sentry.NewStacktrace(); stacktrace == nil {
+		return nil
+	}
+	frames := stacktrace.Frames
+	for len(frames) > 1 {
+		frame := frames[len(frames)-1]
+		inModule := frame.Module == modulePath || strings.HasPrefix(frame.Module, modulePath+"/")
+		if !inModule || strings.HasSuffix(frame.AbsPath, "_test.go") {
+			break
+		}
+		frames = frames[:len(frames)-1]
+	}
+	stacktrace.Frames = frames
+	return stacktrace
+}
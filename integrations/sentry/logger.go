@@ -2,7 +2,7 @@ package sentry
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -10,6 +10,7 @@ import (
 
 	"github.com/getsentry/sentry-go"
 	"github.com/getsentry/sentry-go/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/ensarkovankaya/go-logging/core"
 )
@@ -28,34 +29,105 @@ var (
 
 const Type = "sentry"
 
+// loggerKey is the tag and the log attribute holding the logger name.
+const loggerKey = "logger"
+
+// Level names used by Levels and SetLevels in addition to core.LogLevelName.
+const (
+	EventLevelName      = "event"
+	BreadcrumbLevelName = "breadcrumb"
+)
+
 type Option func(l *Logger)
 
+// Logger sends entries to Sentry as events, breadcrumbs and logs. The level fields are the levels
+// set by New and the options, the levels changed at runtime with SetLevels or the Set*Level methods
+// are shared by every logger derived from the same New call and are reported by Levels.
+//
+// Fields are routed to the Sentry tags, user, request and contexts by their key or value, see the
+// core.TagPrefix conventions and routeFields, the other fields are extras. With and Named clone
+// the hub and bind the fields and the logger name to the scope of the clone, string extras as
+// tags. Events read them from the scope, while breadcrumbs and logs, which Sentry does not decorate
+// with the scope, receive the bound fields directly.
+//
+// Events report the error fields, bound or not, as exceptions instead of extras, see CaptureEvent.
 type Logger struct {
 	LogLevel        core.Level
 	EventLevel      core.Level
 	BreadcrumbLevel core.Level
+	levels          *levels
 	Hub             *sentry.Hub
 	FlushTimeout    time.Duration
 	Name            string
-	NowFunc         func() time.Time
+	// Fields are the fields bound with With, in order.
+	Fields []core.Field
+	// Fingerprints group the events by logger name, Sentry groups them by default otherwise.
+	Fingerprints FingerprintRules
+	// RateLimiter suppresses the repeated events, nil sends every event.
+	RateLimiter *RateLimiter
+	NowFunc     func() time.Time
+}
+
+// WithLogLevel sends the entries at level and above as Sentry logs.
+func WithLogLevel(level core.Level) Option {
+	return func(l *Logger) {
+		l.LogLevel = level
+	}
+}
+
+// WithEventLevel captures the entries at level and above as Sentry events.
+func WithEventLevel(level core.Level) Option {
+	return func(l *Logger) {
+		l.EventLevel = level
+	}
+}
+
+// WithBreadcrumbLevel records the entries at level and above, below the event level, as breadcrumbs.
+func WithBreadcrumbLevel(level core.Level) Option {
+	return func(l *Logger) {
+		l.BreadcrumbLevel = level
+	}
 }
 
 func IsActive() bool {
 	return os.Getenv("SENTRY_DSN") != ""
 }
 
+// New returns a logger configured by the SENTRY_* environment variables, see ReadConfig, and the
+// options. The invalid variables are reported on stderr and replaced with their default, Sentry is
+// only disabled when the client cannot be created.
 func New(opts ...Option) *Logger {
+	cfg, err := ReadConfig()
+	if err = errors.Join(err, cfg.validate(DefaultConfig())); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Invalid Sentry configuration, using the defaults of the invalid values: %v\n", err)
+	}
 	logger := &Logger{
-		LogLevel:        defaultLogLevel,
-		EventLevel:      defaultEventLevel,
-		BreadcrumbLevel: defaultBreadcrumbLevel,
-		Hub:             Initialize(),
-		FlushTimeout:    FLushTimeout,
+		LogLevel:        cfg.LogLevel,
+		EventLevel:      cfg.EventLevel,
+		BreadcrumbLevel: cfg.BreadcrumbLevel,
+		FlushTimeout:    cfg.FlushTimeout,
 		NowFunc:         time.Now,
 	}
+	if cfg.RateLimit > 0 {
+		logger.RateLimiter = NewRateLimiter(cfg.RateLimit, cfg.RateLimitWindow)
+		logger.RateLimiter.BypassLevel = cfg.RateLimitBypassLevel
+	}
 	for _, opt := range opts {
 		opt(logger)
 	}
+	logger.levels = &levels{
+		log:        core.NewAtomicLevel(logger.LogLevel),
+		event:      core.NewAtomicLevel(logger.EventLevel),
+		breadcrumb: core.NewAtomicLevel(logger.BreadcrumbLevel),
+	}
+	if logger.Hub == nil {
+		hub, err := cfg.newHub()
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to initialize Sentry, events are not sent: %v\n", err)
+			hub = disabledHub()
+		}
+		logger.Hub = hub
+	}
 	return logger
 }
 
@@ -64,12 +136,14 @@ func (l *Logger) Type() string {
 }
 
 func (l *Logger) With(fields ...core.Field) core.Interface {
+	_l := l.copy()
 	if len(fields) == 0 {
-		return l.copy()
+		return _l
 	}
-	_l := l.copy()
-	_l.Hub.WithScope(func(scope *sentry.Scope) {
-		scope.SetExtras(l.transform(fields...))
+	_l.Fields = append(append(make([]core.Field, 0, len(l.Fields)+len(fields)), l.Fields...), fields...)
+	user := routeFields(_l.Fields).user
+	_l.Hub.ConfigureScope(func(scope *sentry.Scope) {
+		routeFields(fields).bind(scope, user)
 	})
 	return _l
 }
@@ -90,12 +164,23 @@ func (l *Logger) Named(name string) core.Interface {
 	}
 	_l := l.copy()
 	_l.Name = name
-	_l.Hub.WithScope(func(scope *sentry.Scope) {
-		scope.SetTag("logger", name)
+	_l.Hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag(loggerKey, name)
 	})
 	return _l
 }
 
+func (l *Logger) Trace(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanCaptureEvent(core.LevelTrace) {
+		l.CaptureEvent(ctx, core.LevelTrace, msg, fields...)
+	} else if l.CanBreadcrumb(core.LevelTrace) {
+		l.AddBreadcrumb(ctx, core.LevelTrace, msg, fields...)
+	}
+	if l.CanLog(core.LevelTrace) {
+		l.Log(ctx, core.LevelTrace, msg, fields...)
+	}
+}
+
 func (l *Logger) Debug(ctx context.Context, msg string, fields ...core.Field) {
 	if l.CanCaptureEvent(core.LevelDebug) {
 		l.CaptureEvent(ctx, core.LevelDebug, msg, fields...)
@@ -140,6 +225,28 @@ func (l *Logger) Error(ctx context.Context, msg string, fields ...core.Field) {
 	}
 }
 
+func (l *Logger) Fatal(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanCaptureEvent(core.LevelFatal) {
+		l.CaptureEvent(ctx, core.LevelFatal, msg, fields...)
+	} else if l.CanBreadcrumb(core.LevelFatal) {
+		l.AddBreadcrumb(ctx, core.LevelFatal, msg, fields...)
+	}
+	if l.CanLog(core.LevelFatal) {
+		l.Log(ctx, core.LevelFatal, msg, fields...)
+	}
+}
+
+func (l *Logger) Panic(ctx context.Context, msg string, fields ...core.Field) {
+	if l.CanCaptureEvent(core.LevelPanic) {
+		l.CaptureEvent(ctx, core.LevelPanic, msg, fields...)
+	} else if l.CanBreadcrumb(core.LevelPanic) {
+		l.AddBreadcrumb(ctx, core.LevelPanic, msg, fields...)
+	}
+	if l.CanLog(core.LevelPanic) {
+		l.Log(ctx, core.LevelPanic, msg, fields...)
+	}
+}
+
 func (l *Logger) Flush(_ context.Context) error {
 	if ok := l.Hub.Flush(l.FlushTimeout); !ok {
 		return fmt.Errorf("failed to flush Sentry hub within %s", l.FlushTimeout.String())
@@ -148,21 +255,43 @@ func (l *Logger) Flush(_ context.Context) error {
 }
 
 func (l *Logger) SetEventLevel(level core.Level) {
-	l.EventLevel = level
+	l.setLevel(EventLevelName, level)
 }
 
 func (l *Logger) SetLogLevel(level core.Level) {
-	l.LogLevel = level
+	l.setLevel(core.LogLevelName, level)
 }
 
 func (l *Logger) SetBreadcrumbLevel(level core.Level) {
-	l.BreadcrumbLevel = level
+	l.setLevel(BreadcrumbLevelName, level)
+}
+
+func (l *Logger) Levels() map[string]core.Level {
+	return map[string]core.Level{
+		core.LogLevelName:   l.getLevel(core.LogLevelName),
+		EventLevelName:      l.getLevel(EventLevelName),
+		BreadcrumbLevelName: l.getLevel(BreadcrumbLevelName),
+	}
+}
+
+func (l *Logger) SetLevels(levels map[string]core.Level) error {
+	for name := range levels {
+		if _, _, ok := l.levelByName(name); !ok {
+			return fmt.Errorf("unknown %s level: %s", Type, name)
+		}
+	}
+	for name, level := range levels {
+		l.setLevel(name, level)
+	}
+	return nil
 }
 
 func (l *Logger) Log(ctx context.Context, level core.Level, msg string, fields ...core.Field) {
-	logger := sentry.NewLogger(sentry.SetHubOnContext(ctx, l.getHub(ctx)))
+	logger := sentry.NewLogger(sentry.SetHubOnContext(ctx, l.getTracedHub(ctx)))
 	l.attachAttributes(logger, fields...)
 	switch level {
+	case core.LevelTrace:
+		logger.Trace(ctx, msg)
 	case core.LevelDebug:
 		logger.Debug(ctx, msg)
 	case core.LevelInfo:
@@ -171,39 +300,126 @@ func (l *Logger) Log(ctx context.Context, level core.Level, msg string, fields .
 		logger.Warn(ctx, msg)
 	case core.LevelError:
 		logger.Error(ctx, msg)
+	case core.LevelPanic, core.LevelFatal:
+		logFatal(ctx, logger, msg)
 	default:
 		return
 	}
 }
 
 func (l *Logger) CanBreadcrumb(level core.Level) bool {
-	return l.BreadcrumbLevel <= level
+	return l.getLevel(BreadcrumbLevelName).Enabled(level)
 }
 
 func (l *Logger) CanCaptureEvent(level core.Level) bool {
-	return l.EventLevel <= level
+	return l.getLevel(EventLevelName).Enabled(level)
 }
 
 func (l *Logger) CanLog(level core.Level) bool {
-	return l.LogLevel <= level
+	return l.getLevel(core.LogLevelName).Enabled(level)
+}
+
+// levels are the levels changed at runtime, shared by every logger derived from the same New call.
+type levels struct {
+	log        *core.AtomicLevel
+	event      *core.AtomicLevel
+	breadcrumb *core.AtomicLevel
+}
+
+// levelByName returns the level field and the level changed at runtime of name, the latter is nil
+// when the logger was not created by New.
+func (l *Logger) levelByName(name string) (*core.Level, *core.AtomicLevel, bool) {
+	var atomicLevels levels
+	if l.levels != nil {
+		atomicLevels = *l.levels
+	}
+	switch name {
+	case core.LogLevelName:
+		return &l.LogLevel, atomicLevels.log, true
+	case EventLevelName:
+		return &l.EventLevel, atomicLevels.event, true
+	case BreadcrumbLevelName:
+		return &l.BreadcrumbLevel, atomicLevels.breadcrumb, true
+	default:
+		return nil, nil, false
+	}
+}
+
+// getLevel returns the level of name changed at runtime, the level field when the logger was not
+// created by New.
+func (l *Logger) getLevel(name string) core.Level {
+	field, atomicLevel, _ := l.levelByName(name)
+	if atomicLevel == nil {
+		return *field
+	}
+	return atomicLevel.Load()
+}
+
+// setLevel changes the level of name of every logger derived from the same New call.
+func (l *Logger) setLevel(name string, level core.Level) {
+	field, atomicLevel, _ := l.levelByName(name)
+	if atomicLevel == nil {
+		*field = level
+		return
+	}
+	atomicLevel.Store(level)
 }
 
 func (l *Logger) AddBreadcrumb(ctx context.Context, level core.Level, msg string, fields ...core.Field) {
 	l.getHub(ctx).AddBreadcrumb(&sentry.Breadcrumb{
 		Level:     l.getSentryLevel(level),
 		Message:   msg,
-		Data:      l.transform(fields...),
+		Data:      l.transform(l.entryFields(core.TraceFields(ctx), fields)...),
 		Timestamp: l.NowFunc(),
 	}, nil)
 }
 
+// CaptureEvent sends an event with the error fields as exceptions. The chains built with %w or
+// errors.Join are unwrapped, stack traces are taken from the errors carrying one and the outermost
+// error falls back to the stack of the call site. The fingerprint of the first FingerprintRule
+// matching the logger name is applied.
+//
+// Events are dropped while the RateLimiter suppresses them, the number of suppressed events is
+// attached as the suppressed_events extra to the next event of the same key that is sent.
 func (l *Logger) CaptureEvent(ctx context.Context, level core.Level, msg string, fields ...core.Field) {
-	l.getHub(ctx).CaptureEvent(&sentry.Event{
+	bound, entry := routeFields(l.Fields), routeFields(fields)
+	errs := make([]error, 0, len(bound.errors)+len(entry.errors))
+	for _, field := range append(bound.errors, entry.errors...) {
+		errs = append(errs, field.Value.(error))
+	}
+	err := joinErrors(errs)
+	now := l.NowFunc()
+	suppressed := 0
+	if l.RateLimiter != nil {
+		var allowed bool
+		allowed, suppressed = l.RateLimiter.allow(rateKey{level: level, name: l.Name, message: msg, errorType: errorType(err)}, now)
+		if !allowed {
+			return
+		}
+	}
+	user := bound.user
+	mergeUser(&user, entry.user)
+	event := &sentry.Event{
 		Level:     l.getSentryLevel(level),
 		Message:   msg,
-		Extra:     l.transform(fields...),
-		Timestamp: l.NowFunc(),
-	})
+		Extra:     l.transform(entry.extra...),
+		Tags:      entry.tags,
+		Contexts:  entry.contexts,
+		User:      user,
+		Timestamp: now,
+	}
+	if entry.request != nil {
+		event.Request = sentry.NewRequest(entry.request)
+	}
+	// The bound fields are set on the event and not only on the scope of l.Hub, since the hub of ctx,
+	// such as the one of the sentryhttp middleware, is used instead when there is one.
+	bound.apply(event, l.Name)
+	if suppressed > 0 {
+		event.Extra[suppressedKey] = suppressed
+	}
+	l.setException(ctx, event, err)
+	event.Fingerprint = l.fingerprint(event)
+	l.getTracedHub(ctx).CaptureEvent(event)
 }
 
 func (l *Logger) copy() *Logger {
@@ -214,7 +430,7 @@ func (l *Logger) copy() *Logger {
 
 func (l *Logger) getSentryLevel(level core.Level) sentry.Level {
 	switch level {
-	case core.LevelDebug:
+	case core.LevelTrace, core.LevelDebug:
 		return sentry.LevelDebug
 	case core.LevelInfo:
 		return sentry.LevelInfo
@@ -222,27 +438,42 @@ func (l *Logger) getSentryLevel(level core.Level) sentry.Level {
 		return sentry.LevelWarning
 	case core.LevelError:
 		return sentry.LevelError
+	case core.LevelPanic, core.LevelFatal:
+		return sentry.LevelFatal
 	default:
 		return ""
 	}
 }
 
+// logFatal sends a log with fatal severity. sentry.Logger only offers that severity through Fatal,
+// which exits, and Panic, which panics after the log is queued; the panic is recovered so that
+// terminating the process stays with batch.Logger.
+func logFatal(ctx context.Context, logger sentry.Logger, msg string) {
+	defer func() {
+		_ = recover()
+	}()
+	logger.Panic(ctx, msg)
+}
+
 func (l *Logger) attachAttributes(logger sentry.Logger, fields ...core.Field) {
-	if len(fields) == 0 {
-		return
-	}
 	if l.Name != "" {
-		logger.SetAttributes(attribute.String("logger", l.Name))
-	}
-	attributeMap := l.transform(fields...)
-	var err error
-	var decoded []byte
-	for key, value := range attributeMap {
-		if decoded, err = json.Marshal(value); err != nil {
-			decoded = []byte(fmt.Sprintf("[decode error]: %v", err))
+		logger.SetAttributes(attribute.String(loggerKey, l.Name))
+	}
+	entry := make([]core.Field, 0, len(l.Fields)+len(fields))
+	logger.SetAttributes(routeFields(append(append(entry, l.Fields...), fields...)).attributes()...)
+}
+
+// entryFields returns the bound fields, except the ones kept by the scope, between leading and the
+// fields of the entry.
+func (l *Logger) entryFields(leading, fields []core.Field) []core.Field {
+	entry := make([]core.Field, 0, len(leading)+len(l.Fields)+len(fields))
+	entry = append(entry, leading...)
+	for _, field := range l.Fields {
+		if !isScopeField(field) {
+			entry = append(entry, field)
 		}
-		logger.SetAttributes(attribute.String(key, string(decoded)))
 	}
+	return append(entry, fields...)
 }
 
 func (l *Logger) transform(fields ...core.Field) map[string]interface{} {
@@ -260,26 +491,19 @@ func (l *Logger) getHub(ctx context.Context) *sentry.Hub {
 	return l.Hub
 }
 
-func init() {
-	if os.Getenv(envSentryEventLevel) != "" {
-		if level, err := core.ParseLevel(os.Getenv(envSentryEventLevel)); err == nil {
-			defaultEventLevel = level
-		} else {
-			_, _ = fmt.Fprintf(os.Stderr, "Invalid %s environment value, using default: %s\n", envSentryEventLevel, defaultEventLevel.String())
-		}
-	}
-	if os.Getenv(envSentryLogLevel) != "" {
-		if level, err := core.ParseLevel(os.Getenv(envSentryLogLevel)); err == nil {
-			defaultLogLevel = level
-		} else {
-			_, _ = fmt.Fprintf(os.Stderr, "Invalid %s environment value, using default: %s\n", envSentryLogLevel, defaultLogLevel.String())
-		}
-	}
-	if os.Getenv(envSentryBreadcrumbLevel) != "" {
-		if level, err := core.ParseLevel(os.Getenv(envSentryBreadcrumbLevel)); err == nil {
-			defaultBreadcrumbLevel = level
-		} else {
-			_, _ = fmt.Fprintf(os.Stderr, "Invalid %s environment value, using default: %s\n", envSentryBreadcrumbLevel, defaultBreadcrumbLevel.String())
-		}
+// getTracedHub returns the hub for ctx linked to the OpenTelemetry span active in ctx, if any.
+// The hub is cloned before its propagation context is changed, so the span does not leak into
+// the scope shared by other calls; breadcrumbs must therefore be added through getHub.
+func (l *Logger) getTracedHub(ctx context.Context) *sentry.Hub {
+	hub := l.getHub(ctx)
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return hub
 	}
+	hub = hub.Clone()
+	hub.Scope().SetPropagationContext(sentry.PropagationContext{
+		TraceID: sentry.TraceID(spanContext.TraceID()),
+		SpanID:  sentry.SpanID(spanContext.SpanID()),
+	})
+	return hub
 }
@@ -2,12 +2,18 @@ package sentry
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/ensarkovankaya/go-logging/core"
 )
@@ -125,10 +131,13 @@ func getLoggerForTest(t *testing.T, opts ...Option) (*Logger, *MockTransport) {
 		T:  t,
 		mu: &sync.Mutex{},
 	}
-	hub := Initialize(func(opt *sentry.ClientOptions) {
+	hub, err := Initialize(WithClientOptions(func(opt *sentry.ClientOptions) {
 		opt.Transport = transport
 		opt.Debug = true
-	})
+	}))
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
 	opts = append(opts, func(l *Logger) {
 		l.Hub = hub
 	})
@@ -166,3 +175,385 @@ func getFields() []core.Field {
 		core.F("duration", twoMinutesTwentyThree),
 	}
 }
+
+func TestLogger_TraceContext(t *testing.T) {
+	ctx := context.Background()
+	logger, transport := getLoggerForTest(t, func(l *Logger) {
+		l.EventLevel = core.LevelError
+		l.BreadcrumbLevel = core.LevelDebug
+		l.LogLevel = core.LevelDisabled
+	})
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03},
+		SpanID:     trace.SpanID{0x04, 0x05},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx = trace.ContextWithSpanContext(ctx, spanContext)
+	logger.Info(ctx, "Info message")
+	logger.Error(ctx, "Error message")
+	if err := logger.Flush(ctx); err != nil {
+		t.Errorf("Flush failed: %v", err)
+	}
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	traceContext, ok := events[0].Contexts["trace"]
+	if !ok {
+		t.Fatal("Expected trace context on event")
+	}
+	if traceID := fmt.Sprint(traceContext["trace_id"]); traceID != spanContext.TraceID().String() {
+		t.Errorf("Expected trace_id %s, got %s", spanContext.TraceID().String(), traceID)
+	}
+	if spanID := fmt.Sprint(traceContext["span_id"]); spanID != spanContext.SpanID().String() {
+		t.Errorf("Expected span_id %s, got %s", spanContext.SpanID().String(), spanID)
+	}
+	if len(events[0].Breadcrumbs) != 1 {
+		t.Fatalf("Expected 1 breadcrumb, got %d", len(events[0].Breadcrumbs))
+	}
+	if traceID := events[0].Breadcrumbs[0].Data[core.TraceIDKey]; traceID != spanContext.TraceID().String() {
+		t.Errorf("Expected breadcrumb %s %s, got %v", core.TraceIDKey, spanContext.TraceID().String(), traceID)
+	}
+}
+
+func TestLogger_Fatal(t *testing.T) {
+	ctx := context.Background()
+	logger, transport := getLoggerForTest(t, func(l *Logger) {
+		l.EventLevel = core.LevelPanic
+		l.BreadcrumbLevel = core.LevelTrace
+		l.LogLevel = core.LevelTrace
+	})
+	logger.Trace(ctx, "Trace message")
+	logger.Fatal(ctx, "Fatal message")
+	if err := logger.Flush(ctx); err != nil {
+		t.Errorf("Flush failed: %v", err)
+	}
+	if transport.EventCount != 1 {
+		t.Fatalf("Expected 1 event, got %d", transport.EventCount)
+	}
+	if transport.LogCount != 2 {
+		t.Errorf("Expected 2 logs, got %d", transport.LogCount)
+	}
+	for _, event := range transport.Events() {
+		if event.Type == "" && event.Level != sentry.LevelFatal {
+			t.Errorf("Expected event level %s, got %s", sentry.LevelFatal, event.Level)
+		}
+		for _, log := range event.Logs {
+			if log.Body == "Fatal message" && log.Level != sentry.LogLevelFatal {
+				t.Errorf("Expected log level %s, got %s", sentry.LogLevelFatal, log.Level)
+			}
+		}
+	}
+}
+
+func TestLogger_WithNamedContextHub(t *testing.T) {
+	logger, transport := getLoggerForTest(t, func(l *Logger) {
+		l.EventLevel = core.LevelError
+	})
+	ctx := logger.WithContext(context.Background())
+	logger.Named("billing").With(
+		core.F("order", "42"),
+		core.F("attempt", 3),
+		core.F(core.TagPrefix+"region", "eu"),
+		core.F(core.ContextPrefix+"payment", map[string]any{"provider": "acme"}),
+	).Error(ctx, "Error message", core.F(core.TagPrefix+"region", "us"))
+	if err := logger.Flush(ctx); err != nil {
+		t.Errorf("Flush failed: %v", err)
+	}
+
+	var event *sentry.Event
+	for _, e := range transport.Events() {
+		if e.Type == "" {
+			event = e
+		}
+	}
+	if event == nil {
+		t.Fatal("Expected an error event")
+	}
+	if event.Tags[loggerKey] != "billing" || event.Tags["order"] != "42" || event.Tags["region"] != "us" {
+		t.Errorf("Expected the bound tags with the entry ones first, got %v", event.Tags)
+	}
+	if event.Extra["attempt"] != 3 {
+		t.Errorf("Expected the attempt extra, got %v", event.Extra)
+	}
+	if event.Contexts["payment"]["provider"] != "acme" {
+		t.Errorf("Expected the payment context, got %v", event.Contexts)
+	}
+}
+
+func TestLogger_WithNamedScope(t *testing.T) {
+	ctx := context.Background()
+	logger, transport := getLoggerForTest(t, func(l *Logger) {
+		l.EventLevel = core.LevelError
+		l.BreadcrumbLevel = core.LevelDebug
+		l.LogLevel = core.LevelInfo
+	})
+	request := httptest.NewRequest(http.MethodPost, "https://example.com/orders", nil)
+	derived := logger.
+		With(core.F("tenant", "acme"), core.F("attempt", 3), core.F("user", sentry.User{ID: "42", Email: "jane@example.com"})).
+		Named("worker").
+		With(core.F("request", request))
+	derived.Info(ctx, "Info message")
+	derived.Error(ctx, "Error message")
+	logger.Error(ctx, "Parent message")
+	if err := logger.Flush(ctx); err != nil {
+		t.Errorf("Flush failed: %v", err)
+	}
+
+	var event, parent *sentry.Event
+	logs := make(map[string]sentry.Log)
+	for _, e := range transport.Events() {
+		switch {
+		case e.Type == "" && e.Message == "Error message":
+			event = e
+		case e.Type == "" && e.Message == "Parent message":
+			parent = e
+		}
+		for _, log := range e.Logs {
+			logs[log.Body] = log
+		}
+	}
+	if event == nil || parent == nil {
+		t.Fatalf("Expected the derived and the parent events, got %v", transport.Events())
+	}
+	if event.Tags["tenant"] != "acme" || event.Tags[loggerKey] != "worker" {
+		t.Errorf("Expected the tenant and logger tags, got %v", event.Tags)
+	}
+	if event.Extra["attempt"] != 3 {
+		t.Errorf("Expected the attempt extra, got %v", event.Extra)
+	}
+	if event.User.ID != "42" || event.Request == nil || event.Request.URL != "https://example.com/orders" {
+		t.Errorf("Expected the bound user and request, got %+v and %+v", event.User, event.Request)
+	}
+	if len(event.Breadcrumbs) != 1 || event.Breadcrumbs[0].Data["tenant"] != "acme" || event.Breadcrumbs[0].Data["attempt"] != 3 {
+		t.Errorf("Expected the bound fields on the breadcrumb, got %+v", event.Breadcrumbs)
+	}
+	for _, body := range []string{"Info message", "Error message"} {
+		attributes := logs[body].Attributes
+		if attributes[loggerKey].Value != "worker" || attributes["tenant"].Value != `"acme"` || attributes["user.id"].Value != "42" {
+			t.Errorf("Expected the bound fields on the %q log, got %v", body, attributes)
+		}
+		if _, ok := attributes["request"]; ok {
+			t.Errorf("Expected the request to stay on the scope, got %v", attributes["request"])
+		}
+	}
+	if len(parent.Tags) != 0 || len(parent.Extra) != 0 || parent.User.ID != "" || len(parent.Breadcrumbs) != 0 {
+		t.Errorf("Expected the parent scope to be left untouched, got %+v", parent)
+	}
+}
+
+func TestLogger_RouteFields(t *testing.T) {
+	ctx := context.Background()
+	logger, transport := getLoggerForTest(t, func(l *Logger) {
+		l.EventLevel = core.LevelError
+		l.LogLevel = core.LevelInfo
+	})
+	request := httptest.NewRequest(http.MethodGet, "https://example.com/orders/7", nil)
+	derived := logger.With(core.Tag("region", "eu"), core.UserID("42"), core.Context("tenant", map[string]any{"plan": "pro"}))
+	derived.Error(ctx, "Error message",
+		core.UserEmail("jane@example.com"),
+		core.Request(request),
+		core.Tag("order", "7"),
+		core.F(core.ContextPrefix+"retry", 3),
+		core.F("attempt", 2),
+	)
+	if err := logger.Flush(ctx); err != nil {
+		t.Errorf("Flush failed: %v", err)
+	}
+
+	var event *sentry.Event
+	var log sentry.Log
+	for _, e := range transport.Events() {
+		if e.Type == "" && e.Message == "Error message" {
+			event = e
+		}
+		for _, l := range e.Logs {
+			log = l
+		}
+	}
+	if event == nil {
+		t.Fatalf("Expected the event, got %v", transport.Events())
+	}
+	if event.Tags["region"] != "eu" || event.Tags["order"] != "7" {
+		t.Errorf("Expected the region and order tags, got %v", event.Tags)
+	}
+	if event.User.ID != "42" || event.User.Email != "jane@example.com" {
+		t.Errorf("Expected the bound and the entry user, got %+v", event.User)
+	}
+	if event.Request == nil || event.Request.Method != http.MethodGet || event.Request.URL != "https://example.com/orders/7" {
+		t.Errorf("Expected the request, got %+v", event.Request)
+	}
+	if event.Contexts["tenant"]["plan"] != "pro" || event.Contexts["retry"]["value"] != 3 {
+		t.Errorf("Expected the tenant and retry contexts, got %v", event.Contexts)
+	}
+	if len(event.Extra) != 1 || event.Extra["attempt"] != 2 {
+		t.Errorf("Expected only the attempt extra, got %v", event.Extra)
+	}
+	attributes := log.Attributes
+	if attributes["tag.region"].Value != "eu" || attributes["user.email"].Value != "jane@example.com" || attributes["url.full"].Value != "https://example.com/orders/7" {
+		t.Errorf("Expected the routed fields on the log, got %v", attributes)
+	}
+}
+
+func TestLogger_RateLimit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	logger, transport := getLoggerForTest(t, WithRateLimit(2, time.Minute), func(l *Logger) {
+		l.EventLevel = core.LevelError
+		l.LogLevel = core.LevelDisabled
+		l.NowFunc = func() time.Time { return now }
+	})
+	for i := 0; i < 5; i++ {
+		logger.Error(ctx, "Query failed", core.E(io.EOF))
+	}
+	logger.Error(ctx, "Query failed")
+	logger.Named("db").Error(ctx, "Query failed", core.E(io.EOF))
+	logger.Fatal(ctx, "Query failed", core.E(io.EOF))
+	now = now.Add(time.Minute)
+	logger.Error(ctx, "Query failed", core.E(io.EOF))
+	if err := logger.Flush(ctx); err != nil {
+		t.Errorf("Flush failed: %v", err)
+	}
+
+	events := make([]*sentry.Event, 0)
+	for _, event := range transport.Events() {
+		if event.Type == "" {
+			events = append(events, event)
+		}
+	}
+	// 2 of the 5 repeated events, the event without error, the named and the fatal events and the
+	// event of the next window.
+	if len(events) != 6 {
+		t.Fatalf("Expected 6 events, got %d", len(events))
+	}
+	for _, event := range events[:5] {
+		if _, ok := event.Extra[suppressedKey]; ok {
+			t.Errorf("Expected no suppressed count on %q, got %v", event.Message, event.Extra)
+		}
+	}
+	if events[5].Extra[suppressedKey] != 3 {
+		t.Errorf("Expected 3 suppressed events, got %v", events[5].Extra)
+	}
+}
+
+func TestRateLimiter_Sweep(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(1, time.Minute)
+	for i := 0; i < maxRateKeys; i++ {
+		key := rateKey{level: core.LevelError, message: fmt.Sprintf("message %d", i)}
+		limiter.allow(key, now.Add(time.Duration(i)))
+		if allowed, _ := limiter.allow(key, now.Add(time.Duration(i))); allowed {
+			t.Fatalf("Expected the second event of %q to be suppressed", key.message)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Second)
+		limiter.allow(rateKey{level: core.LevelError, message: fmt.Sprintf("new message %d", i)}, now)
+		if len(limiter.windows) > maxRateKeys {
+			t.Fatalf("Expected at most %d keys, got %d", maxRateKeys, len(limiter.windows))
+		}
+	}
+	if _, ok := limiter.windows[rateKey{level: core.LevelError, message: "message 0"}]; ok {
+		t.Error("Expected the oldest key to be forgotten")
+	}
+
+	now = now.Add(time.Minute)
+	limiter.allow(rateKey{level: core.LevelError, message: "last message"}, now)
+	if len(limiter.windows) != 1 {
+		t.Errorf("Expected the elapsed windows with suppressed events to be forgotten, got %d keys", len(limiter.windows))
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	rollback := fmt.Errorf("rollback: %w", context.Canceled)
+	err := fmt.Errorf("save order: %w", errors.Join(io.EOF, rollback))
+
+	list := exceptions(err, -1)
+	expected := []struct {
+		Type   string
+		ID     int
+		Parent int
+		Source string
+	}{
+		{"*errors.errorString", 4, 3, "cause"},
+		{"*fmt.wrapError", 3, 1, "errors[1]"},
+		{"*errors.errorString", 2, 1, "errors[0]"},
+		{"*errors.joinError", 1, 0, "cause"},
+		{"*fmt.wrapError", 0, -1, ""},
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("Expected %d exceptions, got %+v", len(expected), list)
+	}
+	for i, exception := range list {
+		mechanism := exception.Mechanism
+		parent := -1
+		if mechanism.ParentID != nil {
+			parent = *mechanism.ParentID
+		}
+		if exception.Type != expected[i].Type || mechanism.ExceptionID != expected[i].ID || parent != expected[i].Parent || mechanism.Source != expected[i].Source {
+			t.Errorf("Expected exception %d to be %+v, got %s %+v", i, expected[i], exception.Type, mechanism)
+		}
+	}
+	if !list[3].Mechanism.IsExceptionGroup {
+		t.Error("Expected the joined errors to be an exception group")
+	}
+	if list[len(list)-1].Value != err.Error() {
+		t.Errorf("Expected the outermost error last, got %s", list[len(list)-1].Value)
+	}
+
+	if limited := exceptions(err, 2); len(limited) != 2 || limited[1].Value != err.Error() {
+		t.Errorf("Expected the 2 outermost errors, got %+v", limited)
+	}
+	if single := exceptions(io.EOF, -1); len(single) != 1 || single[0].Mechanism != nil {
+		t.Errorf("Expected a single exception without mechanism, got %+v", single)
+	}
+}
+
+func TestLogger_CaptureException(t *testing.T) {
+	ctx := context.Background()
+	logger, transport := getLoggerForTest(t, WithFingerprints(
+		FingerprintRule{Pattern: "db.*", Fingerprint: []string{FingerprintLogger, FingerprintErrorType}},
+		FingerprintRule{Pattern: "*", Fingerprint: []string{FingerprintDefault, FingerprintErrorType}},
+	))
+	err := fmt.Errorf("query orders: %w", io.ErrUnexpectedEOF)
+	logger.Named("db").Named("orders").Error(ctx, "Query failed", core.E(err), core.F("table", "orders"))
+	logger.With(core.E(io.EOF)).Error(ctx, "Read failed")
+	logger.Error(ctx, "No error")
+	if err := logger.Flush(ctx); err != nil {
+		t.Errorf("Flush failed: %v", err)
+	}
+
+	events := make([]*sentry.Event, 0, 3)
+	for _, event := range transport.Events() {
+		if event.Type == "" {
+			events = append(events, event)
+		}
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	query := events[0]
+	if len(query.Exception) != 2 || query.Exception[1].Value != err.Error() || query.Exception[0].Value != io.ErrUnexpectedEOF.Error() {
+		t.Fatalf("Expected the error chain as exceptions, got %+v", query.Exception)
+	}
+	if _, ok := query.Extra["error"]; ok || query.Extra["table"] != "orders" {
+		t.Errorf("Expected the error field to leave the extras, got %v", query.Extra)
+	}
+	stacktrace := query.Exception[1].Stacktrace
+	if stacktrace == nil || len(stacktrace.Frames) == 0 {
+		t.Fatal("Expected the call site stack trace on the outermost exception")
+	}
+	if caller := stacktrace.Frames[len(stacktrace.Frames)-1]; caller.Function != "TestLogger_CaptureException" {
+		t.Errorf("Expected the stack trace to end at the caller, got %s.%s", caller.Module, caller.Function)
+	}
+	if fingerprint := strings.Join(query.Fingerprint, ","); fingerprint != "db.orders,*fmt.wrapError" {
+		t.Errorf("Expected the db fingerprint, got %s", fingerprint)
+	}
+
+	if read := events[1]; len(read.Exception) != 1 || read.Exception[0].Type != "*errors.errorString" {
+		t.Errorf("Expected the bound error as exception, got %+v", read.Exception)
+	}
+	if fingerprint := strings.Join(events[2].Fingerprint, ","); fingerprint != FingerprintDefault || len(events[2].Exception) != 0 {
+		t.Errorf("Expected the default fingerprint without exception, got %s and %+v", fingerprint, events[2].Exception)
+	}
+}
@@ -0,0 +1,132 @@
+package sentry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+var (
+	defaultRateLimit            = 0
+	defaultRateLimitWindow      = time.Minute
+	defaultRateLimitBypassLevel = core.LevelPanic
+)
+
+var (
+	envSentryRateLimit            = "SENTRY_RATE_LIMIT"
+	envSentryRateLimitWindow      = "SENTRY_RATE_LIMIT_WINDOW"
+	envSentryRateLimitBypassLevel = "SENTRY_RATE_LIMIT_BYPASS_LEVEL"
+)
+
+// suppressedKey is the extra holding the number of events suppressed since the previous event of
+// the same key was sent.
+const suppressedKey = "suppressed_events"
+
+// maxRateKeys is the maximum number of keys a RateLimiter keeps.
+const maxRateKeys = 1024
+
+// RateLimiter limits the events sent per key, the level, message, logger name and error type of
+// an event, to Limit per Window. The suppressed events are counted and the count is attached to
+// the next event of the key that is sent. Events at BypassLevel and above are never suppressed.
+//
+// At most maxRateKeys keys are kept. Once reached, the keys whose window elapsed are forgotten, or
+// the key of the oldest window when none elapsed, together with their suppressed count.
+//
+// A RateLimiter is shared by every logger derived from the same New call.
+type RateLimiter struct {
+	Limit       int
+	Window      time.Duration
+	BypassLevel core.Level
+
+	mu      sync.Mutex
+	windows map[rateKey]*rateWindow
+}
+
+type rateKey struct {
+	level     core.Level
+	name      string
+	message   string
+	errorType string
+}
+
+type rateWindow struct {
+	start      time.Time
+	sent       int
+	suppressed int
+}
+
+// NewRateLimiter returns a RateLimiter sending limit events per window, a non-positive window
+// defaults to one minute.
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	if window <= 0 {
+		window = defaultRateLimitWindow
+	}
+	return &RateLimiter{
+		Limit:       limit,
+		Window:      window,
+		BypassLevel: defaultRateLimitBypassLevel,
+		windows:     make(map[rateKey]*rateWindow),
+	}
+}
+
+// WithRateLimit limits the events sent per key to limit per window, see RateLimiter. A limit of
+// zero sends every event.
+func WithRateLimit(limit int, window time.Duration) Option {
+	return func(l *Logger) {
+		if limit <= 0 {
+			l.RateLimiter = nil
+			return
+		}
+		l.RateLimiter = NewRateLimiter(limit, window)
+	}
+}
+
+// allow reports whether an event of key may be sent at now, and if so the number of events of key
+// suppressed since the previous one was sent.
+func (r *RateLimiter) allow(key rateKey, now time.Time) (bool, int) {
+	if r.BypassLevel.Enabled(key.level) {
+		return true, 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.windows == nil {
+		r.windows = make(map[rateKey]*rateWindow)
+	}
+	window, ok := r.windows[key]
+	if !ok {
+		r.sweep(now)
+		window = &rateWindow{start: now}
+		r.windows[key] = window
+	} else if now.Sub(window.start) >= r.Window {
+		window.start, window.sent = now, 0
+	}
+	if window.sent >= r.Limit {
+		window.suppressed++
+		return false, 0
+	}
+	window.sent++
+	suppressed := window.suppressed
+	window.suppressed = 0
+	return true, suppressed
+}
+
+// sweep makes room for a new key when there are maxRateKeys keys by forgetting the keys whose
+// window elapsed, or the key of the oldest window when none elapsed. The suppressed events of the
+// forgotten keys are no longer reported.
+func (r *RateLimiter) sweep(now time.Time) {
+	if len(r.windows) < maxRateKeys {
+		return
+	}
+	var oldest *rateKey
+	for key, window := range r.windows {
+		if now.Sub(window.start) >= r.Window {
+			delete(r.windows, key)
+		} else if oldest == nil || window.start.Before(r.windows[*oldest].start) {
+			oldest = &key
+		}
+	}
+	if len(r.windows) >= maxRateKeys && oldest != nil {
+		delete(r.windows, *oldest)
+	}
+}
@@ -0,0 +1,238 @@
+package sentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/getsentry/sentry-go/attribute"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+// routedFields holds the fields of an entry by their destination in Sentry.
+type routedFields struct {
+	tags     map[string]string
+	user     sentry.User
+	request  *http.Request
+	contexts map[string]sentry.Context
+	errors   []core.Field
+	extra    []core.Field
+}
+
+// routeFields sorts the fields by destination, later fields override earlier ones:
+//   - error values are reported as exceptions,
+//   - sentry.User values and the core.UserIDKey, core.UserEmailKey and core.UserNameKey keys set
+//     the user,
+//   - *http.Request values set the request,
+//   - the keys prefixed with core.TagPrefix are tags and the ones prefixed with core.ContextPrefix
+//     contexts,
+//   - the other fields are extras.
+func routeFields(fields []core.Field) routedFields {
+	var routed routedFields
+	for _, field := range fields {
+		switch value := field.Value.(type) {
+		case error:
+			routed.errors = append(routed.errors, field)
+			continue
+		case sentry.User:
+			mergeUser(&routed.user, value)
+			continue
+		case *http.Request:
+			if value != nil {
+				routed.request = value
+				continue
+			}
+		}
+		switch {
+		case field.Key == core.UserIDKey:
+			routed.user.ID = stringValue(field.Value)
+		case field.Key == core.UserEmailKey:
+			routed.user.Email = stringValue(field.Value)
+		case field.Key == core.UserNameKey:
+			routed.user.Name = stringValue(field.Value)
+		case strings.HasPrefix(field.Key, core.TagPrefix) && len(field.Key) > len(core.TagPrefix):
+			if routed.tags == nil {
+				routed.tags = make(map[string]string)
+			}
+			routed.tags[strings.TrimPrefix(field.Key, core.TagPrefix)] = stringValue(field.Value)
+		case strings.HasPrefix(field.Key, core.ContextPrefix) && len(field.Key) > len(core.ContextPrefix):
+			if routed.contexts == nil {
+				routed.contexts = make(map[string]sentry.Context)
+			}
+			routed.contexts[strings.TrimPrefix(field.Key, core.ContextPrefix)] = contextValue(field.Value)
+		default:
+			routed.extra = append(routed.extra, field)
+		}
+	}
+	return routed
+}
+
+// bind sets the routed fields on scope, string extras as tags. user is the user of all the bound
+// fields, since the scope replaces its user as a whole.
+func (r routedFields) bind(scope *sentry.Scope, user sentry.User) {
+	for key, value := range r.tags {
+		scope.SetTag(key, value)
+	}
+	for name, context := range r.contexts {
+		scope.SetContext(name, context)
+	}
+	if r.request != nil {
+		scope.SetRequest(r.request)
+	}
+	if !user.IsEmpty() {
+		scope.SetUser(user)
+	}
+	for _, field := range r.extra {
+		if value, ok := field.Value.(string); ok {
+			scope.SetTag(field.Key, value)
+		} else {
+			scope.SetExtra(field.Key, field.Value)
+		}
+	}
+}
+
+// apply sets the routed fields on event like bind sets them on a scope, without overriding the
+// values already set on event. name is set as the logger tag when not empty.
+func (r routedFields) apply(event *sentry.Event, name string) {
+	setTag := func(key, value string) {
+		if event.Tags == nil {
+			event.Tags = make(map[string]string)
+		}
+		if _, ok := event.Tags[key]; !ok {
+			event.Tags[key] = value
+		}
+	}
+	for key, value := range r.tags {
+		setTag(key, value)
+	}
+	for name, context := range r.contexts {
+		if event.Contexts == nil {
+			event.Contexts = make(map[string]sentry.Context)
+		}
+		if _, ok := event.Contexts[name]; !ok {
+			event.Contexts[name] = context
+		}
+	}
+	if event.Request == nil && r.request != nil {
+		event.Request = sentry.NewRequest(r.request)
+	}
+	for _, field := range r.extra {
+		if value, ok := field.Value.(string); ok {
+			setTag(field.Key, value)
+		} else if _, ok = event.Extra[field.Key]; !ok {
+			if event.Extra == nil {
+				event.Extra = make(map[string]any)
+			}
+			event.Extra[field.Key] = field.Value
+		}
+	}
+	if name != "" {
+		setTag(loggerKey, name)
+	}
+}
+
+// attributes returns the log attributes of the routed fields. The user and the request are named
+// after the Sentry log conventions, other values are JSON encoded.
+func (r routedFields) attributes() []attribute.Builder {
+	attributes := make([]attribute.Builder, 0, len(r.tags)+len(r.contexts)+len(r.errors)+len(r.extra)+5)
+	if r.user.ID != "" {
+		attributes = append(attributes, attribute.String("user.id", r.user.ID))
+	}
+	if r.user.Email != "" {
+		attributes = append(attributes, attribute.String("user.email", r.user.Email))
+	}
+	if r.user.Name != "" {
+		attributes = append(attributes, attribute.String("user.name", r.user.Name))
+	}
+	if r.request != nil {
+		attributes = append(attributes,
+			attribute.String("http.request.method", r.request.Method),
+			attribute.String("url.full", r.request.URL.String()),
+		)
+	}
+	for key, value := range r.tags {
+		attributes = append(attributes, attribute.String(core.TagPrefix+key, value))
+	}
+	for name, context := range r.contexts {
+		attributes = append(attributes, attribute.String(core.ContextPrefix+name, jsonValue(context)))
+	}
+	for _, field := range r.errors {
+		attributes = append(attributes, attribute.String(field.Key, field.Value.(error).Error()))
+	}
+	for _, field := range r.extra {
+		attributes = append(attributes, attribute.String(field.Key, jsonValue(field.Value)))
+	}
+	return attributes
+}
+
+// isScopeField reports whether a bound field is kept by the scope rather than written with the
+// fields of breadcrumbs.
+func isScopeField(field core.Field) bool {
+	switch value := field.Value.(type) {
+	case sentry.User:
+		return true
+	case *http.Request:
+		return value != nil
+	}
+	switch field.Key {
+	case core.UserIDKey, core.UserEmailKey, core.UserNameKey:
+		return true
+	}
+	return strings.HasPrefix(field.Key, core.ContextPrefix)
+}
+
+// mergeUser sets the non-empty attributes of src on dst.
+func mergeUser(dst *sentry.User, src sentry.User) {
+	if src.ID != "" {
+		dst.ID = src.ID
+	}
+	if src.Email != "" {
+		dst.Email = src.Email
+	}
+	if src.IPAddress != "" {
+		dst.IPAddress = src.IPAddress
+	}
+	if src.Username != "" {
+		dst.Username = src.Username
+	}
+	if src.Name != "" {
+		dst.Name = src.Name
+	}
+	for key, value := range src.Data {
+		if dst.Data == nil {
+			dst.Data = make(map[string]string, len(src.Data))
+		}
+		dst.Data[key] = value
+	}
+}
+
+func stringValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func contextValue(value any) sentry.Context {
+	switch v := value.(type) {
+	case map[string]any:
+		return v
+	default:
+		return sentry.Context{"value": v}
+	}
+}
+
+func jsonValue(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("[decode error]: %v", err)
+	}
+	return string(encoded)
+}
@@ -1,9 +1,12 @@
 package sentry
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/getsentry/sentry-go"
@@ -13,59 +16,376 @@ import (
 
 type ClientOption = func(*sentry.ClientOptions)
 
+// ConfigOption customises the Config read from the environment, see Initialize.
+type ConfigOption = func(*Config)
+
+// BeforeSendFunc is called before an event is sent, the event is dropped when it returns nil.
+type BeforeSendFunc = func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event
+
 var FLushTimeout = time.Second * 5
 
-func Initialize(opts ...ClientOption) *sentry.Hub {
-	options := &sentry.ClientOptions{
-		Dsn:         os.Getenv("SENTRY_DSN"),
-		ServerName:  os.Getenv("APP_NAME"),
-		Environment: core.ReadEnvironment(),
-	}
-	options.EnableLogs, _ = core.ParseBool("SENTRY_ENABLE_LOGS", true, false)
-	options.Debug, _ = core.ParseBool("SENTRY_DEBUG", false, false)
-	options.AttachStacktrace, _ = core.ParseBool("SENTRY_ATTACH_STACKTRACE", true, false)
-	options.EnableTracing, _ = core.ParseBool("SENTRY_ENABLE_TRACING", true, false)
-	if os.Getenv("SENTRY_SAMPLE_RATE") != "" {
-		rate, err := strconv.ParseFloat(os.Getenv("SENTRY_SAMPLE_RATE"), 64)
-		if err != nil {
-			options.SampleRate = rate
+var (
+	envSentryDSN              = "SENTRY_DSN"
+	envAppName                = "APP_NAME"
+	envSentryEnableLogs       = "SENTRY_ENABLE_LOGS"
+	envSentryDebug            = "SENTRY_DEBUG"
+	envSentryAttachStacktrace = "SENTRY_ATTACH_STACKTRACE"
+	envSentryEnableTracing    = "SENTRY_ENABLE_TRACING"
+	envSentrySampleRate       = "SENTRY_SAMPLE_RATE"
+	envSentryTraceSampleRate  = "SENTRY_TRACE_SAMPLE_RATE"
+	envSentryMaxBreadcrumbs   = "SENTRY_MAX_BREADCRUMBS"
+	envSentryFlushTimeout     = "SENTRY_FLUSH_TIMEOUT"
+)
+
+// Config holds the settings of the Sentry client and of the loggers created by New. Validation
+// errors name the environment variable of the setting, wherever its value came from, except the
+// ones of IgnoreErrors and InAppPrefixes, which have no variable and are named after their field.
+type Config struct {
+	DSN              string
+	ServerName       string
+	Environment      string
+	EnableLogs       bool
+	Debug            bool
+	AttachStacktrace bool
+	EnableTracing    bool
+	// SampleRate is the rate of the events sent, between 0 and 1, 0 sends every event.
+	SampleRate float64
+	// TracesSampleRate is the rate of the transactions sent, between 0 and 1.
+	TracesSampleRate float64
+	// MaxBreadcrumbs is the number of breadcrumbs kept by the scope, 0 keeps the Sentry default.
+	MaxBreadcrumbs int
+	// BeforeSend hooks are called in order before an event is sent, the event is dropped as soon
+	// as a hook returns nil.
+	BeforeSend []BeforeSendFunc
+	// IgnoreErrors are regular expressions, the events whose message or error matches one of them
+	// are dropped.
+	IgnoreErrors []string
+	// InAppPrefixes are the import path prefixes of the application packages. When set, only the
+	// stack frames of these packages are marked as in-app, Sentry guesses them otherwise.
+	InAppPrefixes []string
+	// Options are applied to the client options built from the configuration, in order.
+	Options []ClientOption
+
+	// The following settings are the defaults of the loggers created by New and are not used by
+	// the client, see the Logger fields of the same name.
+	FlushTimeout    time.Duration
+	LogLevel        core.Level
+	EventLevel      core.Level
+	BreadcrumbLevel core.Level
+	// RateLimit is the number of events sent per key and RateLimitWindow, 0 sends every event,
+	// see RateLimiter.
+	RateLimit            int
+	RateLimitWindow      time.Duration
+	RateLimitBypassLevel core.Level
+}
+
+// DefaultConfig returns the Config used for the environment variables that are not set.
+func DefaultConfig() *Config {
+	return &Config{
+		EnableLogs:           true,
+		AttachStacktrace:     true,
+		EnableTracing:        true,
+		FlushTimeout:         FLushTimeout,
+		LogLevel:             defaultLogLevel,
+		EventLevel:           defaultEventLevel,
+		BreadcrumbLevel:      defaultBreadcrumbLevel,
+		RateLimit:            defaultRateLimit,
+		RateLimitWindow:      defaultRateLimitWindow,
+		RateLimitBypassLevel: defaultRateLimitBypassLevel,
+	}
+}
+
+// ReadConfig returns the Config set by the SENTRY_* environment variables, the error reports every
+// variable that cannot be parsed. The variables that cannot be parsed keep their DefaultConfig
+// value.
+func ReadConfig() (*Config, error) {
+	cfg := DefaultConfig()
+	cfg.DSN = os.Getenv(envSentryDSN)
+	cfg.ServerName = os.Getenv(envAppName)
+	cfg.Environment = core.ReadEnvironment()
+	errs := make([]error, 0)
+	parse := func(env string, set func(value string) error) {
+		value := os.Getenv(env)
+		if value == "" {
+			return
 		}
+		if err := set(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q: %w", env, value, err))
+		}
+	}
+	parseBool := func(target *bool, env string) {
+		parse(env, func(value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err == nil {
+				*target = enabled
+			}
+			return err
+		})
+	}
+	parseLevel := func(target *core.Level, env string) {
+		parse(env, func(value string) error {
+			level, err := core.ParseLevel(value)
+			if err == nil {
+				*target = level
+			}
+			return err
+		})
 	}
-	if os.Getenv("SENTRY_TRACE_SAMPLE_RATE") != "" {
-		rate, err := strconv.ParseFloat(os.Getenv("SENTRY_TRACE_SAMPLE_RATE"), 64)
-		if err != nil {
-			options.TracesSampleRate = rate
+	parseDuration := func(target *time.Duration, env string) {
+		parse(env, func(value string) error {
+			duration, err := time.ParseDuration(value)
+			if err == nil {
+				*target = duration
+			}
+			return err
+		})
+	}
+	parseBool(&cfg.EnableLogs, envSentryEnableLogs)
+	parseBool(&cfg.Debug, envSentryDebug)
+	parseBool(&cfg.AttachStacktrace, envSentryAttachStacktrace)
+	parseBool(&cfg.EnableTracing, envSentryEnableTracing)
+	parse(envSentrySampleRate, func(value string) error {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			cfg.SampleRate = rate
+		}
+		return err
+	})
+	parse(envSentryTraceSampleRate, func(value string) error {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			cfg.TracesSampleRate = rate
 		}
+		return err
+	})
+	parse(envSentryMaxBreadcrumbs, func(value string) error {
+		breadcrumbs, err := strconv.Atoi(value)
+		if err == nil {
+			cfg.MaxBreadcrumbs = breadcrumbs
+		}
+		return err
+	})
+	parseDuration(&cfg.FlushTimeout, envSentryFlushTimeout)
+	parseLevel(&cfg.LogLevel, envSentryLogLevel)
+	parseLevel(&cfg.EventLevel, envSentryEventLevel)
+	parseLevel(&cfg.BreadcrumbLevel, envSentryBreadcrumbLevel)
+	parse(envSentryRateLimit, func(value string) error {
+		limit, err := strconv.Atoi(value)
+		if err == nil {
+			cfg.RateLimit = limit
+		}
+		return err
+	})
+	parseDuration(&cfg.RateLimitWindow, envSentryRateLimitWindow)
+	parseLevel(&cfg.RateLimitBypassLevel, envSentryRateLimitBypassLevel)
+	return cfg, errors.Join(errs...)
+}
+
+// Validate reports every invalid value of the configuration.
+func (c *Config) Validate() error {
+	return c.validate(nil)
+}
+
+// validate reports every invalid value of the configuration. When defaults is not nil, the invalid
+// values are replaced with the ones of defaults, and the invalid patterns and prefixes are removed.
+func (c *Config) validate(defaults *Config) error {
+	errs := make([]error, 0)
+	check := func(name string, err error, fallback func()) bool {
+		if err == nil {
+			return true
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		if defaults != nil && fallback != nil {
+			fallback()
+		}
+		return false
+	}
+	if c.DSN != "" {
+		_, err := sentry.NewDsn(c.DSN)
+		check(envSentryDSN, err, func() { c.DSN = defaults.DSN })
+	}
+	check(envSentrySampleRate, validateRate(c.SampleRate), func() { c.SampleRate = defaults.SampleRate })
+	check(envSentryTraceSampleRate, validateRate(c.TracesSampleRate), func() { c.TracesSampleRate = defaults.TracesSampleRate })
+	if c.MaxBreadcrumbs < 0 {
+		err := fmt.Errorf("must not be negative, got %d", c.MaxBreadcrumbs)
+		check(envSentryMaxBreadcrumbs, err, func() { c.MaxBreadcrumbs = defaults.MaxBreadcrumbs })
 	}
-	if os.Getenv("SENTRY_MAX_BREADCRUMBS") != "" {
-		rate, err := strconv.ParseInt(os.Getenv("SENTRY_MAX_BREADCRUMBS"), 10, 64)
-		if err != nil {
-			options.MaxBreadcrumbs = int(rate)
+	if c.FlushTimeout <= 0 {
+		err := fmt.Errorf("must be positive, got %v", c.FlushTimeout)
+		check(envSentryFlushTimeout, err, func() { c.FlushTimeout = defaults.FlushTimeout })
+	}
+	if c.RateLimit < 0 {
+		err := fmt.Errorf("must not be negative, got %d", c.RateLimit)
+		check(envSentryRateLimit, err, func() { c.RateLimit = defaults.RateLimit })
+	}
+	if c.RateLimitWindow <= 0 {
+		err := fmt.Errorf("must be positive, got %v", c.RateLimitWindow)
+		check(envSentryRateLimitWindow, err, func() { c.RateLimitWindow = defaults.RateLimitWindow })
+	}
+	patterns := make([]string, 0, len(c.IgnoreErrors))
+	for i, pattern := range c.IgnoreErrors {
+		if _, err := regexp.Compile(pattern); check(fmt.Sprintf("IgnoreErrors[%d]", i), err, nil) {
+			patterns = append(patterns, pattern)
 		}
 	}
-	for _, opt := range opts {
-		opt(options)
+	prefixes := make([]string, 0, len(c.InAppPrefixes))
+	for i, prefix := range c.InAppPrefixes {
+		if prefix == "" {
+			check(fmt.Sprintf("InAppPrefixes[%d]", i), errors.New("must not be empty"), nil)
+		} else {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if defaults != nil {
+		c.IgnoreErrors, c.InAppPrefixes = patterns, prefixes
+	}
+	return errors.Join(errs...)
+}
+
+// ClientOptions returns the client options of the configuration.
+func (c *Config) ClientOptions() sentry.ClientOptions {
+	options := sentry.ClientOptions{
+		Dsn:              c.DSN,
+		ServerName:       c.ServerName,
+		Environment:      c.Environment,
+		EnableLogs:       c.EnableLogs,
+		Debug:            c.Debug,
+		AttachStacktrace: c.AttachStacktrace,
+		EnableTracing:    c.EnableTracing,
+		SampleRate:       c.SampleRate,
+		TracesSampleRate: c.TracesSampleRate,
+		MaxBreadcrumbs:   c.MaxBreadcrumbs,
+		IgnoreErrors:     c.IgnoreErrors,
+	}
+	hooks := c.BeforeSend
+	if len(c.InAppPrefixes) > 0 {
+		hooks = append([]BeforeSendFunc{markInApp(c.InAppPrefixes)}, hooks...)
+	}
+	if len(hooks) > 0 {
+		options.BeforeSend = func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			for _, hook := range hooks {
+				if event = hook(event, hint); event == nil {
+					return nil
+				}
+			}
+			return event
+		}
+	}
+	for _, opt := range c.Options {
+		opt(&options)
 	}
-	client, err := sentry.NewClient(*options)
+	return options
+}
+
+// NewHub validates the configuration and returns a hub with a client built from it.
+func (c *Config) NewHub() (*sentry.Hub, error) {
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid Sentry configuration: %w", err)
+	}
+	return c.newHub()
+}
+
+// newHub returns a hub with a client built from the configuration without validating it.
+func (c *Config) newHub() (*sentry.Hub, error) {
+	client, err := sentry.NewClient(c.ClientOptions())
 	if err != nil {
-		panic(err)
-	}
-	hub := sentry.NewHub(client, sentry.NewScope())
-	return hub
-}
-
-func init() {
-	if os.Getenv("SENTRY_FLUSH_TIMEOUT") != "" {
-		timeout, err := time.ParseDuration(os.Getenv("SENTRY_FLUSH_TIMEOUT"))
-		if err != nil {
-			_, _ = fmt.Fprintf(
-				os.Stderr,
-				"Invalid SENTRY_FLUSH_TIMEOUT value: %s, using default %v\n",
-				os.Getenv("SENTRY_FLUSH_TIMEOUT"),
-				FLushTimeout,
-			)
-		} else {
-			FLushTimeout = timeout
+		return nil, fmt.Errorf("failed to create Sentry client: %w", err)
+	}
+	return sentry.NewHub(client, sentry.NewScope()), nil
+}
+
+// disabledHub returns a hub whose client sends nothing.
+func disabledHub() *sentry.Hub {
+	client, _ := sentry.NewClient(sentry.ClientOptions{})
+	return sentry.NewHub(client, sentry.NewScope())
+}
+
+// Initialize returns a hub configured by the SENTRY_* environment variables and the options, the
+// error reports every variable that cannot be parsed and every invalid value.
+//
+// Initialize used to take ClientOption, pass them with WithClientOptions or use InitializeClient.
+func Initialize(opts ...ConfigOption) (*sentry.Hub, error) {
+	cfg, err := ReadConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if err = errors.Join(err, cfg.Validate()); err != nil {
+		return nil, fmt.Errorf("invalid Sentry configuration: %w", err)
+	}
+	return cfg.newHub()
+}
+
+// InitializeClient returns a hub configured by the SENTRY_* environment variables and the client
+// options, see Initialize.
+func InitializeClient(opts ...ClientOption) (*sentry.Hub, error) {
+	return Initialize(WithClientOptions(opts...))
+}
+
+// WithBeforeSend adds a hook called before an event is sent, see Config.BeforeSend.
+func WithBeforeSend(hook BeforeSendFunc) ConfigOption {
+	return func(c *Config) {
+		c.BeforeSend = append(c.BeforeSend, hook)
+	}
+}
+
+// WithIgnoreErrors drops the events whose message or error matches one of the patterns.
+func WithIgnoreErrors(patterns ...string) ConfigOption {
+	return func(c *Config) {
+		c.IgnoreErrors = append(c.IgnoreErrors, patterns...)
+	}
+}
+
+// WithInAppPrefixes marks the stack frames of the packages with the prefixes as in-app.
+func WithInAppPrefixes(prefixes ...string) ConfigOption {
+	return func(c *Config) {
+		c.InAppPrefixes = append(c.InAppPrefixes, prefixes...)
+	}
+}
+
+// WithClientOptions customises the client options built from the configuration.
+func WithClientOptions(opts ...ClientOption) ConfigOption {
+	return func(c *Config) {
+		c.Options = append(c.Options, opts...)
+	}
+}
+
+// markInApp returns a hook marking the stack frames of the packages with the prefixes as in-app
+// and the other frames as not in-app.
+func markInApp(prefixes []string) BeforeSendFunc {
+	return func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+		mark := func(stacktrace *sentry.Stacktrace) {
+			if stacktrace == nil {
+				return
+			}
+			for i := range stacktrace.Frames {
+				frame := &stacktrace.Frames[i]
+				frame.InApp = hasPrefix(frame.Module, prefixes)
+			}
 		}
+		for i := range event.Exception {
+			mark(event.Exception[i].Stacktrace)
+		}
+		for i := range event.Threads {
+			mark(event.Threads[i].Stacktrace)
+		}
+		return event
+	}
+}
+
+// hasPrefix reports whether the import path is one of the prefixes or a package below one.
+func hasPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func validateRate(rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("must be between 0 and 1, got %v", rate)
 	}
+	return nil
 }
@@ -0,0 +1,210 @@
+package sentry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+func TestReadConfig(t *testing.T) {
+	t.Setenv("SENTRY_SAMPLE_RATE", "0.25")
+	t.Setenv("SENTRY_TRACE_SAMPLE_RATE", "0.5")
+	t.Setenv("SENTRY_MAX_BREADCRUMBS", "20")
+	t.Setenv("SENTRY_ENABLE_LOGS", "false")
+	cfg, err := ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig failed: %v", err)
+	}
+	if cfg.SampleRate != 0.25 || cfg.TracesSampleRate != 0.5 || cfg.MaxBreadcrumbs != 20 {
+		t.Errorf("Expected the rates and breadcrumbs of the environment, got %+v", cfg)
+	}
+	if cfg.EnableLogs || !cfg.AttachStacktrace || !cfg.EnableTracing {
+		t.Errorf("Expected logs disabled and the other defaults, got %+v", cfg)
+	}
+
+	t.Setenv("SENTRY_FLUSH_TIMEOUT", "2s")
+	t.Setenv("SENTRY_EVENT_LEVEL", "warning")
+	t.Setenv("SENTRY_RATE_LIMIT", "10")
+	t.Setenv("SENTRY_RATE_LIMIT_BYPASS_LEVEL", "fatal")
+	if cfg, err = ReadConfig(); err != nil {
+		t.Fatalf("ReadConfig failed: %v", err)
+	}
+	if cfg.FlushTimeout != 2*time.Second || cfg.EventLevel != core.LevelWarning || cfg.LogLevel != core.LevelInfo {
+		t.Errorf("Expected the flush timeout and levels of the environment, got %+v", cfg)
+	}
+	if cfg.RateLimit != 10 || cfg.RateLimitWindow != time.Minute || cfg.RateLimitBypassLevel != core.LevelFatal {
+		t.Errorf("Expected the rate limit of the environment, got %+v", cfg)
+	}
+
+	t.Setenv("SENTRY_SAMPLE_RATE", "half")
+	t.Setenv("SENTRY_MAX_BREADCRUMBS", "many")
+	t.Setenv("SENTRY_DEBUG", "maybe")
+	t.Setenv("SENTRY_ATTACH_STACKTRACE", "maybe")
+	t.Setenv("SENTRY_FLUSH_TIMEOUT", "soon")
+	t.Setenv("SENTRY_LOG_LEVEL", "loud")
+	t.Setenv("SENTRY_RATE_LIMIT_WINDOW", "a while")
+	cfg, err = ReadConfig()
+	if err == nil {
+		t.Fatal("Expected parse error")
+	}
+	envs := []string{
+		"SENTRY_SAMPLE_RATE",
+		"SENTRY_MAX_BREADCRUMBS",
+		"SENTRY_DEBUG",
+		"SENTRY_ATTACH_STACKTRACE",
+		"SENTRY_FLUSH_TIMEOUT",
+		"SENTRY_LOG_LEVEL",
+		"SENTRY_RATE_LIMIT_WINDOW",
+	}
+	for _, env := range envs {
+		if !strings.Contains(err.Error(), env+":") {
+			t.Errorf("Expected an error for %s, got: %v", env, err)
+		}
+	}
+	if !cfg.AttachStacktrace || cfg.FlushTimeout != FLushTimeout || cfg.LogLevel != core.LevelInfo || cfg.RateLimitWindow != time.Minute {
+		t.Errorf("Expected the defaults of the invalid variables, got %+v", cfg)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := &Config{
+		DSN:              "not-a-dsn",
+		SampleRate:       2,
+		TracesSampleRate: -1,
+		MaxBreadcrumbs:   -1,
+		IgnoreErrors:     []string{"timeout", "("},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	names := []string{
+		"SENTRY_DSN",
+		"SENTRY_SAMPLE_RATE",
+		"SENTRY_TRACE_SAMPLE_RATE",
+		"SENTRY_MAX_BREADCRUMBS",
+		"IgnoreErrors[1]",
+	}
+	for _, name := range names {
+		if !strings.Contains(err.Error(), name+":") {
+			t.Errorf("Expected an error for %s, got: %v", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "IgnoreErrors[0]") {
+		t.Errorf("Expected the valid pattern to pass, got: %v", err)
+	}
+	if _, err := cfg.NewHub(); err == nil {
+		t.Error("Expected NewHub to return the validation error")
+	}
+}
+
+func TestInitialize_Errors(t *testing.T) {
+	t.Setenv("SENTRY_SAMPLE_RATE", "half")
+	t.Setenv("SENTRY_RATE_LIMIT", "-1")
+	_, err := Initialize(WithIgnoreErrors("("))
+	if err == nil {
+		t.Fatal("Expected Initialize to fail")
+	}
+	for _, name := range []string{"SENTRY_SAMPLE_RATE", "SENTRY_RATE_LIMIT", "IgnoreErrors[0]"} {
+		if !strings.Contains(err.Error(), name+":") {
+			t.Errorf("Expected an error for %s, got: %v", name, err)
+		}
+	}
+
+	t.Setenv("SENTRY_SAMPLE_RATE", "0.5")
+	t.Setenv("SENTRY_RATE_LIMIT", "")
+	transport := &MockTransport{T: t, mu: &sync.Mutex{}}
+	hub, err := InitializeClient(func(options *sentry.ClientOptions) {
+		options.Transport = transport
+	})
+	if err != nil {
+		t.Fatalf("InitializeClient failed: %v", err)
+	}
+	if options := hub.Client().Options(); options.Transport != transport || options.SampleRate != 0.5 {
+		t.Errorf("Expected the client options and the sample rate of the environment, got %+v", options)
+	}
+}
+
+func TestNew_InvalidEnvironment(t *testing.T) {
+	dsn := "https://public@sentry.example.com/1"
+	t.Setenv("SENTRY_DSN", dsn)
+	t.Setenv("SENTRY_SAMPLE_RATE", "2")
+	t.Setenv("SENTRY_TRACE_SAMPLE_RATE", "0.5")
+	t.Setenv("SENTRY_EVENT_LEVEL", "loud")
+	t.Setenv("SENTRY_LOG_LEVEL", "warning")
+	t.Setenv("SENTRY_FLUSH_TIMEOUT", "-1s")
+	t.Setenv("SENTRY_RATE_LIMIT", "3")
+	logger := New()
+	options := logger.Hub.Client().Options()
+	if options.Dsn != dsn || options.SampleRate != 1 || options.TracesSampleRate != 0.5 {
+		t.Errorf("Expected Sentry enabled with the valid variables, got %+v", options)
+	}
+	if logger.EventLevel != core.LevelError || logger.LogLevel != core.LevelWarning {
+		t.Errorf("Expected the default event level and the log level of the environment, got %s and %s", logger.EventLevel, logger.LogLevel)
+	}
+	if logger.FlushTimeout != FLushTimeout {
+		t.Errorf("Expected the default flush timeout, got %v", logger.FlushTimeout)
+	}
+	if logger.RateLimiter == nil || logger.RateLimiter.Limit != 3 {
+		t.Errorf("Expected the rate limit of the environment, got %+v", logger.RateLimiter)
+	}
+}
+
+func TestInitialize_Options(t *testing.T) {
+	ctx := context.Background()
+	transport := &MockTransport{T: t, mu: &sync.Mutex{}}
+	hooked := 0
+	hub, err := Initialize(
+		WithIgnoreErrors("^ignored"),
+		WithInAppPrefixes(modulePath),
+		WithBeforeSend(func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			hooked++
+			if event.Message == "Dropped message" {
+				return nil
+			}
+			return event
+		}),
+		WithClientOptions(func(options *sentry.ClientOptions) {
+			options.Transport = transport
+			options.EnableLogs = false
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	logger := New(func(l *Logger) {
+		l.Hub = hub
+		l.EventLevel = core.LevelError
+	})
+	logger.Error(ctx, "ignored message")
+	logger.Error(ctx, "Dropped message")
+	logger.Error(ctx, "Sent message", core.E(errors.Join(io.EOF)))
+
+	events := transport.Events()
+	if len(events) != 1 || events[0].Message != "Sent message" {
+		t.Fatalf("Expected only the sent event, got %v", events)
+	}
+	if hooked != 2 {
+		t.Errorf("Expected the hook to run for the events not ignored, ran %d times", hooked)
+	}
+	inApp := 0
+	for _, frame := range events[0].Exception[len(events[0].Exception)-1].Stacktrace.Frames {
+		if inModule := strings.HasPrefix(frame.Module, modulePath); frame.InApp != inModule {
+			t.Errorf("Expected frame %s in-app to be %t", frame.Module, inModule)
+		}
+		if frame.InApp {
+			inApp++
+		}
+	}
+	if inApp == 0 {
+		t.Error("Expected the frames of the module to be in-app")
+	}
+}
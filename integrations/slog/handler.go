@@ -0,0 +1,125 @@
+// Package slog bridges log/slog and core.Interface in both directions.
+package slog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+type HandlerOption func(h *Handler)
+
+// Handler is a slog.Handler that forwards records to a core.Interface.
+type Handler struct {
+	Logger core.Interface
+	Level  slog.Leveler
+}
+
+func NewHandler(logger core.Interface, opts ...HandlerOption) *Handler {
+	handler := &Handler{
+		Logger: logger,
+		Level:  slog.LevelDebug,
+	}
+	for _, opt := range opts {
+		opt(handler)
+	}
+	return handler
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.Level.Level()
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	fields := make([]core.Field, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendFields(fields, attr)
+		return true
+	})
+	switch getCoreLevel(record.Level) {
+	case core.LevelTrace:
+		h.Logger.Trace(ctx, record.Message, fields...)
+	case core.LevelDebug:
+		h.Logger.Debug(ctx, record.Message, fields...)
+	case core.LevelInfo:
+		h.Logger.Info(ctx, record.Message, fields...)
+	case core.LevelWarning:
+		h.Logger.Warning(ctx, record.Message, fields...)
+	default:
+		h.Logger.Error(ctx, record.Message, fields...)
+	}
+	return nil
+}
+
+// WithAttrs binds the attributes to the underlying logger through With.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := make([]core.Field, 0, len(attrs))
+	for _, attr := range attrs {
+		fields = appendFields(fields, attr)
+	}
+	_h := *h
+	_h.Logger = h.Logger.With(fields...)
+	return &_h
+}
+
+// WithGroup maps the group to a named logger through Named.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	_h := *h
+	_h.Logger = h.Logger.Named(name)
+	return &_h
+}
+
+// appendFields appends the attribute to fields. Groups become nested map fields,
+// groups without a key are inlined as the slog.Handler contract requires.
+func appendFields(fields []core.Field, attr slog.Attr) []core.Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+	if attr.Value.Kind() != slog.KindGroup {
+		return append(fields, core.F(attr.Key, attr.Value.Any()))
+	}
+	group := attr.Value.Group()
+	if len(group) == 0 {
+		return fields
+	}
+	if attr.Key == "" {
+		for _, member := range group {
+			fields = appendFields(fields, member)
+		}
+		return fields
+	}
+	members := make([]core.Field, 0, len(group))
+	for _, member := range group {
+		members = appendFields(members, member)
+	}
+	nested := make(map[string]any, len(members))
+	for _, member := range members {
+		nested[member.Key] = member.Value
+	}
+	return append(fields, core.F(attr.Key, nested))
+}
+
+// getCoreLevel maps slog levels to core levels. Levels above slog.LevelError map to core.LevelError,
+// a slog record must never terminate the process through batch.Logger.
+func getCoreLevel(level slog.Level) core.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return core.LevelTrace
+	case level < slog.LevelInfo:
+		return core.LevelDebug
+	case level < slog.LevelWarn:
+		return core.LevelInfo
+	case level < slog.LevelError:
+		return core.LevelWarning
+	default:
+		return core.LevelError
+	}
+}
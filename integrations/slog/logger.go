@@ -0,0 +1,129 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+const Type = "slog"
+
+// Levels used for the severities slog does not define.
+const (
+	LevelTrace = slog.LevelDebug - 4
+	LevelPanic = slog.LevelError + 4
+	LevelFatal = slog.LevelError + 8
+)
+
+// callerSkip skips runtime.Callers, Logger.Log and the level method.
+const callerSkip = 3
+
+type Option func(l *Logger)
+
+// Logger is a core.Interface that writes into a slog.Handler.
+type Logger struct {
+	Handler slog.Handler
+	Name    string
+	NowFunc func() time.Time
+}
+
+func New(handler slog.Handler, opts ...Option) *Logger {
+	logger := &Logger{
+		Handler: handler,
+		NowFunc: time.Now,
+	}
+	for _, opt := range opts {
+		opt(logger)
+	}
+	return logger
+}
+
+func (l *Logger) Type() string {
+	return Type
+}
+
+func (l *Logger) Named(name string) core.Interface {
+	_l := *l
+	switch {
+	case name == "":
+	case l.Name != "":
+		_l.Name = fmt.Sprintf("%s.%s", l.Name, name)
+	default:
+		_l.Name = name
+	}
+	return &_l
+}
+
+func (l *Logger) Clone() core.Interface {
+	_l := *l
+	return &_l
+}
+
+func (l *Logger) WithContext(ctx context.Context) context.Context {
+	return ctx
+}
+
+func (l *Logger) With(fields ...core.Field) core.Interface {
+	_l := *l
+	_l.Handler = l.Handler.WithAttrs(l.attrs(fields))
+	return &_l
+}
+
+func (l *Logger) Trace(ctx context.Context, msg string, fields ...core.Field) {
+	l.Log(ctx, LevelTrace, msg, fields)
+}
+
+func (l *Logger) Debug(ctx context.Context, msg string, fields ...core.Field) {
+	l.Log(ctx, slog.LevelDebug, msg, fields)
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, fields ...core.Field) {
+	l.Log(ctx, slog.LevelInfo, msg, fields)
+}
+
+func (l *Logger) Warning(ctx context.Context, msg string, fields ...core.Field) {
+	l.Log(ctx, slog.LevelWarn, msg, fields)
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, fields ...core.Field) {
+	l.Log(ctx, slog.LevelError, msg, fields)
+}
+
+func (l *Logger) Fatal(ctx context.Context, msg string, fields ...core.Field) {
+	l.Log(ctx, LevelFatal, msg, fields)
+}
+
+func (l *Logger) Panic(ctx context.Context, msg string, fields ...core.Field) {
+	l.Log(ctx, LevelPanic, msg, fields)
+}
+
+// Flush is a no-op, slog.Handler has no buffering contract.
+func (l *Logger) Flush(_ context.Context) error {
+	return nil
+}
+
+func (l *Logger) Log(ctx context.Context, level slog.Level, msg string, fields []core.Field) {
+	if !l.Handler.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(callerSkip, pcs[:])
+	record := slog.NewRecord(l.NowFunc(), level, msg, pcs[0])
+	if l.Name != "" {
+		record.AddAttrs(slog.String("logger", l.Name))
+	}
+	record.AddAttrs(l.attrs(fields)...)
+	_ = l.Handler.Handle(ctx, record)
+}
+
+func (l *Logger) attrs(fields []core.Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, field := range fields {
+		attrs = append(attrs, slog.Any(field.Key, field.Value))
+	}
+	return attrs
+}
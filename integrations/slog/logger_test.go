@@ -0,0 +1,195 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/ensarkovankaya/go-logging/core"
+)
+
+type entry struct {
+	Level   core.Level
+	Name    string
+	Message string
+	Fields  map[string]any
+}
+
+type mockLogger struct {
+	name    string
+	extra   []core.Field
+	entries *[]entry
+}
+
+func newMockLogger() *mockLogger {
+	return &mockLogger{entries: &[]entry{}}
+}
+
+func (l *mockLogger) Type() string {
+	return "mock"
+}
+
+func (l *mockLogger) Named(name string) core.Interface {
+	_l := *l
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	_l.name = name
+	return &_l
+}
+
+func (l *mockLogger) Clone() core.Interface {
+	_l := *l
+	return &_l
+}
+
+func (l *mockLogger) WithContext(ctx context.Context) context.Context {
+	return ctx
+}
+
+func (l *mockLogger) With(fields ...core.Field) core.Interface {
+	_l := *l
+	_l.extra = append(append([]core.Field{}, l.extra...), fields...)
+	return &_l
+}
+
+func (l *mockLogger) Trace(_ context.Context, msg string, fields ...core.Field) {
+	l.log(core.LevelTrace, msg, fields)
+}
+
+func (l *mockLogger) Debug(_ context.Context, msg string, fields ...core.Field) {
+	l.log(core.LevelDebug, msg, fields)
+}
+
+func (l *mockLogger) Info(_ context.Context, msg string, fields ...core.Field) {
+	l.log(core.LevelInfo, msg, fields)
+}
+
+func (l *mockLogger) Warning(_ context.Context, msg string, fields ...core.Field) {
+	l.log(core.LevelWarning, msg, fields)
+}
+
+func (l *mockLogger) Error(_ context.Context, msg string, fields ...core.Field) {
+	l.log(core.LevelError, msg, fields)
+}
+
+func (l *mockLogger) Fatal(_ context.Context, msg string, fields ...core.Field) {
+	l.log(core.LevelFatal, msg, fields)
+}
+
+func (l *mockLogger) Panic(_ context.Context, msg string, fields ...core.Field) {
+	l.log(core.LevelPanic, msg, fields)
+}
+
+func (l *mockLogger) Flush(_ context.Context) error {
+	return nil
+}
+
+func (l *mockLogger) log(level core.Level, msg string, fields []core.Field) {
+	e := entry{Level: level, Name: l.name, Message: msg, Fields: map[string]any{}}
+	for _, field := range append(append([]core.Field{}, l.extra...), fields...) {
+		e.Fields[field.Key] = field.Value
+	}
+	*l.entries = append(*l.entries, e)
+}
+
+func TestHandler_Levels(t *testing.T) {
+	mock := newMockLogger()
+	logger := slog.New(NewHandler(mock, func(h *Handler) {
+		h.Level = slog.LevelInfo
+	}))
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Warn("warning message")
+	logger.Error("error message")
+	logger.Log(context.Background(), slog.LevelError+4, "critical message")
+
+	expected := []core.Level{core.LevelInfo, core.LevelWarning, core.LevelError, core.LevelError}
+	if len(*mock.entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(*mock.entries))
+	}
+	for i, level := range expected {
+		if (*mock.entries)[i].Level != level {
+			t.Errorf("expected entry %d level %s, got %s", i, level, (*mock.entries)[i].Level)
+		}
+	}
+}
+
+func TestHandler_GroupsAndAttrs(t *testing.T) {
+	mock := newMockLogger()
+	logger := slog.New(NewHandler(mock)).With("service", "api").WithGroup("db")
+	logger.Info(
+		"query",
+		slog.String("table", "users"),
+		slog.Group("stats", slog.Int("rows", 3), slog.Group("timing", slog.Duration("elapsed", time.Second))),
+		slog.Group("", slog.Bool("inlined", true)),
+		slog.Group("empty"),
+	)
+
+	if len(*mock.entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(*mock.entries))
+	}
+	e := (*mock.entries)[0]
+	if e.Name != "db" {
+		t.Errorf("expected logger name 'db', got '%s'", e.Name)
+	}
+	if e.Fields["service"] != "api" {
+		t.Errorf("expected service field 'api', got '%v'", e.Fields["service"])
+	}
+	if e.Fields["table"] != "users" {
+		t.Errorf("expected table field 'users', got '%v'", e.Fields["table"])
+	}
+	if e.Fields["inlined"] != true {
+		t.Errorf("expected inlined field true, got '%v'", e.Fields["inlined"])
+	}
+	if _, ok := e.Fields["empty"]; ok {
+		t.Error("expected empty group to be dropped")
+	}
+	stats, ok := e.Fields["stats"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected stats field to be a map, got %T", e.Fields["stats"])
+	}
+	if stats["rows"] != int64(3) {
+		t.Errorf("expected stats.rows 3, got '%v'", stats["rows"])
+	}
+	timing, ok := stats["timing"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected stats.timing field to be a map, got %T", stats["timing"])
+	}
+	if timing["elapsed"] != time.Second {
+		t.Errorf("expected stats.timing.elapsed 1s, got '%v'", timing["elapsed"])
+	}
+}
+
+func TestLogger_WritesToHandler(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	handler := slog.NewJSONHandler(buffer, &slog.HandlerOptions{Level: slog.LevelInfo})
+	logger := New(handler).Named("http").Named("client").Named("").With(core.F("service", "api"))
+	ctx := context.Background()
+	logger.Debug(ctx, "debug message")
+	logger.Warning(ctx, "warning message", core.F("status", 503))
+
+	lines := bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n"))
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %d: %s", len(lines), buffer.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal(lines[0], &record); err != nil {
+		t.Fatalf("failed to unmarshal record: %v", err)
+	}
+	expected := map[string]any{
+		"level":   "WARN",
+		"msg":     "warning message",
+		"logger":  "http.client",
+		"service": "api",
+		"status":  float64(503),
+	}
+	for key, value := range expected {
+		if record[key] != value {
+			t.Errorf("expected %s '%v', got '%v'", key, value, record[key])
+		}
+	}
+}
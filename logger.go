@@ -3,11 +3,18 @@ package logging
 import (
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	"sync/atomic"
 
+	"github.com/ensarkovankaya/go-logging/admin"
+	"github.com/ensarkovankaya/go-logging/config"
 	"github.com/ensarkovankaya/go-logging/core"
 	"github.com/ensarkovankaya/go-logging/integrations/batch"
 	"github.com/ensarkovankaya/go-logging/integrations/console"
+	"github.com/ensarkovankaya/go-logging/integrations/otel"
 	"github.com/ensarkovankaya/go-logging/integrations/sentry"
+	slogBridge "github.com/ensarkovankaya/go-logging/integrations/slog"
 )
 
 type Field = core.Field
@@ -15,15 +22,19 @@ type Interface = core.Interface
 type Level = core.Level
 
 const (
+	LevelTrace    = core.LevelTrace
 	LevelDebug    = core.LevelDebug
 	LevelInfo     = core.LevelInfo
 	LevelWarning  = core.LevelWarning
 	LevelError    = core.LevelError
+	LevelPanic    = core.LevelPanic
+	LevelFatal    = core.LevelFatal
 	LevelDisabled = core.LevelDisabled
 )
 
+// globalLogger is swapped atomically so ReplaceGlobal can run while other goroutines log through G.
 var (
-	globalLogger *batch.Logger
+	globalLogger atomic.Pointer[batch.Logger]
 )
 
 var (
@@ -33,11 +44,12 @@ var (
 
 // G returns the global logger instance.
 func G() *batch.Logger {
-	return globalLogger
+	return globalLogger.Load()
 }
 
+// ReplaceGlobal replaces the global logger. Calls already running on the previous logger complete on it.
 func ReplaceGlobal(logger *batch.Logger) {
-	globalLogger = logger
+	globalLogger.Store(logger)
 }
 
 // F exports core.F
@@ -50,11 +62,17 @@ func E(err error) Field {
 	return core.E(err)
 }
 
+// Slog returns a *slog.Logger that writes through the global logger, so code using log/slog
+// shares the integrations configured on G().
+func Slog() *slog.Logger {
+	return slog.New(slogBridge.NewHandler(G()))
+}
+
 // L returns the logger from the context.
 func L(ctx context.Context) core.Interface {
 	logger := FromContext(ctx)
 	if logger == nil {
-		return globalLogger
+		return G()
 	}
 	return logger
 }
@@ -78,7 +96,7 @@ func Named(name string) func(ctx context.Context) core.Interface {
 
 func init() {
 	var err error
-	globalLogger = batch.New()
+	globalLogger.Store(batch.New())
 	if autoConfigure, err = core.ParseBool(autoConfigEnv, false, false); err != nil {
 		panic(fmt.Sprintf("Failed to parse %v: %v\n", autoConfigEnv, err))
 	}
@@ -89,9 +107,61 @@ func init() {
 
 func AutoConfigure() {
 	if console.IsActive() {
-		globalLogger.AddIntegration(console.New())
+		G().AddIntegration(console.New())
 	}
 	if sentry.IsActive() {
-		globalLogger.AddIntegration(sentry.New())
+		G().AddIntegration(sentry.New())
+	}
+	if otel.IsActive() {
+		G().AddIntegration(otel.New())
+	}
+}
+
+// ConfigureFromFile builds the global logger from a YAML or JSON configuration file, see config.Config.
+// The global logger is left unchanged when the file is invalid.
+func ConfigureFromFile(path string) (*batch.Logger, error) {
+	cfg, err := config.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return configure(cfg)
+}
+
+// ConfigureFromReader builds the global logger from a YAML or JSON configuration document, see ConfigureFromFile.
+func ConfigureFromReader(r io.Reader) (*batch.Logger, error) {
+	cfg, err := config.Load(r)
+	if err != nil {
+		return nil, err
 	}
+	return configure(cfg)
+}
+
+// WatchConfigFile builds the global logger from a configuration file like ConfigureFromFile, then
+// reloads it on file change or SIGHUP until ctx is done, see config.Watcher.
+func WatchConfigFile(ctx context.Context, path string) (*batch.Logger, error) {
+	cfg, err := config.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := configure(cfg)
+	if err != nil {
+		return nil, err
+	}
+	go config.NewWatcher(path, logger, config.WithCurrent(cfg)).Run(ctx)
+	return logger, nil
+}
+
+func configure(cfg *config.Config) (*batch.Logger, error) {
+	logger, err := cfg.Build()
+	if err != nil {
+		return nil, err
+	}
+	ReplaceGlobal(logger)
+	return logger, nil
+}
+
+// AdminHandler returns an http.Handler that lists and changes the levels of the integrations
+// registered on the global logger at runtime, see admin.Handler.
+func AdminHandler() *admin.Handler {
+	return admin.NewHandler(G)
 }
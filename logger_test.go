@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ensarkovankaya/go-logging/integrations/batch"
+)
+
+func TestReplaceGlobal(t *testing.T) {
+	defer ReplaceGlobal(G())
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					L(ctx).Info(ctx, "message")
+					Named("test")(ctx).Debug(ctx, "message")
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		ReplaceGlobal(batch.New())
+	}
+	close(done)
+	wg.Wait()
+
+	logger := batch.New()
+	ReplaceGlobal(logger)
+	if G() != logger {
+		t.Error("expected G to return the replaced logger")
+	}
+}